@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Chats record the git branch they ran on. Once a feature branch is merged
+// and deleted, its chats are usually done with too; -delete-dead-branches
+// finds them by asking git in each project's directory which branches are
+// left.
+
+// gitBranches returns the local and remote-tracking branch names of the
+// repository at dir; remote branches count under their short name too, so a
+// branch only pushed is still alive. It fails when dir is not a repository.
+func gitBranches(dir string) (map[string]bool, error) {
+	out, err := exec.Command("git", "-C", dir, "for-each-ref", "--format=%(refname)", "refs/heads", "refs/remotes").Output()
+	if err != nil {
+		return nil, err
+	}
+	branches := make(map[string]bool)
+	for _, ref := range strings.Fields(string(out)) {
+		switch {
+		case strings.HasPrefix(ref, "refs/heads/"):
+			branches[strings.TrimPrefix(ref, "refs/heads/")] = true
+		case strings.HasPrefix(ref, "refs/remotes/"):
+			// refs/remotes/<remote>/<branch>
+			if _, branch, ok := strings.Cut(strings.TrimPrefix(ref, "refs/remotes/"), "/"); ok && branch != "HEAD" {
+				branches[branch] = true
+			}
+		}
+	}
+	return branches, nil
+}
+
+// deadBranchChats returns the chats whose branch no longer exists in their
+// project's repository, using list (gitBranches) once per directory. Chats
+// without a branch, on a detached HEAD, or whose project directory is gone,
+// not a repository or has no branches yet are never returned: there is
+// nothing to check them against.
+func deadBranchChats(chats []Chat, list func(dir string) (map[string]bool, error)) []Chat {
+	repos := make(map[string]map[string]bool)
+	var dead []Chat
+	for _, chat := range chats {
+		if chat.GitBranch == "" || chat.GitBranch == "HEAD" || chat.ProjectPath == "" {
+			continue
+		}
+		branches, seen := repos[chat.ProjectPath]
+		if !seen {
+			if info, err := os.Stat(chat.ProjectPath); err == nil && info.IsDir() {
+				branches, _ = list(chat.ProjectPath)
+			}
+			repos[chat.ProjectPath] = branches
+		}
+		if len(branches) > 0 && !branches[chat.GitBranch] {
+			dead = append(dead, chat)
+		}
+	}
+	return dead
+}
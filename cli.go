@@ -0,0 +1,913 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Non-interactive modes. Each run* function prints human-readable progress to
+// stdout and returns the process exit code; main decides whether to exit.
+
+// runDelete deletes the chats with the given UUIDs without starting the TUI.
+// Each argument may also be a UUID prefix or glob (see matchUUIDs). Unknown
+// arguments and explicitly named protected UUIDs count as failures. Unless yes is set, the user
+// must confirm on stdin. With verify set, each deletion is re-checked on disk
+// and leftovers count as failures. With summary set, a single JSON line with the tallies
+// is written to stderr so CI jobs can capture it separately from stdout.
+func runDelete(uuids []string, protect []string, yes, verify, summary bool) int {
+	var res deleteResult
+	defer func() {
+		if summary {
+			writeSummary(res)
+		}
+	}()
+
+	// A UUID can exist in more than one project (cloned sessions); a UUID
+	// argument addresses every copy.
+	all, err := findAllChats()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		res.Failed += len(uuids)
+		return exitCode(res)
+	}
+	byUUID := make(map[string][]Chat)
+	for _, chat := range all {
+		byUUID[chat.UUID] = append(byUUID[chat.UUID], chat)
+	}
+
+	known := make([]string, 0, len(byUUID))
+	for uuid := range byUUID {
+		known = append(known, uuid)
+	}
+
+	var targets []Chat
+	seen := make(map[string]bool)
+	for _, arg := range uuids {
+		matches, err := matchUUIDs(arg, known)
+		switch {
+		case err != nil:
+			fmt.Printf("Invalid pattern %q: %v\n", arg, err)
+			res.Failed++
+			continue
+		case len(matches) == 0:
+			fmt.Printf("Not found: %s\n", arg)
+			res.Failed++
+			continue
+		case len(matches) > 1 || matches[0] != arg:
+			fmt.Printf("%s matches %d chat(s)\n", arg, len(matches))
+		}
+		for _, uuid := range matches {
+			if seen[uuid] {
+				continue
+			}
+			seen[uuid] = true
+			if isProtectedUUID(uuid, protect) {
+				fmt.Printf("Protected, skipping: %s\n", uuid)
+				// Only an explicitly named UUID is a failure; a pattern
+				// is expected to sweep past protected chats.
+				if uuid == arg {
+					res.Failed++
+				}
+				continue
+			}
+			targets = append(targets, byUUID[uuid]...)
+		}
+	}
+
+	if len(targets) == 0 {
+		fmt.Println("Nothing to delete.")
+		return exitCode(res)
+	}
+
+	fmt.Printf("Chats to delete (%d):\n", len(targets))
+	for _, chat := range targets {
+		fmt.Printf("  %s  %s  (%s)\n", chat.UUID, chat.Title, chat.Project)
+	}
+	if !yes && !confirm(fmt.Sprintf("Delete %d chat(s)?", len(targets))) {
+		fmt.Println("Aborted.")
+		return 1
+	}
+
+	deleteTargets(targets, &res, verify)
+	return exitCode(res)
+}
+
+// runDeleteMatching deletes every chat whose title matches pattern (see
+// titleMatcher) without starting the TUI. Protected chats are skipped. The
+// matched titles are listed and must be confirmed unless yes is set.
+func runDeleteMatching(pattern string, protect []string, yes, verify, summary bool) int {
+	var res deleteResult
+	defer func() {
+		if summary {
+			writeSummary(res)
+		}
+	}()
+
+	match, err := titleMatcher(pattern)
+	if err != nil {
+		fmt.Printf("Invalid pattern %q: %v\n", pattern, err)
+		res.Failed++
+		return exitCode(res)
+	}
+	all, err := findAllChats()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		res.Failed++
+		return exitCode(res)
+	}
+
+	var targets []Chat
+	for _, chat := range all {
+		if !match(chat.Title) {
+			continue
+		}
+		if isProtectedUUID(chat.UUID, protect) {
+			fmt.Printf("Protected, skipping: %s  %s\n", chat.UUID, chat.Title)
+			continue
+		}
+		targets = append(targets, chat)
+	}
+	if len(targets) == 0 {
+		fmt.Printf("No chat titles match %q.\n", pattern)
+		return exitCode(res)
+	}
+
+	if !yes {
+		fmt.Printf("Chats matching %q (%d):\n", pattern, len(targets))
+		for _, chat := range targets {
+			fmt.Printf("  %s  %s  (%s)\n", chat.UUID, chat.Title, chat.Project)
+		}
+		if !confirm(fmt.Sprintf("Delete %d chat(s)?", len(targets))) {
+			fmt.Println("Aborted.")
+			return 1
+		}
+	}
+
+	deleteTargets(targets, &res, verify)
+	return exitCode(res)
+}
+
+// runKeepRecent applies a retention policy: in every project the newest n
+// chats are kept and the rest are deleted. Protected chats are never deleted.
+// The plan is printed and must be confirmed unless yes is set.
+func runKeepRecent(n int, protect []string, yes, verify, summary bool) int {
+	var res deleteResult
+	defer func() {
+		if summary {
+			writeSummary(res)
+		}
+	}()
+
+	all, err := findAllChats()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		res.Failed++
+		return exitCode(res)
+	}
+	candidates := keepRecentTargets(all, n)
+	var targets []Chat
+	for _, chat := range candidates {
+		if isProtectedUUID(chat.UUID, protect) {
+			fmt.Printf("Protected, keeping: %s  %s  (%s)\n", chat.UUID, chat.Title, chat.Project)
+			continue
+		}
+		targets = append(targets, chat)
+	}
+	switch {
+	case len(candidates) == 0:
+		fmt.Printf("No project has more than %d chat(s); nothing to delete.\n", n)
+		return exitCode(res)
+	case len(targets) == 0:
+		fmt.Printf("All %d candidate(s) are protected; nothing to delete.\n", len(candidates))
+		return exitCode(res)
+	}
+
+	fmt.Printf("Keeping the newest %d chat(s) per project; chats to delete (%d):\n", n, len(targets))
+	var last Chat
+	for i, chat := range targets {
+		if i == 0 || chat.Root != last.Root || chat.Project != last.Project {
+			fmt.Printf("  %s\n", chat.Project)
+		}
+		last = chat
+		fmt.Printf("    %s  %s  %s\n", chat.Timestamp, chat.UUID, chat.Title)
+	}
+	if !yes && !confirm(fmt.Sprintf("Delete %d chat(s)?", len(targets))) {
+		fmt.Println("Aborted.")
+		return 1
+	}
+
+	deleteTargets(targets, &res, verify)
+	return exitCode(res)
+}
+
+// runDeleteDeadBranches deletes the chats whose git branch no longer exists
+// in their project's repository (see deadBranchChats). Protected chats are
+// kept. The chats are listed by project and branch and must be confirmed
+// unless yes is set.
+func runDeleteDeadBranches(protect []string, yes, verify, summary bool) int {
+	var res deleteResult
+	defer func() {
+		if summary {
+			writeSummary(res)
+		}
+	}()
+
+	all, err := findAllChats()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		res.Failed++
+		return exitCode(res)
+	}
+	var targets []Chat
+	for _, chat := range deadBranchChats(all, gitBranches) {
+		if isProtectedUUID(chat.UUID, protect) {
+			fmt.Printf("Protected, keeping: %s  %s  (%s)\n", chat.UUID, chat.Title, chat.Project)
+			continue
+		}
+		targets = append(targets, chat)
+	}
+	if len(targets) == 0 {
+		fmt.Println("No chats on deleted branches.")
+		return exitCode(res)
+	}
+	sort.SliceStable(targets, func(i, j int) bool {
+		if targets[i].ProjectPath != targets[j].ProjectPath {
+			return targets[i].ProjectPath < targets[j].ProjectPath
+		}
+		return targets[i].GitBranch < targets[j].GitBranch
+	})
+
+	fmt.Printf("Chats on branches that no longer exist (%d):\n", len(targets))
+	var last Chat
+	for i, chat := range targets {
+		if i == 0 || chat.ProjectPath != last.ProjectPath || chat.GitBranch != last.GitBranch {
+			fmt.Printf("  %s  [%s]\n", chat.ProjectPath, chat.GitBranch)
+		}
+		last = chat
+		fmt.Printf("    %s  %s  %s\n", chat.Timestamp, chat.UUID, chat.Title)
+	}
+	if !yes && !confirm(fmt.Sprintf("Delete %d chat(s)?", len(targets))) {
+		fmt.Println("Aborted.")
+		return 1
+	}
+
+	deleteTargets(targets, &res, verify)
+	return exitCode(res)
+}
+
+// keepRecentTargets returns every chat that is not among the n most recent
+// of its project, grouped by project in first-seen order and newest first
+// within a project.
+func keepRecentTargets(chats []Chat, n int) []Chat {
+	type projectKey struct{ root, project string }
+	byProject := make(map[projectKey][]Chat)
+	var order []projectKey
+	for _, chat := range chats {
+		key := projectKey{chat.Root.dir, chat.Project}
+		if _, ok := byProject[key]; !ok {
+			order = append(order, key)
+		}
+		byProject[key] = append(byProject[key], chat)
+	}
+
+	var targets []Chat
+	for _, key := range order {
+		project := byProject[key]
+		sortChats(project)
+		if len(project) > n {
+			targets = append(targets, project[n:]...)
+		}
+	}
+	return targets
+}
+
+// titleMatcher compiles a -delete-matching pattern. A pattern written as
+// /expr/ is a regular expression; anything else is a case-insensitive
+// substring.
+func titleMatcher(pattern string) (func(title string) bool, error) {
+	if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+	if pattern == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+	needle := strings.ToLower(pattern)
+	return func(title string) bool {
+		return strings.Contains(strings.ToLower(title), needle)
+	}, nil
+}
+
+// deleteTargets deletes already confirmed chats and adds the outcome to res.
+// With cache_related_files the files are resolved through the index first.
+func deleteTargets(targets []Chat, res *deleteResult, verify bool) {
+	if cacheRelatedFiles {
+		if err := resolveRelatedFiles(targets, false); err != nil {
+			fmt.Printf("Warning: could not save the related-file index: %v\n", err)
+		}
+		defer func() {
+			if err := forgetRelatedFiles(targets); err != nil {
+				fmt.Printf("Warning: could not update the related-file index: %v\n", err)
+			}
+		}()
+	}
+
+	// Delete one chat at a time so a single failure doesn't stop the batch.
+	var deleted []Chat
+	for _, chat := range targets {
+		r, err := deleteChats([]Chat{chat}, nil)
+		if err == nil && verify {
+			if leftovers := verifyDeletion(r, []Chat{chat}); len(leftovers) > 0 {
+				for _, l := range leftovers {
+					fmt.Printf("Warning: still present after delete: %s\n", l)
+				}
+				// Not deleted after all: neither its bytes nor a log record
+				res.Failed++
+				continue
+			}
+		}
+		res.Deleted += r.Deleted
+		res.Failed += r.Failed
+		res.FreedBytes += r.FreedBytes
+		res.shared = append(res.shared, r.shared...)
+		res.records = append(res.records, r.records...)
+		if err != nil {
+			fmt.Printf("Failed: %s: %v\n", chat.UUID, err)
+			continue
+		}
+		if r.Deleted > 0 {
+			deleted = append(deleted, chat)
+		}
+	}
+
+	shared := res.sharedNote()
+	if shared != "" {
+		shared = "; " + shared
+	}
+	fmt.Printf("✓ Deleted %d chat(s), freed %s%s\n", res.Deleted, formatBytes(res.FreedBytes), shared)
+	if err := appendDeletionLog(res.records); err != nil {
+		fmt.Printf("Warning: could not write the deletion log: %v\n", err)
+	}
+	if err := runPostDeleteHook(postDeleteHook, deleted, false); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
+
+// matchUUIDs resolves a -delete argument against the known UUIDs: an exact
+// UUID matches itself, an argument containing *, ? or [ is a glob, anything
+// else is a prefix. Matches are sorted.
+func matchUUIDs(arg string, known []string) ([]string, error) {
+	glob := strings.ContainsAny(arg, "*?[")
+	if glob {
+		if _, err := filepath.Match(arg, ""); err != nil {
+			return nil, err
+		}
+	}
+	var matches []string
+	for _, uuid := range known {
+		if uuid == arg {
+			return []string{uuid}, nil
+		}
+		if glob {
+			if ok, _ := filepath.Match(arg, uuid); ok {
+				matches = append(matches, uuid)
+			}
+		} else if strings.HasPrefix(uuid, arg) {
+			matches = append(matches, uuid)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// runExportMarkdown prints the transcript of the chat with the given UUID as
+// Markdown to stdout. Errors go to stderr so the output can be redirected.
+func runExportMarkdown(uuid string) int {
+	return runExport(uuid, writeMarkdownTranscript)
+}
+
+// runExportJSONL prints the raw JSONL of the chat with the given UUID to
+// stdout.
+func runExportJSONL(uuid string) int {
+	return runExport(uuid, writeRawTranscript)
+}
+
+func runExport(uuid string, write func(io.Writer, Chat) error) int {
+	all, err := findAllChats()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, chat := range all {
+		if chat.UUID == uuid {
+			if err := write(os.Stdout, chat); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			return 0
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Not found: %s\n", uuid)
+	return 1
+}
+
+// runListFiles prints the files a deletion of uuid would touch, without
+// touching anything. It exits non-zero when nothing resolves at all.
+func runListFiles(uuid string) int {
+	n, err := writeRelatedFiles(os.Stdout, uuid)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if n == 0 {
+		fmt.Fprintf(os.Stderr, "No files found for %s\n", uuid)
+		return 1
+	}
+	return 0
+}
+
+// writeRelatedFiles lists every path findRelatedFiles checks for each
+// project holding uuid, marking it as present or missing, and returns how
+// many are present. A UUID without a chat file still gets its UUID-keyed
+// artifacts listed, which is how orphans show up.
+func writeRelatedFiles(w io.Writer, uuid string) (int, error) {
+	all, err := findAllChats()
+	if err != nil {
+		return 0, err
+	}
+	var copies []Chat
+	for _, chat := range all {
+		if chat.UUID == uuid {
+			copies = append(copies, chat)
+		}
+	}
+	if len(copies) == 0 {
+		for _, root := range scanRoots() {
+			copies = append(copies, Chat{UUID: uuid, Root: root})
+		}
+	}
+
+	total := 0
+	for _, chat := range copies {
+		if chat.Project == "" {
+			fmt.Fprintf(w, "%s (no chat file)\n", uuid)
+		} else {
+			fmt.Fprintf(w, "%s (%s)\n", uuid, chat.Project)
+		}
+		root := chat.root()
+		paths := relatedPaths(root, uuid, chat.Project, func(slug string) bool {
+			return isSlugUsedInOtherChats(root, slug, uuid, chat.Project)
+		})
+		for _, path := range paths {
+			mark := "✓"
+			if _, err := os.Stat(path); err != nil {
+				mark = "✗"
+			} else {
+				total++
+			}
+			fmt.Fprintf(w, "  %s %s\n", mark, path)
+		}
+	}
+	return total, nil
+}
+
+// runPurgeIndex removes sessions-index.json entries whose chat files no
+// longer exist, in every Claude directory. Live chats are never touched.
+func runPurgeIndex(yes bool) int {
+	var stale []staleIndexEntry
+	for _, root := range scanRoots() {
+		stale = append(stale, findStaleIndexEntries(root)...)
+	}
+	if len(stale) == 0 {
+		fmt.Println("No stale index entries.")
+		return 0
+	}
+
+	fmt.Printf("Stale index entries (%d):\n", len(stale))
+	type indexKey struct {
+		root    claudeRoot
+		project string
+	}
+	byIndex := make(map[indexKey]map[string]bool)
+	var order []indexKey
+	for _, e := range stale {
+		fmt.Printf("  %s  (%s)\n", e.SessionID, e.Project)
+		key := indexKey{e.Root, e.Project}
+		if byIndex[key] == nil {
+			byIndex[key] = make(map[string]bool)
+			order = append(order, key)
+		}
+		byIndex[key][e.SessionID] = true
+	}
+	if !yes && !confirm(fmt.Sprintf("Remove %d entr(y/ies) from the index?", len(stale))) {
+		fmt.Println("Aborted.")
+		return 1
+	}
+
+	removed, failed := 0, 0
+	for _, key := range order {
+		n, err := removeIndexEntries(key.root, key.project, byIndex[key])
+		removed += n
+		if err != nil {
+			fmt.Printf("Failed: %s: %v\n", key.project, err)
+			failed++
+		}
+	}
+	fmt.Printf("✓ Removed %d stale index entr(y/ies)\n", removed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// chatSizeRecord is one line of -sizes -json output.
+type chatSizeRecord struct {
+	UUID    string `json:"uuid"`
+	Project string `json:"project"`
+	Root    string `json:"root"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// runSizes prints the disk footprint of every chat (see chatSize), in list
+// order, as each becomes known. With asJSON every chat is one JSON object
+// per line (JSON Lines), so large setups can be consumed as a stream.
+func runSizes(asJSON bool) int {
+	all, err := findAllChats()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	enc := json.NewEncoder(os.Stdout)
+	var total int64
+	measureChats(all, func(chat Chat, size int64) {
+		total += size
+		if asJSON {
+			enc.Encode(chatSizeRecord{UUID: chat.UUID, Project: chat.Project, Root: chat.Root.dir, Bytes: size})
+			return
+		}
+		fmt.Printf("%10s  %s  (%s)\n", formatBytes(size), chat.UUID, chat.Project)
+	})
+	if !asJSON {
+		fmt.Printf("%10s  total for %d chat(s)\n", formatBytes(total), len(all))
+	}
+	return 0
+}
+
+// chatListRecord is one line of -list -json output. Bytes is only set with
+// -sort size, which measures the chats.
+type chatListRecord struct {
+	UUID         string    `json:"uuid"`
+	Title        string    `json:"title"`
+	Project      string    `json:"project"`
+	Root         string    `json:"root"`
+	LastActivity time.Time `json:"last_activity"`
+	Messages     int       `json:"messages"`
+	Compacted    bool      `json:"compacted"`
+	Bytes        int64     `json:"bytes,omitempty"`
+}
+
+// runList prints every chat, newest first as in the TUI. Sorted by "size",
+// each chat's footprint is measured (see chatSize) and the largest come
+// first, so the biggest space consumers lead and their UUIDs can go straight
+// into -delete. With asJSON every chat is one JSON object per line.
+func runList(sortBy string, asJSON bool) int {
+	if sortBy != "date" && sortBy != "size" {
+		fmt.Fprintf(os.Stderr, "Error: -sort must be date or size, not %q\n", sortBy)
+		return 1
+	}
+	all, err := findAllChats()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var sizes []int64
+	if sortBy == "size" {
+		measureChats(all, func(_ Chat, size int64) {
+			sizes = append(sizes, size)
+		})
+		all, sizes = largestFirst(all, sizes)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	var total int64
+	for i, chat := range all {
+		rec := chatListRecord{UUID: chat.UUID, Title: chat.Title, Project: chat.Project, Root: chat.Root.dir,
+			LastActivity: chat.Time, Messages: chat.MessageCount, Compacted: chat.Compacted}
+		if sizes != nil {
+			rec.Bytes = sizes[i]
+			total += sizes[i]
+		}
+		if asJSON {
+			enc.Encode(rec)
+			continue
+		}
+		title := displayTitle(chat.Title, 60)
+		if sizes != nil {
+			fmt.Printf("%10s  %s  %s  (%s)\n", formatBytes(rec.Bytes), chat.UUID, title, chat.Project)
+		} else {
+			fmt.Printf("%s  %s  %s  (%s)\n", chat.Timestamp, chat.UUID, title, chat.Project)
+		}
+	}
+	if !asJSON && sizes != nil {
+		fmt.Printf("%10s  total for %d chat(s)\n", formatBytes(total), len(all))
+	}
+	return 0
+}
+
+// largestFirst orders chats by their sizes, largest first; chats of the same
+// size keep their order.
+func largestFirst(chats []Chat, sizes []int64) ([]Chat, []int64) {
+	order := make([]int, len(chats))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return sizes[order[a]] > sizes[order[b]] })
+	sortedChats := make([]Chat, len(chats))
+	sortedSizes := make([]int64, len(sizes))
+	for i, idx := range order {
+		sortedChats[i], sortedSizes[i] = chats[idx], sizes[idx]
+	}
+	return sortedChats, sortedSizes
+}
+
+// runShowHistory prints the deletion log, oldest first: when, which chat,
+// what it freed and, for trashed chats, the batch to restore it from. With
+// asJSON the log's own JSON lines are printed.
+func runShowHistory(asJSON bool) int {
+	records, err := readDeletionLog()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, r := range records {
+			enc.Encode(r)
+		}
+		return 0
+	}
+	if len(records) == 0 {
+		fmt.Printf("No deletions logged yet (%s).\n", deletionLogPath())
+		return 0
+	}
+	var total int64
+	for _, r := range records {
+		total += r.FreedBytes
+		where := "deleted"
+		if r.TrashBatch != "" {
+			where = "trash " + r.TrashBatch
+		}
+		fmt.Printf("%s  %s  %s  (%s)  %s, %d file(s), %s\n",
+			formatTimestamp(r.Time), r.UUID, r.Title, r.Project, formatBytes(r.FreedBytes), len(r.Files), where)
+	}
+	fmt.Printf("%d chat(s), %s freed in total\n", len(records), formatBytes(total))
+	return 0
+}
+
+// storageStats is the summary printed by -stats.
+type storageStats struct {
+	Chats    int            `json:"chats"`
+	Bytes    int64          `json:"bytes"`
+	Empty    int            `json:"empty"`
+	Oldest   string         `json:"oldest,omitempty"` // RFC 3339; omitted without dated chats
+	Newest   string         `json:"newest,omitempty"`
+	Projects []projectStats `json:"projects"`
+
+	oldest, newest time.Time
+}
+
+// projectStats is one project's share of storageStats.
+type projectStats struct {
+	Project string `json:"project"`
+	Root    string `json:"root"`
+	Chats   int    `json:"chats"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// collectStats sums chats and their sizes (same order) per project, largest
+// project first.
+func collectStats(chats []Chat, sizes []int64) storageStats {
+	var st storageStats
+	byProject := make(map[[2]string]*projectStats)
+	for i, chat := range chats {
+		st.Chats++
+		st.Bytes += sizes[i]
+		if chat.Empty {
+			st.Empty++
+		}
+		if !chat.Time.IsZero() {
+			if st.oldest.IsZero() || chat.Time.Before(st.oldest) {
+				st.oldest = chat.Time
+			}
+			if chat.Time.After(st.newest) {
+				st.newest = chat.Time
+			}
+		}
+		key := [2]string{chat.Root.dir, chat.Project}
+		p := byProject[key]
+		if p == nil {
+			p = &projectStats{Project: chat.Project, Root: chat.Root.dir}
+			byProject[key] = p
+		}
+		p.Chats++
+		p.Bytes += sizes[i]
+	}
+	if !st.oldest.IsZero() {
+		st.Oldest = st.oldest.Format(time.RFC3339)
+		st.Newest = st.newest.Format(time.RFC3339)
+	}
+	st.Projects = []projectStats{}
+	for _, p := range byProject {
+		st.Projects = append(st.Projects, *p)
+	}
+	sort.Slice(st.Projects, func(i, j int) bool {
+		a, b := st.Projects[i], st.Projects[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Project < b.Project
+	})
+	return st
+}
+
+// runStats prints totals for monitoring storage growth: chats, size, empty
+// sessions, the activity range and every project's share. With asJSON the
+// summary is a single JSON object.
+func runStats(asJSON bool) int {
+	all, err := findAllChats()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	sizes := make([]int64, 0, len(all))
+	measureChats(all, func(chat Chat, size int64) {
+		sizes = append(sizes, size)
+	})
+	st := collectStats(all, sizes)
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(st)
+		return 0
+	}
+	fmt.Printf("Chats:   %d (%d empty)\n", st.Chats, st.Empty)
+	fmt.Printf("Size:    %s\n", formatBytes(st.Bytes))
+	fmt.Printf("Oldest:  %s\n", formatTimestamp(st.oldest))
+	fmt.Printf("Newest:  %s\n", formatTimestamp(st.newest))
+	if len(st.Projects) > 0 {
+		fmt.Println("Projects (largest first):")
+	}
+	for _, p := range st.Projects {
+		fmt.Printf("%10s  %5d chat(s)  %s\n", formatBytes(p.Bytes), p.Chats, p.Project)
+	}
+	return 0
+}
+
+// measureChats computes chatSize for every chat with a pool of workers and
+// calls emit in the order of chats, on the calling goroutine.
+func measureChats(chats []Chat, emit func(chat Chat, size int64)) {
+	sizes := make([]int64, len(chats))
+	done := make([]chan struct{}, len(chats))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	slugs := slugCounts(chats)
+	jobs := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		go func() {
+			for i := range jobs {
+				sizes[i] = chatSize(chats[i], slugs[chats[i].root().dir])
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range chats {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	for i, chat := range chats {
+		<-done[i]
+		emit(chat, sizes[i])
+	}
+}
+
+// runRefreshIndex rebuilds the related-file index for every chat.
+func runRefreshIndex() int {
+	all, err := findAllChats()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if err := resolveRelatedFiles(all, true); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ Indexed the related files of %d chat(s) in %s\n", len(all), relatedIndexPath())
+	return 0
+}
+
+// runRestoreTrash puts the chats of a trash batch back where they were.
+func runRestoreTrash(batch string) int {
+	restored, err := restoreTrashBatch(batch)
+	if restored > 0 {
+		fmt.Printf("✓ Restored %d chat(s) from trash batch %s\n", restored, batch)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runEmptyTrash permanently removes every trash batch after showing what
+// that would reclaim.
+func runEmptyTrash(yes bool) int {
+	batches, err := listTrashBatches()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if len(batches) == 0 {
+		fmt.Println("Trash is empty.")
+		return 0
+	}
+
+	fmt.Printf("Trash batches (%d):\n", len(batches))
+	for _, b := range batches {
+		fmt.Printf("  %s  %d chat(s)  %s  %s\n", b.Name, b.Chats, formatBytes(b.Size), b.Reason)
+	}
+	chats, size := trashTotal(batches)
+	fmt.Printf("Emptying the trash permanently deletes %d chat(s) and frees %s.\n", chats, formatBytes(size))
+	if !yes && !confirm("Empty the trash?") {
+		fmt.Println("Aborted.")
+		return 1
+	}
+
+	removed, freed, err := removeTrashBatches(batches)
+	fmt.Printf("✓ Removed %d trash batch(es), freed %s\n", removed, formatBytes(freed))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeSummary emits the machine-readable exit summary to stderr.
+func writeSummary(res deleteResult) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(os.Stderr, string(data))
+}
+
+func exitCode(res deleteResult) int {
+	if res.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// assumeYesEnv set to "1" answers every non-interactive confirmation like
+// -yes, for pipelines where flags are awkward to pass. The TUI ignores it.
+const assumeYesEnv = "CLAUDE_CHATS_ASSUME_YES"
+
+// assumeYes reports whether non-interactive modes skip their confirmation:
+// with -yes or assumeYesEnv.
+func assumeYes(yesFlag bool) bool {
+	return yesFlag || os.Getenv(assumeYesEnv) == "1"
+}
+
+// confirm asks a yes/no question on stdin; anything but "y" means no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	var response string
+	fmt.Scanln(&response)
+	return strings.ToLower(strings.TrimSpace(response)) == "y"
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -3,10 +3,18 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
 // Config stores application configuration
@@ -16,19 +24,186 @@ type Config struct {
 	GroupByProject         bool   `json:"group_by_project"`
 	LastUpdateCheck        int64  `json:"last_update_check"`
 	UpdateCheckIntervalHrs int    `json:"update_check_interval_hours"`
+
+	// UpdateChannel is "stable" (default, full releases only) or "beta"
+	// (pre-releases too).
+	UpdateChannel string `json:"update_channel,omitempty"`
+
+	// AutoRestart re-execs the new binary right after an update instead of
+	// asking first.
+	AutoRestart bool `json:"auto_restart,omitempty"`
+
+	// ClaudeDirs lists further Claude directories (e.g. a project-local
+	// .claude) whose chats are shown alongside those of ClaudeDir.
+	ClaudeDirs []string `json:"claude_dirs,omitempty"`
+
+	// ExcludeFile lists UUIDs or glob patterns (one per line) of chats that
+	// must never be deleted. Overridden by the -exclude-file flag.
+	ExcludeFile string `json:"exclude_file,omitempty"`
+
+	// ExcludeProjects lists project directory names (under projects/) or
+	// glob patterns of them that are never scanned: their chats don't show
+	// up and no mode can delete them.
+	ExcludeProjects []string `json:"exclude_projects,omitempty"`
+
+	// ProtectNoted protects chats that have a note (see notes.go). Unset
+	// means true.
+	ProtectNoted *bool `json:"protect_noted,omitempty"`
+
+	// SelectAllConfirmAbove makes a ask for a second a before selecting
+	// more than this many chats. 0 means 100; negative never asks.
+	SelectAllConfirmAbove int `json:"select_all_confirm_above,omitempty"`
+
+	// HideBelowLines hides chats with fewer JSONL lines than this from the
+	// list. 0 disables hiding at startup; the t key toggles it at runtime.
+	HideBelowLines int `json:"hide_below_lines,omitempty"`
+
+	// HideHelp starts with the help line(s) under the list hidden, leaving
+	// the rows to chats; the h key toggles it at runtime.
+	HideHelp bool `json:"hide_help,omitempty"`
+
+	// FullPaths shows the full decoded project path in the PROJECT column
+	// instead of the directory name. The p key toggles it and keeps the
+	// choice in the state file (see saveState).
+	FullPaths bool `json:"full_paths,omitempty"`
+
+	// MinWidth and MinHeight are the smallest terminal the list is drawn in;
+	// below either, a "terminal too small" notice replaces it. 0 means 40
+	// columns and 12 rows; negative never shows the notice.
+	MinWidth  int `json:"min_width,omitempty"`
+	MinHeight int `json:"min_height,omitempty"`
+
+	// VerifyDeletes re-checks the disk after each deletion and warns about
+	// files or index entries that are still present.
+	VerifyDeletes bool `json:"verify_deletes,omitempty"`
+
+	// UseTrash makes TUI deletions move chats to the trash (see trash.go)
+	// instead of removing them. Toggled in the Settings tab.
+	UseTrash bool `json:"use_trash,omitempty"`
+
+	// TrashRetentionDays permanently removes trash batches older than this
+	// many days on startup. 0 keeps them until the trash is emptied.
+	TrashRetentionDays int `json:"trash_retention_days,omitempty"`
+
+	// AutoPurgeEmpty moves chats with fewer than PurgeBelowLines lines (default
+	// 3), and small empty sessions, to the trash on startup. Off by default.
+	AutoPurgeEmpty  bool `json:"auto_purge_empty,omitempty"`
+	PurgeBelowLines int  `json:"purge_below_lines,omitempty"`
+
+	// ReadOnly starts in audit mode with deletion disabled (same as -readonly).
+	ReadOnly bool `json:"read_only,omitempty"`
+
+	// AssistantTitleBelow titles a chat by the first assistant reply when
+	// the first user message is shorter than this many characters. 0 (the
+	// default) always uses the user message.
+	AssistantTitleBelow int `json:"assistant_title_below,omitempty"`
+
+	// CacheRelatedFiles keeps the related files of every chat in an index
+	// (see relindex.go) that -delete, -delete-matching and -keep-recent
+	// reuse across runs. -refresh-index rebuilds it.
+	CacheRelatedFiles bool `json:"cache_related_files,omitempty"`
+
+	// PostDeleteHook is a shell command run after every deletion that
+	// removed chats; it gets their UUIDs as arguments and on stdin (see
+	// hook.go).
+	PostDeleteHook string `json:"post_delete_hook,omitempty"`
+
+	// DateFormat is a Go time layout for displayed timestamps, e.g.
+	// "02/01/2006 15:04 MST". Empty or invalid uses defaultDateFormat.
+	DateFormat string `json:"date_format,omitempty"`
+
+	// Theme picks a color preset and per-role overrides (see theme.go).
+	Theme *ThemeConfig `json:"theme,omitempty"`
+}
+
+// defaultSelectAllConfirmAbove is select_all_confirm_above when unset.
+const defaultSelectAllConfirmAbove = 100
+
+// selectAllConfirmAbove returns how many chats a selects without asking.
+// A negative select_all_confirm_above never asks.
+func (c *Config) selectAllConfirmAbove() int {
+	switch {
+	case c == nil || c.SelectAllConfirmAbove == 0:
+		return defaultSelectAllConfirmAbove
+	case c.SelectAllConfirmAbove < 0:
+		return math.MaxInt
+	}
+	return c.SelectAllConfirmAbove
+}
+
+// Default min_width and min_height: narrower terminals still get the list,
+// scrolled sideways (see hOverflow); fewer rows leave no room for chats.
+const (
+	defaultMinWidth  = 40
+	defaultMinHeight = 12
+)
+
+// minTerminalSize returns min_width and min_height with their defaults
+// applied; a negative setting is 0, no minimum.
+func (c *Config) minTerminalSize() (width, height int) {
+	width, height = defaultMinWidth, defaultMinHeight
+	if c != nil && c.MinWidth != 0 {
+		width = max(c.MinWidth, 0)
+	}
+	if c != nil && c.MinHeight != 0 {
+		height = max(c.MinHeight, 0)
+	}
+	return width, height
+}
+
+// protectNoted reports whether noted chats are protected (the default).
+func (c *Config) protectNoted() bool {
+	return c == nil || c.ProtectNoted == nil || *c.ProtectNoted
 }
 
 // Chat represents a single chat session
 type Chat struct {
 	UUID      string
 	Title     string
-	Timestamp string
-	Project   string
+	Timestamp string    // display form of Time
+	Time      time.Time // last activity; the sort key
+	Project   string    // encoded directory name under projects/
 	Version   string
-	// MessageCount int // TODO: maybe re-enable later
-	LineCount int
-	Path      string
-	Files     []string // related files for deletion
+	// MessageCount is the number of user and assistant messages:
+	// sessions-index.json's messageCount when the index lists the chat,
+	// counted in the transcript otherwise.
+	MessageCount int
+	LineCount    int
+	Path         string
+	Files        []string   // related files for deletion
+	PlanPath     string     // plans/<slug>.md when the chat has a plan file
+	Root         claudeRoot // Claude directory the chat was found in; zero for claude_dir
+	Note         string     // user annotation from notes.json, empty if none
+
+	// DerivedTitle is the title found in the chat when Title is a label from
+	// labels.json; empty for unlabeled chats.
+	DerivedTitle string
+
+	// Problem says why the chat file couldn't be read fully (permissions,
+	// truncation); such chats are listed in the warning style and can
+	// still be deleted.
+	Problem string
+
+	// GitBranch is the branch the chat last ran on, from its records; empty
+	// outside a git repository.
+	GitBranch string
+
+	// Compacted chats hold a summary record, left by /compact (or an older
+	// session summary); they are marked ∑ in the list. Such chats are often
+	// long-running sessions worth keeping.
+	Compacted bool
+
+	// Empty marks chats without any user or assistant message, listed as
+	// "[empty session]"; auto_purge_empty trashes them whatever their size.
+	Empty bool
+
+	// ProjectPath is the real directory the project stands for, decoded from
+	// Project (see resolveProjectPath).
+	ProjectPath string
+
+	// Protected chats match a pattern from the exclude file; they are shown
+	// but can never be selected or deleted.
+	Protected bool
 
 	// ForkParentID is the parent sessionId for /fork branches (v2.1.118+), empty
 	// otherwise. Currently unused — fork JSONLs are self-contained, so deleting
@@ -36,6 +211,34 @@ type Chat struct {
 	ForkParentID string
 }
 
+// messageContent is the text of message.content. Claude writes content either
+// as a plain string or as an array of typed blocks; for the latter the text
+// blocks are joined with newlines and everything else (tool_use, tool_result,
+// images) is dropped. Any other shape reads as empty instead of failing the
+// whole record.
+type messageContent string
+
+func (c *messageContent) UnmarshalJSON(data []byte) error {
+	*c = ""
+	var plain string
+	if err := json.Unmarshal(data, &plain); err == nil {
+		*c = messageContent(plain)
+		return nil
+	}
+	var blocks []contentBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return nil
+	}
+	var parts []string
+	for _, b := range blocks {
+		if b.Type == "text" && b.Text != "" {
+			parts = append(parts, b.Text)
+		}
+	}
+	*c = messageContent(strings.Join(parts, "\n"))
+	return nil
+}
+
 // JSONLMessage represents a message in the JSONL file
 type JSONLMessage struct {
 	Type        string `json:"type"`
@@ -45,8 +248,10 @@ type JSONLMessage struct {
 	Summary     string `json:"summary"`
 	CustomTitle string `json:"customTitle"`
 	AiTitle     string `json:"aiTitle"`
+	Timestamp   string `json:"timestamp"` // ISO 8601 with milliseconds
+	GitBranch   string `json:"gitBranch"`
 	Message     struct {
-		Content string `json:"content"`
+		Content messageContent `json:"content"`
 	} `json:"message"`
 	ForkedFrom struct {
 		SessionID string `json:"sessionId"`
@@ -75,7 +280,7 @@ type SessionEntry struct {
 }
 
 var (
-	configPath     = filepath.Join(os.Getenv("HOME"), ".config", "claude-chats", "config.json")
+	configPath     = defaultConfigPath()
 	claudeDir      string
 	projectsDir    string
 	debugDir       string
@@ -85,8 +290,38 @@ var (
 	fileHistoryDir string
 	plansDir       string
 	agentsDir      string
+
+	// claudeRoots lists every Claude directory to scan, claude_dir first.
+	// The path globals above hold claude_dir's; see scanRoots.
+	claudeRoots []string
+
+	// projectScope restricts scanning to one project directory under
+	// projectsDir (the -project flag); empty scans every project.
+	projectScope string
+
+	// dateFormat is the Go time layout for displayed chat timestamps (the
+	// date_format config); sorting always uses the parsed times.
+	dateFormat = defaultDateFormat
+
+	// assistantTitleBelow makes the first assistant reply the title when the
+	// first user message is shorter than this many characters (the
+	// assistant_title_below config); 0 keeps the user message.
+	assistantTitleBelow int
+
+	// cacheRelatedFiles makes the non-interactive delete modes resolve
+	// related files through the on-disk index (cache_related_files).
+	cacheRelatedFiles bool
+
+	// postDeleteHook is the post_delete_hook command; empty runs nothing.
+	postDeleteHook string
+
+	// excludeProjects holds the exclude_projects patterns; matching project
+	// directories are never scanned.
+	excludeProjects []string
 )
 
+const defaultDateFormat = "2006-01-02 15:04:05"
+
 // TODO: directories under ~/.claude that are not yet covered. For each, decide
 // whether per-chat cleanup applies (UUID-keyed -> include in findRelatedFiles)
 // or whether it belongs to a global retention pool (timestamp-keyed -> ignore).
@@ -100,20 +335,46 @@ var (
 
 // Config management
 
-func loadConfig() (*Config, error) {
-	data, err := os.ReadFile(configPath)
+// configNames are the config file names looked up next to configPath. A
+// hand-written YAML or TOML file wins over the default config.json.
+var configNames = []string{"config.yaml", "config.yml", "config.toml", "config.json"}
+
+// loadConfig reads the config and the state file on top of it, and returns
+// the path of the config file it read.
+func loadConfig() (*Config, string, error) {
+	path := configPath
+	for _, name := range configNames {
+		if p := filepath.Join(filepath.Dir(configPath), name); fileExists(p) {
+			path = p
+			break
+		}
+	}
+
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return nil, path, err
 	}
 
 	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, err
+	if err := decodeConfig(path, data, &config); err != nil {
+		return nil, path, fmt.Errorf("%s: %w", filepath.Base(path), err)
 	}
 
-	return &config, nil
+	data, err = os.ReadFile(statePath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, path, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, path, fmt.Errorf("%s: %w", filepath.Base(statePath()), err)
+		}
+	}
+
+	return &config, path, nil
 }
 
+// saveConfig writes config to configPath as JSON. Only the first run does
+// so; afterwards the app keeps what it changes in the state file.
 func saveConfig(config *Config) error {
 	// Create config directory if it doesn't exist
 	configDir := filepath.Dir(configPath)
@@ -129,41 +390,443 @@ func saveConfig(config *Config) error {
 	return os.WriteFile(configPath, data, 0644)
 }
 
+// statePath is the file holding what the app changes itself: the time of
+// the last update check and the settings toggled in the TUI. Its keys are
+// config keys and override the config file, which is never rewritten, so
+// comments in a hand-written one survive.
+func statePath() string {
+	return filepath.Join(filepath.Dir(configPath), "state.json")
+}
+
+// saveState sets key to value in the state file.
+func saveState(key string, value any) error {
+	path := statePath()
+	state := make(map[string]any)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return err
+		}
+	}
+	state[key] = value
+	data, err = json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// decodeConfig parses data by the extension of path. YAML and TOML are read
+// into a generic map and passed through JSON, so the json tags on Config are
+// the single source of key names.
+func decodeConfig(path string, data []byte, config *Config) error {
+	var doc map[string]interface{}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+	case ".toml":
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+	default:
+		return json.Unmarshal(data, config)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, config)
+}
+
 func promptForClaudeDir() (string, error) {
-	defaultDir := filepath.Join(os.Getenv("HOME"), ".claude")
+	return pickClaudeDir(os.Stdin, os.Stdout, claudeDirCandidates())
+}
+
+// claudeDirCandidates returns the existing directories among the usual
+// places for Claude's data: ~/.claude, $CLAUDE_CONFIG_DIR, $CLAUDE_HOME and
+// claude under the XDG config and data directories.
+func claudeDirCandidates() []string {
+	home := homeDir()
+	xdgConfig := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfig == "" && home != "" {
+		xdgConfig = filepath.Join(home, ".config")
+	}
+	xdgData := os.Getenv("XDG_DATA_HOME")
+	if xdgData == "" && home != "" {
+		xdgData = filepath.Join(home, ".local", "share")
+	}
+	var places []string
+	if home != "" {
+		places = append(places, filepath.Join(home, ".claude"))
+	}
+	places = append(places, os.Getenv("CLAUDE_CONFIG_DIR"), os.Getenv("CLAUDE_HOME"))
+	if xdgConfig != "" {
+		places = append(places, filepath.Join(xdgConfig, "claude"))
+	}
+	if xdgData != "" {
+		places = append(places, filepath.Join(xdgData, "claude"))
+	}
+
+	var found []string
+	seen := make(map[string]bool)
+	for _, dir := range places {
+		if dir == "" {
+			continue
+		}
+		dir = filepath.Clean(expandHome(dir))
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		found = append(found, dir)
+	}
+	return found
+}
+
+// checkClaudeDir makes sure dir looks like a Claude directory, i.e. has the
+// projects directory holding the chats.
+func checkClaudeDir(dir string) error {
+	if info, err := os.Stat(filepath.Join(dir, "projects")); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s has no projects directory; is it the Claude directory?", dir)
+	}
+	return nil
+}
+
+// pickClaudeDir runs the first-run setup: the candidates are offered as a
+// numbered list (Enter takes the first), or any path can be typed instead.
+// The answer is asked again until it names a directory with projects in it.
+func pickClaudeDir(in io.Reader, out io.Writer, candidates []string) (string, error) {
+	defaultDir := filepath.Join(homeDir(), ".claude")
+	if len(candidates) > 0 {
+		defaultDir = candidates[0]
+	}
+
+	fmt.Fprintln(out, "Claude Chat Manager - First Run Setup")
+	fmt.Fprintln(out)
+	if len(candidates) > 0 {
+		fmt.Fprintln(out, "Found these Claude directories:")
+		for i, dir := range candidates {
+			hint := ""
+			if checkClaudeDir(dir) != nil {
+				hint = "  (no projects directory)"
+			}
+			fmt.Fprintf(out, "  %d) %s%s\n", i+1, dir, hint)
+		}
+		fmt.Fprintln(out)
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		if len(candidates) > 0 {
+			fmt.Fprintf(out, "Choose 1-%d or enter a path [press Enter for %s]: ", len(candidates), defaultDir)
+		} else {
+			fmt.Fprintf(out, "Enter the path to your Claude directory (default: %s)\n", defaultDir)
+			fmt.Fprint(out, "Path [press Enter for default]: ")
+		}
+		input, err := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+		if err != nil && (err != io.EOF || input == "") {
+			return "", err
+		}
 
-	fmt.Println("Claude Chat Manager - First Run Setup")
-	fmt.Println()
-	fmt.Printf("Enter the path to your Claude directory (default: %s)\n", defaultDir)
-	fmt.Print("Path [press Enter for default]: ")
+		dir := expandHome(input)
+		if n, convErr := strconv.Atoi(input); convErr == nil && n >= 1 && n <= len(candidates) {
+			dir = candidates[n-1]
+		}
+		if input == "" {
+			dir = defaultDir
+		}
+		if err := checkClaudeDir(dir); err != nil {
+			fmt.Fprintf(out, "%v\n", err)
+			continue
+		}
+		return dir, nil
+	}
+}
+
+// homeDir returns the user's home directory, or "" when it can't be
+// determined (HOME unset, as in some CI jobs and launchd agents).
+func homeDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return home
+}
+
+// defaultConfigPath returns ~/.config/claude-chats/config.json, or "" without
+// a home directory: main refuses to start then rather than reading and
+// writing under a relative path.
+func defaultConfigPath() string {
+	home := homeDir()
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".config", "claude-chats", "config.json")
+}
+
+// expandHome expands a leading ~ to the home directory. Without one the
+// path is returned as is.
+func expandHome(path string) string {
+	if home := homeDir(); home != "" && strings.HasPrefix(path, "~") {
+		return filepath.Join(home, path[1:])
+	}
+	return path
+}
 
-	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
+// loadExcludeFile reads protection patterns from path: one UUID or glob
+// pattern per line, blank lines and # comments ignored. Patterns are
+// validated up front so a typo fails loudly instead of protecting nothing.
+func loadExcludeFile(path string) ([]string, error) {
+	data, err := os.ReadFile(expandHome(path))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	input = strings.TrimSpace(input)
-	if input == "" {
-		return defaultDir, nil
+	var patterns []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := filepath.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid pattern %q: %w", path, i+1, line, err)
+		}
+		patterns = append(patterns, line)
 	}
+	return patterns, nil
+}
 
-	// Expand ~ to home directory
-	if strings.HasPrefix(input, "~") {
-		input = filepath.Join(os.Getenv("HOME"), input[1:])
+// validateProjectScope checks that name is a project directory under the
+// projects/ of some Claude directory, listing the available ones in the error otherwise.
+// A path ("..", "a/b") is refused: it could reach outside projects/.
+func validateProjectScope(name string) error {
+	if name == "." || name == ".." || strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		return fmt.Errorf("invalid project %q: give a directory name under projects/, not a path", name)
+	}
+	var names []string
+	for _, root := range scanRoots() {
+		dir := root.projects
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && info.IsDir() {
+			return nil
+		}
+		if entries, err := os.ReadDir(dir); err == nil {
+			for _, e := range entries {
+				if e.IsDir() {
+					names = append(names, e.Name())
+				}
+			}
+		}
 	}
+	msg := fmt.Sprintf("unknown project %q", name)
+	if len(names) > 0 {
+		msg += "\nAvailable projects:\n  " + strings.Join(names, "\n  ")
+	}
+	return errors.New(msg)
+}
 
-	return input, nil
+// validateDateFormat rejects layouts that contain no date or time elements
+// (Format would print them verbatim) or that don't read back what they print.
+func validateDateFormat(layout string) error {
+	ref := time.Date(2025, 12, 28, 23, 59, 58, 0, time.Local)
+	out := ref.Format(layout)
+	if out == layout {
+		return fmt.Errorf("date_format %q contains no date or time elements", layout)
+	}
+	if _, err := time.Parse(layout, out); err != nil {
+		return fmt.Errorf("invalid date_format %q: %v", layout, err)
+	}
+	return nil
 }
 
 func initializePaths(dir string) {
-	claudeDir = dir
-	projectsDir = filepath.Join(claudeDir, "projects")
-	debugDir = filepath.Join(claudeDir, "debug")
-	todosDir = filepath.Join(claudeDir, "todos")
-	sessionDir = filepath.Join(claudeDir, "session-env")
-	tasksDir = filepath.Join(claudeDir, "tasks")
-	fileHistoryDir = filepath.Join(claudeDir, "file-history")
-	plansDir = filepath.Join(claudeDir, "plans")
-	agentsDir = filepath.Join(claudeDir, "agents")
+	root := newClaudeRoot(dir)
+	claudeDir = root.dir
+	projectsDir = root.projects
+	debugDir = root.debug
+	todosDir = root.todos
+	sessionDir = root.sessionEnv
+	tasksDir = root.tasks
+	fileHistoryDir = root.fileHistory
+	plansDir = root.plans
+	agentsDir = root.agents
+}
+
+// applyConfig sets the process-wide state derived from config: the Claude
+// directories, date format, title source and theme. Invalid values fall back
+// to the defaults and are reported as warnings. It runs at startup and again
+// when the TUI reloads the config.
+func applyConfig(config *Config) (warnings []string) {
+	setClaudeRoots(config.ClaudeDir, config.ClaudeDirs)
+
+	dateFormat = defaultDateFormat
+	if config.DateFormat != "" {
+		if err := validateDateFormat(config.DateFormat); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%v, using %s", err, defaultDateFormat))
+		} else {
+			dateFormat = config.DateFormat
+		}
+	}
+
+	assistantTitleBelow = config.AssistantTitleBelow
+	cacheRelatedFiles = config.CacheRelatedFiles
+	postDeleteHook = config.PostDeleteHook
+
+	excludeProjects = nil
+	for _, pattern := range config.ExcludeProjects {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			warnings = append(warnings, fmt.Sprintf("exclude_projects: invalid pattern %q: %v", pattern, err))
+			continue
+		}
+		excludeProjects = append(excludeProjects, pattern)
+	}
+
+	switch config.UpdateChannel {
+	case "", channelStable, channelBeta:
+	default:
+		warnings = append(warnings, fmt.Sprintf("update_channel: unknown channel %q, using %s", config.UpdateChannel, channelStable))
+	}
+
+	if err := applyTheme(config.Theme); err != nil {
+		applyTheme(nil)
+		warnings = append(warnings, fmt.Sprintf("%v, using default theme", err))
+	}
+	return warnings
+}
+
+// isExcludedProject reports whether the project directory name matches an
+// exclude_projects pattern.
+func isExcludedProject(name string) bool {
+	for _, pattern := range excludeProjects {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// countExcludedProjects returns how many project directories, across all
+// Claude directories, exclude_projects hides.
+func countExcludedProjects() int {
+	if len(excludeProjects) == 0 {
+		return 0
+	}
+	n := 0
+	for _, root := range scanRoots() {
+		entries, _ := os.ReadDir(root.projects)
+		for _, entry := range entries {
+			if entry.IsDir() && isExcludedProject(entry.Name()) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// setClaudeRoots initializes the paths for primary and records extra as
+// further directories to scan. Duplicates of primary are dropped.
+func setClaudeRoots(primary string, extra []string) {
+	initializePaths(primary)
+	claudeRoots = []string{primary}
+	seen := map[string]bool{filepath.Clean(primary): true}
+	for _, dir := range extra {
+		dir = filepath.Clean(expandHome(dir))
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		claudeRoots = append(claudeRoots, dir)
+	}
+}
+
+// claudeRoot is one Claude directory with the directories in it that hold
+// chats and their related files. Every chat carries the one it was found in
+// (Chat.Root), and whatever reads or removes a chat's files takes it as an
+// argument, so chats of several Claude directories can be handled at once.
+type claudeRoot struct {
+	dir         string
+	projects    string
+	debug       string
+	todos       string
+	sessionEnv  string
+	tasks       string
+	fileHistory string
+	plans       string
+	agents      string
+}
+
+func newClaudeRoot(dir string) claudeRoot {
+	return claudeRoot{
+		dir:         dir,
+		projects:    filepath.Join(dir, "projects"),
+		debug:       filepath.Join(dir, "debug"),
+		todos:       filepath.Join(dir, "todos"),
+		sessionEnv:  filepath.Join(dir, "session-env"),
+		tasks:       filepath.Join(dir, "tasks"),
+		fileHistory: filepath.Join(dir, "file-history"),
+		plans:       filepath.Join(dir, "plans"),
+		agents:      filepath.Join(dir, "agents"),
+	}
+}
+
+// primaryRoot returns claude_dir as the path globals hold it.
+func primaryRoot() claudeRoot {
+	return claudeRoot{
+		dir:         claudeDir,
+		projects:    projectsDir,
+		debug:       debugDir,
+		todos:       todosDir,
+		sessionEnv:  sessionDir,
+		tasks:       tasksDir,
+		fileHistory: fileHistoryDir,
+		plans:       plansDir,
+		agents:      agentsDir,
+	}
+}
+
+// rootOf returns the Claude directory dir among scanRoots, or builds it when
+// it is no longer configured; an empty dir is claude_dir.
+func rootOf(dir string) claudeRoot {
+	if dir == "" {
+		return primaryRoot()
+	}
+	for _, root := range scanRoots() {
+		if root.dir == dir {
+			return root
+		}
+	}
+	return newClaudeRoot(dir)
+}
+
+// root returns the Claude directory of chat; chats built without a Root
+// belong to claude_dir.
+func (chat Chat) root() claudeRoot {
+	if chat.Root.dir == "" {
+		return primaryRoot()
+	}
+	return chat.Root
+}
+
+// scanRoots returns the Claude directories to scan: claudeRoots, or just
+// claude_dir when no roots were configured.
+func scanRoots() []claudeRoot {
+	if len(claudeRoots) == 0 {
+		return []claudeRoot{primaryRoot()}
+	}
+	roots := make([]claudeRoot, len(claudeRoots))
+	for i, dir := range claudeRoots {
+		roots[i] = newClaudeRoot(dir)
+		if dir == claudeDir {
+			roots[i] = primaryRoot()
+		}
+	}
+	return roots
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The deletion log is a permanent record of every chat deleted or trashed,
+// one JSON object per line next to the config, so "what did I delete last
+// week" has an answer after the session history (H) is gone. Every deletion
+// path appends to it: the TUI, the non-interactive modes and auto-purge.
+// -show-history reads it back.
+
+// deletionLogPath returns the file holding the deletion log.
+func deletionLogPath() string {
+	return filepath.Join(filepath.Dir(configPath), "history.log")
+}
+
+// deletionRecord is one deleted chat in the deletion log.
+type deletionRecord struct {
+	Time       time.Time `json:"time"`
+	UUID       string    `json:"uuid"`
+	Title      string    `json:"title"`
+	Project    string    `json:"project"`
+	Files      []string  `json:"files"`
+	FreedBytes int64     `json:"freed_bytes"`
+	TrashBatch string    `json:"trash_batch,omitempty"` // set when moved to the trash
+}
+
+// appendDeletionLog adds records to the end of the deletion log, creating it
+// on first use.
+func appendDeletionLog(records []deletionRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(deletionLogPath()), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(deletionLogPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// readDeletionLog returns every record of the deletion log, oldest first.
+// A missing log is empty; lines that don't parse (a write cut short) are
+// skipped.
+func readDeletionLog() ([]deletionRecord, error) {
+	f, err := os.Open(deletionLogPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []deletionRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		var r deletionRecord
+		if json.Unmarshal(scanner.Bytes(), &r) == nil {
+			records = append(records, r)
+		}
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// transcriptRecord is the subset of a JSONL record needed to rebuild the
+// conversation. Content stays raw: it is either a plain string or an array of
+// typed blocks (text, tool_use, tool_result, ...).
+type transcriptRecord struct {
+	Type    string `json:"type"`
+	IsMeta  bool   `json:"isMeta"`
+	Message struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"message"`
+}
+
+// contentBlock is one element of a structured message content array.
+type contentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	Name string `json:"name"` // tool_use
+}
+
+// contentText extracts readable text from raw message content. Text blocks are
+// joined with blank lines; tool calls become short placeholders; tool results
+// and other block types are dropped.
+func contentText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var plain string
+	if err := json.Unmarshal(raw, &plain); err == nil {
+		return plain
+	}
+
+	var blocks []contentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return ""
+	}
+	var parts []string
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			if b.Text != "" {
+				parts = append(parts, b.Text)
+			}
+		case "tool_use":
+			parts = append(parts, fmt.Sprintf("_[tool call: %s]_", b.Name))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// writeMarkdownTranscript renders the user/assistant exchange of chat as
+// Markdown with a heading per turn. System tags are stripped; meta records and
+// turns left empty after cleaning (e.g. bare tool results) are skipped.
+func writeMarkdownTranscript(w io.Writer, chat Chat) error {
+	file, err := os.Open(chat.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n\n", chat.Title)
+	fmt.Fprintf(bw, "- Session: `%s`\n", chat.UUID)
+	fmt.Fprintf(bw, "- Project: `%s`\n", chat.Project)
+	fmt.Fprintf(bw, "- Last activity: %s\n", chat.Timestamp)
+	if chat.Version != "" {
+		fmt.Fprintf(bw, "- Claude Code version: %s\n", chat.Version)
+	}
+
+	err = eachTurn(file, func(heading, text string) bool {
+		fmt.Fprintf(bw, "\n## %s\n\n%s\n", heading, text)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// eachTurn calls fn with every user/assistant turn of a transcript ("User" or
+// "Assistant" and the cleaned text) until fn returns false. System tags are
+// stripped; meta records and turns left empty after cleaning (e.g. bare tool
+// results) are skipped.
+func eachTurn(r io.Reader, fn func(heading, text string) bool) error {
+	// Lines are read whole however long they are: a turn with a pasted file
+	// can run past any fixed buffer, and an export must not stop there.
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if heading, text, ok := transcriptTurn(line); ok && !fn(heading, text) {
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// transcriptTurn returns the heading and cleaned text of a JSONL line, with
+// ok false for anything eachTurn skips.
+func transcriptTurn(line []byte) (heading, text string, ok bool) {
+	var rec transcriptRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return "", "", false
+	}
+	if rec.IsMeta || (rec.Type != "user" && rec.Type != "assistant") {
+		return "", "", false
+	}
+
+	text = strings.TrimSpace(stripSystemTags(contentText(rec.Message.Content)))
+	if text == "" || text == "[Request interrupted by user]" {
+		return "", "", false
+	}
+
+	heading = "User"
+	if rec.Type == "assistant" {
+		heading = "Assistant"
+	}
+	return heading, text, true
+}
+
+// writeRawTranscript copies the chat's JSONL file unchanged, for tools that
+// want the machine-readable session.
+func writeRawTranscript(w io.Writer, chat Chat) error {
+	file, err := os.Open(chat.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// exportMarkdown writes the transcript of chat to path, refusing to overwrite
+// an existing file.
+func exportMarkdown(chat Chat, path string) error {
+	return exportFile(chat, path, writeMarkdownTranscript)
+}
+
+// exportJSONL copies the raw JSONL of chat to path, refusing to overwrite an
+// existing file.
+func exportJSONL(chat Chat, path string) error {
+	return exportFile(chat, path, writeRawTranscript)
+}
+
+// exportFile creates path and fills it with write; a failed write removes the
+// partial file.
+func exportFile(chat Chat, path string, write func(io.Writer, Chat) error) error {
+	path = expandHome(path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%s already exists", path)
+		}
+		return err
+	}
+	if err := write(f, chat); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
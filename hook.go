@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// The post_delete_hook command runs after every deletion that removed at
+// least one chat, from the TUI and the non-interactive delete modes alike,
+// e.g. to send a notification or commit a manifest of the cleanup. A failing
+// hook is reported but never undoes the deletion.
+
+// hookTimeout bounds the hook so a hanging command can't freeze the TUI.
+const hookTimeout = time.Minute
+
+// runPostDeleteHook runs command through the shell. The deleted UUIDs are
+// its arguments ($1, $2, ... with sh) and are also written one per line to
+// its stdin; CLAUDE_CHATS_DELETED holds their count and CLAUDE_CHATS_TRASHED
+// is 1 when the chats went to the trash. An empty command does nothing.
+func runPostDeleteHook(command string, deleted []Chat, trashed bool) error {
+	if command == "" || len(deleted) == 0 {
+		return nil
+	}
+	uuids := make([]string, len(deleted))
+	for i, chat := range deleted {
+		uuids[i] = chat.UUID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command, "post_delete_hook")
+		cmd.Args = append(cmd.Args, uuids...)
+	}
+	trashedFlag := "0"
+	if trashed {
+		trashedFlag = "1"
+	}
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("CLAUDE_CHATS_DELETED=%d", len(uuids)),
+		"CLAUDE_CHATS_TRASHED="+trashedFlag)
+	cmd.Stdin = strings.NewReader(strings.Join(uuids, "\n") + "\n")
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("post_delete_hook timed out after %s", hookTimeout)
+		}
+		if msg := lastLine(out.String()); msg != "" {
+			return fmt.Errorf("post_delete_hook: %v: %s", err, msg)
+		}
+		return fmt.Errorf("post_delete_hook: %v", err)
+	}
+	return nil
+}
+
+// lastLine returns the last non-empty line of s, trimmed; the usual place
+// for a command's error message.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,33 @@
+package main
+
+import "path/filepath"
+
+// Labels replace a chat's derived title with one the user picked, stored by
+// UUID next to the config. They are applied while scanning, so every view
+// and non-interactive mode (-delete-matching included) sees the label.
+
+// labelsPath returns the file holding the chat labels.
+func labelsPath() string {
+	return filepath.Join(filepath.Dir(configPath), "labels.json")
+}
+
+// loadLabels reads every label, keyed by UUID. A missing file means no labels.
+func loadLabels() (map[string]string, error) {
+	return readUUIDMap(labelsPath())
+}
+
+// setLabel stores label for uuid; an empty label restores the derived title.
+func setLabel(uuid, label string) error {
+	return setUUIDValue(labelsPath(), uuid, label)
+}
+
+// applyLabels puts the labels in place of the titles of chats, keeping the
+// derived title in DerivedTitle.
+func applyLabels(chats []Chat, labels map[string]string) {
+	for i := range chats {
+		if label, ok := labels[chats[i].UUID]; ok {
+			chats[i].DerivedTitle = chats[i].Title
+			chats[i].Title = label
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -13,6 +15,28 @@ func main() {
 	// Parse command-line flags
 	updateFlag := flag.Bool("update", false, "Check for updates and install if available")
 	versionFlag := flag.Bool("version", false, "Show current version")
+	excludeFileFlag := flag.String("exclude-file", "", "File of UUIDs or glob patterns to protect from deletion (overrides config)")
+	deleteFlag := flag.String("delete", "", "Delete the given comma-separated chat UUIDs without starting the TUI")
+	keepRecentFlag := flag.Int("keep-recent", 0, "Delete all but the newest N chats of every project without starting the TUI")
+	deadBranchesFlag := flag.Bool("delete-dead-branches", false, "Delete every chat whose git branch no longer exists in its project without starting the TUI")
+	deleteMatchingFlag := flag.String("delete-matching", "", "Delete every chat whose title contains the text (or matches /regex/) without starting the TUI")
+	yesFlag := flag.Bool("yes", false, "Skip the confirmation prompt in non-interactive modes (or set CLAUDE_CHATS_ASSUME_YES=1)")
+	summaryFlag := flag.Bool("summary", false, "Print a JSON summary of non-interactive results to stderr")
+	exportMDFlag := flag.String("export-md", "", "Print the chat with the given UUID as Markdown to stdout")
+	exportJSONLFlag := flag.String("export-jsonl", "", "Print the raw JSONL of the chat with the given UUID to stdout")
+	purgeIndexFlag := flag.Bool("purge-index", false, "Remove sessions-index.json entries whose chat files no longer exist")
+	sizesFlag := flag.Bool("sizes", false, "Print the disk size of every chat")
+	listFlag := flag.Bool("list", false, "Print every chat (UUID, title, project), newest first")
+	sortFlag := flag.String("sort", "date", "With -list, the order: date (newest first) or size (largest first)")
+	showHistoryFlag := flag.Bool("show-history", false, "Print the log of every chat deleted so far (~/.config/claude-chats/history.log)")
+	statsFlag := flag.Bool("stats", false, "Print totals: chats, size, empty sessions, activity range and per-project shares")
+	jsonFlag := flag.Bool("json", false, "With -sizes or -list, print one JSON object per chat; with -stats, print the totals as JSON; with -show-history, print the log's JSON lines")
+	refreshIndexFlag := flag.Bool("refresh-index", false, "Rebuild the related-file index used with cache_related_files")
+	filesFlag := flag.String("files", "", "Print the files that deleting the chat with the given UUID would remove")
+	emptyTrashFlag := flag.Bool("empty-trash", false, "Permanently remove every trash batch")
+	restoreTrashFlag := flag.String("restore-trash", "", "Restore the chats of the given trash batch")
+	readOnlyFlag := flag.Bool("readonly", false, "Browse without any possibility of deletion")
+	projectFlag := flag.String("project", "", "Only scan chats in this project directory (name under projects/)")
 	flag.Parse()
 
 	// Show version
@@ -21,8 +45,15 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Everything the tool keeps lives under the home directory; without one
+	// the paths would silently be relative to wherever it was started.
+	if configPath == "" {
+		fmt.Println("Error: cannot determine the home directory; set HOME to run claude-chats")
+		os.Exit(1)
+	}
+
 	// Load or create config
-	config, err := loadConfig()
+	config, _, err := loadConfig()
 	if err != nil {
 		// First run - prompt for directory
 		dir, err := promptForClaudeDir()
@@ -58,17 +89,28 @@ func main() {
 		config.AutoUpdates = true
 	}
 
-	// Initialize paths from config
-	initializePaths(config.ClaudeDir)
+	// Initialize paths, date format and theme from config
+	warnings := applyConfig(config)
+
+	if *projectFlag != "" {
+		if err := validateProjectScope(*projectFlag); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		projectScope = *projectFlag
+	}
+
+	for _, w := range warnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
 
 	// Manual update check
 	if *updateFlag {
 		fmt.Printf("Checking for updates...\n")
-		if newVersion := checkForUpdate(); newVersion != "" {
-			if promptAndUpdate(newVersion) {
+		if newVersion := checkForUpdate(config.UpdateChannel); newVersion != "" {
+			if promptAndUpdate(newVersion, config.AutoRestart) {
 				// User declined or update failed
-				config.LastUpdateCheck = time.Now().Unix()
-				saveConfig(config)
+				saveState("last_update_check", time.Now().Unix())
 			}
 		} else {
 			fmt.Printf("You're up to date (v%s)\n", CurrentVersion)
@@ -76,30 +118,203 @@ func main() {
 		return
 	}
 
-	// Automatic update check (on startup)
+	// Load protected UUID patterns (flag wins over config)
+	excludeFile := config.ExcludeFile
+	if *excludeFileFlag != "" {
+		excludeFile = *excludeFileFlag
+	}
+	var protect []string
+	if excludeFile != "" {
+		protect, err = loadExcludeFile(excludeFile)
+		if err != nil {
+			fmt.Printf("Error reading exclude file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Noted chats are protected too; the TUI applies notes itself so that
+	// editing one takes effect immediately.
+	// An unreadable notes file refuses every deletion: there's no telling
+	// which chats it protects.
+	batchProtect := protect
+	var notesErr error
+	if config.protectNoted() {
+		notes, err := loadNotes()
+		if err != nil {
+			notesErr = fmt.Errorf("cannot read %s: %w; fix or remove it to delete chats", notesPath(), err)
+		}
+		batchProtect = append(notedUUIDs(notes), protect...)
+	}
+
+	readOnly := config.ReadOnly || *readOnlyFlag
+	yes := assumeYes(*yesFlag) // headless modes only; the TUI always asks
+
+	if notesErr != nil && (*deleteFlag != "" || *deleteMatchingFlag != "" || *keepRecentFlag != 0 || *deadBranchesFlag) {
+		fmt.Printf("Error: %v\n", notesErr)
+		os.Exit(1)
+	}
+
+	// Non-interactive delete
+	if *deleteFlag != "" {
+		if readOnly {
+			fmt.Println("Error: read-only mode, -delete is disabled")
+			os.Exit(1)
+		}
+		os.Exit(runDelete(splitList(*deleteFlag), batchProtect, yes, config.VerifyDeletes, *summaryFlag))
+	}
+
+	if *deleteMatchingFlag != "" {
+		if readOnly {
+			fmt.Println("Error: read-only mode, -delete-matching is disabled")
+			os.Exit(1)
+		}
+		os.Exit(runDeleteMatching(*deleteMatchingFlag, batchProtect, yes, config.VerifyDeletes, *summaryFlag))
+	}
+
+	if *keepRecentFlag != 0 {
+		if readOnly {
+			fmt.Println("Error: read-only mode, -keep-recent is disabled")
+			os.Exit(1)
+		}
+		if *keepRecentFlag < 0 {
+			fmt.Println("Error: -keep-recent needs a positive number")
+			os.Exit(1)
+		}
+		os.Exit(runKeepRecent(*keepRecentFlag, batchProtect, yes, config.VerifyDeletes, *summaryFlag))
+	}
+
+	if *deadBranchesFlag {
+		if readOnly {
+			fmt.Println("Error: read-only mode, -delete-dead-branches is disabled")
+			os.Exit(1)
+		}
+		os.Exit(runDeleteDeadBranches(batchProtect, yes, config.VerifyDeletes, *summaryFlag))
+	}
+
+	if *purgeIndexFlag {
+		if readOnly {
+			fmt.Println("Error: read-only mode, -purge-index is disabled")
+			os.Exit(1)
+		}
+		os.Exit(runPurgeIndex(yes))
+	}
+
+	if *emptyTrashFlag {
+		if readOnly {
+			fmt.Println("Error: read-only mode, -empty-trash is disabled")
+			os.Exit(1)
+		}
+		os.Exit(runEmptyTrash(yes))
+	}
+
+	if *restoreTrashFlag != "" {
+		os.Exit(runRestoreTrash(*restoreTrashFlag))
+	}
+
+	if *refreshIndexFlag {
+		os.Exit(runRefreshIndex())
+	}
+
+	if *sizesFlag {
+		os.Exit(runSizes(*jsonFlag))
+	}
+
+	if *listFlag {
+		os.Exit(runList(*sortFlag, *jsonFlag))
+	}
+
+	if *statsFlag {
+		os.Exit(runStats(*jsonFlag))
+	}
+
+	if *showHistoryFlag {
+		os.Exit(runShowHistory(*jsonFlag))
+	}
+
+	if *filesFlag != "" {
+		os.Exit(runListFiles(*filesFlag))
+	}
+
+	// Non-interactive export
+	if *exportMDFlag != "" {
+		os.Exit(runExportMarkdown(*exportMDFlag))
+	}
+	if *exportJSONLFlag != "" {
+		os.Exit(runExportJSONL(*exportJSONLFlag))
+	}
+
+	// Automatic update check (on startup, interactive runs only)
 	if config.AutoUpdates &&
 		os.Getenv("CLAUDE_CHATS_DISABLE_AUTOUPDATER") != "1" &&
 		shouldCheckUpdate(config.LastUpdateCheck, config.UpdateCheckIntervalHrs) {
 
-		if newVersion := checkForUpdate(); newVersion != "" {
+		if newVersion := checkForUpdate(config.UpdateChannel); newVersion != "" {
 			// Prompt for update
-			if promptAndUpdate(newVersion) {
+			if promptAndUpdate(newVersion, config.AutoRestart) {
 				// User declined or update failed, save check time
-				config.LastUpdateCheck = time.Now().Unix()
-				saveConfig(config)
+				saveState("last_update_check", time.Now().Unix())
 			}
 			// If update succeeded, program exits in promptAndUpdate
 		} else {
 			// No update available, save check time
-			config.LastUpdateCheck = time.Now().Unix()
-			saveConfig(config)
+			saveState("last_update_check", time.Now().Unix())
+		}
+	}
+
+	// Auto-purge empty chats to the trash (interactive runs only)
+	var purgeStatus string
+	if config.AutoPurgeEmpty && !readOnly && notesErr != nil {
+		fmt.Printf("Warning: auto-purge skipped: %v\n", notesErr)
+	} else if config.AutoPurgeEmpty && !readOnly {
+		below := config.PurgeBelowLines
+		if below <= 0 {
+			below = defaultPurgeBelowLines
+		}
+		count, batch, err := autoPurgeEmpty(below, batchProtect)
+		if err != nil {
+			fmt.Printf("Warning: auto-purge stopped: %v\n", err)
+		}
+		if count > 0 {
+			purgeStatus = fmt.Sprintf("Auto-purged %d empty chat(s) to trash; undo with: claude-chats -restore-trash %s", count, batch)
+			fmt.Println(purgeStatus)
+		}
+	}
+
+	// Drop trash batches past their retention (interactive runs only)
+	if config.TrashRetentionDays > 0 && !readOnly {
+		if batches, err := listTrashBatches(); err == nil {
+			expired := expiredTrashBatches(batches, config.TrashRetentionDays, time.Now())
+			if removed, freed, err := removeTrashBatches(expired); removed > 0 || err != nil {
+				fmt.Printf("Removed %d trash batch(es) older than %d days, freed %s\n", removed, config.TrashRetentionDays, formatBytes(freed))
+				if err != nil {
+					fmt.Printf("Warning: %v\n", err)
+				}
+			}
 		}
 	}
 
 	// Run TUI
-	p := tea.NewProgram(initialModel(config), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	m := initialModel(config, protect)
+	m.copiedMsg = purgeStatus
+	m.readOnly = readOnly
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithoutSignalHandler())
+
+	// Route SIGINT/SIGTERM through the model so a running deletion finishes
+	// its current chat instead of being killed half-way.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		for range sigs {
+			p.Send(interruptMsg{})
+		}
+	}()
+
+	final, err := p.Run()
+	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	if fm, ok := final.(model); ok && fm.exitReport != "" {
+		fmt.Println(fm.exitReport)
+	}
 }
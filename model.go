@@ -2,7 +2,12 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -18,9 +23,10 @@ const compactModeWidth = 110
 const (
 	tabChats    = 0
 	tabSettings = 1
+	tabTrash    = 2
 )
 
-var tabs = []string{"Chats", "Settings"}
+var tabs = []string{"Chats", "Settings", "Trash"}
 
 var (
 	// Styles
@@ -56,6 +62,10 @@ var (
 			Foreground(adaptiveColor("46", "10")).
 			Bold(true)
 
+	// warningStyle marks chats whose file couldn't be read fully.
+	warningStyle = lipgloss.NewStyle().
+			Foreground(adaptiveColor("214", "3"))
+
 	helpStyle = lipgloss.NewStyle().
 			Foreground(adaptiveColor("241", "8"))
 )
@@ -73,9 +83,73 @@ func adaptiveColor(rich string, fallback string) lipgloss.TerminalColor {
 // Messages
 type deleteCompleteMsg struct {
 	count int
+	chats []Chat // the chats that were deleted
+	// leftovers lists paths still present after deletion (verify_deletes)
+	leftovers []string
+	note      string // appended to the success status, e.g. project dir removal
+	trashed   bool   // chats were moved to the trash, not deleted
+	total     int    // chats the batch set out to delete; more than count if stopped
+	hookErr   string // why post_delete_hook failed; the deletion stands
+	freed     int64  // bytes freed (or moved to the trash)
+	err       string // why the batch stopped part-way; the first count chats are gone
+}
+
+// updateProgressMsg tells the model whether an update is being downloaded or
+// installed. The binary may be replaced and re-executed at the end, so no
+// deletion starts meanwhile; one already running finishes first as usual.
+type updateProgressMsg struct {
+	installing bool
+}
+
+// updateInProgress is the error shown for deleting while an update installs.
+const updateInProgress = "update in progress: deleting is disabled until it finishes"
+
+// interruptMsg is sent by main on SIGINT/SIGTERM so a running deletion can
+// finish its current chat before the program exits.
+type interruptMsg struct{}
+
+// expandedFile is one related file listed under an expanded chat (l).
+type expandedFile struct {
+	name string // relative to the chat's Claude directory where possible
+	size int64
+}
+
+// historyEntry records one chat deleted during this run.
+type historyEntry struct {
+	chat Chat
+	at   time.Time
+}
+
+// scanProgressMsg carries a batch of chats from the startup scan; next
+// delivers the following message (see startScan).
+type scanProgressMsg struct {
+	chats       []Chat
+	done, total int // files scanned so far / in total
+	next        <-chan tea.Msg
+}
+
+// scanDoneMsg ends the startup scan.
+type scanDoneMsg struct {
+	err error
+}
+
+// chatSizesMsg carries a batch of on-disk chat footprints, keyed by
+// chatSizeKey, for the SIZE column and ranking projects by size; next
+// delivers the following batch until done reaches total (see
+// computeChatSizes). Batches of an older gen are stale.
+type chatSizesMsg struct {
+	sizes       map[string]int64
+	done, total int
+	gen         int
+	next        <-chan tea.Msg
 }
 
-type errMsg string
+// preflightMsg carries the shared file analysis of the deletion being
+// confirmed (see startPreflight).
+type preflightMsg struct {
+	report preflightReport
+	gen    int
+}
 
 type clearCopiedMsg struct {
 	id int
@@ -91,6 +165,7 @@ type groupRow struct {
 	isHeader bool
 	project  string // project name (set for both headers and chats)
 	chatIdx  int    // index into m.chats (-1 for headers)
+	path     string // decoded project path (headers only)
 }
 
 type model struct {
@@ -99,9 +174,62 @@ type model struct {
 	chats         []Chat
 	cursor        int
 	selected      map[int]bool
+	scanErr       string            // why the last scan failed; distinguishes "unreadable" from "empty"
+	scanning      bool              // the startup scan is still streaming chats in
+	scanned       []Chat            // chats streamed in so far, unfiltered and unsorted
+	scanDone      int               // files scanned so far by the startup scan
+	scanTotal     int               // files the startup scan will read
+	excluded      int               // project directories hidden by exclude_projects
+	protect       []string          // exclude-file patterns; matching chats are protected
+	notes         map[string]string // notes.json as read for the current scan (see readNotes)
+	notesErr      error             // why notes.json couldn't be read; every chat is protected then
+	hideTrivial   bool              // drop chats below hideThreshold() lines from the list
+	hiddenCount   int               // chats dropped by hideTrivial on the last scan
+	window        int               // index into timeWindows; 0 shows all dates
+	outsideWindow int               // chats dropped by the time window on the last scan
+	artifact      int               // index into artifactFilters; 0 shows every chat
+	lacking       int               // chats dropped by the artifact filter on the last scan
+	msgFilter     string            // message count filter (M), e.g. "<=2"; "" shows every chat
+	msgMismatch   int               // chats dropped by msgFilter on the last scan
+	artifactMenu  bool              // m was pressed; the next key picks the artifact filter
+	hideHelp      bool              // the help line(s) under the list are hidden (h)
+	focus         bool              // focus mode (Z): every row but the cursor's is dimmed
+	hOffset       int               // columns the list is scrolled right on narrow terminals (< / >)
+	staged        map[string]string // staging list (x): staging time by UUID, kept across runs
+	expanded      string            // UUID whose related files show under its row while it has the cursor (l)
+	expandedFiles []expandedFile    // those files, measured when expanded
+	lineNumbers   int               // line number gutter: numbersOff, numbersAbsolute or numbersRelative (#)
+	reviewing     map[string]bool   // UUIDs shown in review mode (o); nil shows all
+	count         string            // digits typed so far; see the count handling in update
+	cwd           string            // working directory at startup, for the current-project guard
+	cwdConfirmed  bool              // the extra current-project confirmation was given
+	sizeSort      bool              // grouped view ranks projects by size (z)
+	chatSizes     map[string]int64  // bytes per chat (chatSizeKey); nil until first computed
+	sizing        bool              // a size computation is running
+	sizeDone      int               // chats measured by the running computation
+	sizeTotal     int               // chats the running computation measures
+	sizeGen       int               // bumped by r, so late batches of an older run are dropped
+	unreviewed    int               // chats dropped by review mode on the last scan
+	fullPaths     bool              // show full decoded project paths instead of basenames
+	readOnly      bool              // audit mode: every deletion path is disabled
+	updating      bool              // an update is downloading or installing; deletions wait for it
+	rowCache      rowCache          // rendered chat rows, reset on every scan
 	confirmDelete bool
+	preflight     *preflightReport // shared file analysis of a big pending deletion; nil while it runs
+	preflightGen  int              // bumped per confirmation, so a late analysis of an older one is dropped
+	stepQueue     []int            // chats still to decide one by one (s in the confirm dialog)
+	stepTotal     int              // chats in the one-by-one review when it started
+	stepOrig      []int            // selection before the review, restored by Esc
 	deleting      bool
+	stopDelete    *atomic.Bool // set to stop the running deletion after its current chat
+	quitPending   bool         // quit once the running deletion has stopped
+	exitReport    string       // printed by main after the TUI exits
 	deleted       int
+	deletedNote   string // extra detail for the "Deleted N chat(s)" status
+	trashed       bool   // the last deletion moved chats to the trash
+	deleteProject string // set when the pending deletion retires a whole project
+	deleteInView  bool   // set when the pending deletion is everything in view (D)
+	deleteChoice  string // "trash" or "permanent" when picked with t / P in the confirm dialog
 	error         string
 	width         int
 	height        int
@@ -115,28 +243,521 @@ type model struct {
 	// so the selection state doesn't leak into the next d gesture.
 	autoSelected bool
 
+	// Set while a select-all above select_all_confirm_above waits for a
+	// second a.
+	selectAllPending bool
+
 	// Settings tab
 	settingsCursor int
+	trashPending   []trashBatch // batches awaiting the "Empty trash" confirmation
+	trashHint      string       // outcome shown next to "Empty trash"
+	emptying       bool         // confirmed batches are being removed
+
+	// Trash tab (see trashview.go)
+	trashBatches  []trashBatch    // newest first, loaded when the tab opens
+	trashCursor   int             // index into trashBatches
+	trashSelected map[string]bool // batch names marked with Space
+	trashStatus   string          // outcome of the last restore or deletion
+	trashLoading  bool            // the batches are being read
+	restoring     bool            // a restore runs in the background
 
 	// Grouped view state
 	grouped          bool
 	expandedProjects map[string]bool
 	groupRows        []groupRow // virtual row list built from chats + expanded state
+
+	// Chats deleted during this run, oldest first, and the bytes they freed
+	history      []historyEntry
+	sessionFreed int64
+
+	// Full-screen overlay (help, details); overlayNone when closed
+	overlay       int
+	overlayTitle  string
+	overlayLines  []string
+	overlayScroll int
+
+	// Single-line text prompt (see prompt.go)
+	promptAction int
+	promptLabel  string
+	promptValue  string
+	promptChat   int // chat index the prompt acts on
 }
 
-func initialModel(cfg *Config) model {
+func initialModel(cfg *Config, protect []string) model {
 	grouped := cfg != nil && cfg.GroupByProject
+	staged, stagedErr := loadStaged()
+	if staged == nil {
+		staged = make(map[string]string)
+	}
 	m := model{
 		cfg:              cfg,
-		chats:            findAllChats(),
 		selected:         make(map[int]bool),
+		protect:          protect,
+		cwd:              workingDir(),
+		hideTrivial:      cfg != nil && cfg.HideBelowLines > 0,
+		hideHelp:         cfg != nil && cfg.HideHelp,
+		fullPaths:        cfg != nil && cfg.FullPaths,
+		staged:           staged,
 		grouped:          grouped,
 		expandedProjects: make(map[string]bool),
+		trashSelected:    make(map[string]bool),
+		scanning:         true, // Init starts the scan
+		excluded:         countExcludedProjects(),
+	}
+	m.readNotes()
+	if stagedErr != nil {
+		m.error = fmt.Sprintf("cannot read %s: %v; starting with nothing staged", stagedPath(), stagedErr)
+	}
+	return m
+}
+
+// startScan reads the chats in the background. Each message it delivers
+// carries a batch of chats and the channel for the next one, so the list
+// fills in while files are still being read.
+func startScan() tea.Cmd {
+	return func() tea.Msg {
+		ch := make(chan tea.Msg)
+		go func() {
+			err := streamChats(func(chats []Chat, done, total int) {
+				ch <- scanProgressMsg{chats: chats, done: done, total: total, next: ch}
+			})
+			ch <- scanDoneMsg{err: err}
+		}()
+		return <-ch
+	}
+}
+
+func waitForScan(ch <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-ch
 	}
+}
+
+// showScanned lists the chats streamed in so far, keeping the selection.
+func (m *model) showScanned(err error) {
+	chats := append([]Chat(nil), m.scanned...)
+	sortChats(chats)
+	uuids, auto := m.selectedUUIDs(), m.autoSelected
+	m.setChats(chats, err)
+	m.selectUUIDs(uuids)
+	m.autoSelected = auto
 	if m.grouped {
 		m.rebuildGroupRows()
 	}
-	return m
+}
+
+// reloadConfig re-reads the config file and applies it without restarting:
+// theme, date format, thresholds and Claude directories take effect and the
+// list is rescanned, keeping the selection. Read-only mode, once on, stays on.
+func (m *model) reloadConfig() {
+	cfg, path, err := loadConfig()
+	if err != nil {
+		m.error = "cannot reload config: " + err.Error()
+		return
+	}
+	warnings := applyConfig(cfg)
+	m.cfg = cfg
+	m.readOnly = m.readOnly || cfg.ReadOnly
+	if m.grouped != cfg.GroupByProject {
+		m.grouped = cfg.GroupByProject
+		m.groupRows = nil
+		m.cursor, m.scrollOffset = 0, 0
+	}
+
+	m.excluded = countExcludedProjects()
+	uuids := m.selectedUUIDs()
+	m.loadChats()
+	m.selectUUIDs(uuids)
+	if m.grouped {
+		m.rebuildGroupRows()
+	}
+	m.moveCursorTo(m.cursor)
+
+	m.error, m.deleted = "", 0
+	m.copiedMsg = "Config reloaded from " + path
+	if len(warnings) > 0 {
+		m.error = strings.Join(warnings, "; ")
+	}
+}
+
+// loadChats rescans disk synchronously, superseding a startup scan still in
+// progress.
+func (m *model) loadChats() {
+	chats, err := findAllChats()
+	m.scanning = false
+	m.scanned = nil
+	m.readNotes()
+	m.setChats(chats, err)
+}
+
+// readNotes reads notes.json for the scan about to run, so the batches of a
+// streaming scan don't read it again each.
+func (m *model) readNotes() {
+	m.notes, m.notesErr = loadNotes()
+}
+
+// setChats shows the scanned chats: it applies exclude-file protection and
+// notes and drops trivial chats, chats outside the time window and, in
+// review mode, unselected chats when those filters are on.
+func (m *model) setChats(chats []Chat, err error) {
+	m.chats = chats
+	m.scanErr = ""
+	if err != nil {
+		m.scanErr = err.Error()
+	}
+	markProtected(m.chats, m.protect)
+	applyNotes(m.chats, m.notes, m.cfg.protectNoted())
+	if m.notesErr != nil && m.cfg.protectNoted() {
+		// Without the notes there's no telling which chats they protect
+		for i := range m.chats {
+			m.chats[i].Protected = true
+		}
+		m.error = fmt.Sprintf("cannot read %s: %v; every chat is protected until it is fixed", notesPath(), m.notesErr)
+	}
+	m.rowCache = make(rowCache)
+
+	m.hiddenCount, m.outsideWindow, m.unreviewed, m.lacking, m.msgMismatch = 0, 0, 0, 0, 0
+	threshold := m.hideThreshold()
+	messages, _ := messageFilter(m.msgFilter) // validated when it was typed
+	since := windowStart(m.window, time.Now())
+	kept := m.chats[:0]
+	for _, chat := range m.chats {
+		switch {
+		case m.hideTrivial && chat.LineCount < threshold:
+			m.hiddenCount++
+		case !since.IsZero() && chat.Time.Before(since):
+			m.outsideWindow++
+		case m.artifact != 0 && !artifactFilters[m.artifact].has(chat):
+			m.lacking++
+		case messages != nil && !messages(chat.MessageCount):
+			m.msgMismatch++
+		case m.reviewing != nil && !m.reviewing[chat.UUID]:
+			m.unreviewed++
+		default:
+			kept = append(kept, chat)
+		}
+	}
+	m.chats = kept
+}
+
+// activeFilters describes every way the list currently deviates from showing
+// all chats, for the stats line.
+func (m model) activeFilters() []string {
+	var filters []string
+	if projectScope != "" {
+		filters = append(filters, "Project: "+projectScope)
+	}
+	if m.excluded > 0 {
+		filters = append(filters, fmt.Sprintf("Excluded: %d project(s)", m.excluded))
+	}
+	if m.hideTrivial {
+		filters = append(filters, fmt.Sprintf("Hidden: %d", m.hiddenCount))
+	}
+	if label := timeWindows[m.window].label; label != "" {
+		filters = append(filters, label)
+	}
+	if m.artifact != 0 {
+		filters = append(filters, "Has: "+artifactFilters[m.artifact].label)
+	}
+	if m.msgFilter != "" {
+		filters = append(filters, "Messages "+m.msgFilter)
+	}
+	if m.reviewing != nil {
+		filters = append(filters, "Reviewing selection")
+	}
+	if m.sizeSort && m.grouped {
+		filters = append(filters, "Sort: size↓")
+	}
+	return filters
+}
+
+// filtersResettable reports whether Esc has runtime filters (or a size sort)
+// to clear. The -project scope is fixed for the run and stays.
+func (m model) filtersResettable() bool {
+	return m.hideTrivial || m.window != 0 || m.artifact != 0 || m.msgFilter != "" || m.reviewing != nil || m.sizeSort
+}
+
+// resetFilters turns off the t, w, m, M and o filters and the z sort,
+// keeping the selection.
+func (m *model) resetFilters() {
+	uuids := m.selectedUUIDs()
+	m.hideTrivial = false
+	m.window = 0
+	m.artifact = 0
+	m.msgFilter = ""
+	m.reviewing = nil
+	m.sizeSort = false
+	m.loadChats()
+	m.selectUUIDs(uuids)
+	m.cursor = 0
+	m.scrollOffset = 0
+	if m.grouped {
+		m.rebuildGroupRows()
+	}
+}
+
+// filteredOut is how many scanned chats the list filters currently hide.
+func (m model) filteredOut() int {
+	return m.hiddenCount + m.outsideWindow + m.lacking + m.msgMismatch + m.unreviewed
+}
+
+// messageFilter parses a message count filter (M): "<=2", ">=100", "<5",
+// ">50", "=0" or a bare number for that exact count. An empty expression
+// keeps every chat (nil).
+func messageFilter(expr string) (func(count int) bool, error) {
+	expr = strings.ReplaceAll(expr, " ", "")
+	if expr == "" {
+		return nil, nil
+	}
+	op := strings.TrimRight(expr, "0123456789")
+	n, err := strconv.Atoi(expr[len(op):])
+	if err != nil {
+		return nil, fmt.Errorf("%q: expected e.g. <=2 or >=100", expr)
+	}
+	switch op {
+	case "<=", "≤":
+		return func(c int) bool { return c <= n }, nil
+	case ">=", "≥":
+		return func(c int) bool { return c >= n }, nil
+	case "<":
+		return func(c int) bool { return c < n }, nil
+	case ">":
+		return func(c int) bool { return c > n }, nil
+	case "=", "":
+		return func(c int) bool { return c == n }, nil
+	}
+	return nil, fmt.Errorf("%q: expected e.g. <=2 or >=100", expr)
+}
+
+// timeWindows are the quick date filters cycled with w.
+var timeWindows = []struct {
+	days  int // 0 = no filter, 1 = since local midnight
+	label string
+}{
+	{0, ""},
+	{1, "Today"},
+	{7, "Last 7 days"},
+	{30, "Last 30 days"},
+}
+
+// windowStart returns the earliest last-activity time the window keeps, or
+// the zero time when window shows everything. Windows start at local midnight
+// so "Last 7 days" means today plus the six days before it.
+func windowStart(window int, now time.Time) time.Time {
+	days := timeWindows[window].days
+	if days == 0 {
+		return time.Time{}
+	}
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return midnight.AddDate(0, 0, -(days - 1))
+}
+
+// defaultHideBelowLines is the trivial-chat threshold used by the t toggle
+// when hide_below_lines is not configured.
+const defaultHideBelowLines = 5
+
+// hideThreshold is the line count below which chats are hidden.
+func (m model) hideThreshold() int {
+	if m.cfg != nil && m.cfg.HideBelowLines > 0 {
+		return m.cfg.HideBelowLines
+	}
+	return defaultHideBelowLines
+}
+
+// projectLabel is how a project is shown: the basename of its decoded path,
+// or the full path when toggled with p. Falls back to the encoded name.
+func (m model) projectLabel(project, path string) string {
+	if path == "" {
+		return project
+	}
+	if m.fullPaths {
+		return path
+	}
+	return filepath.Base(path)
+}
+
+// projectPathOf returns the decoded path of project, from any of its chats.
+func (m model) projectPathOf(project string) string {
+	for _, chat := range m.chats {
+		if chat.Project == project {
+			return chat.ProjectPath
+		}
+	}
+	return ""
+}
+
+// chatProjectLabel is projectLabel for the project of chat.
+// Chats from a Claude directory other than claude_dir name it in brackets.
+func (m model) chatProjectLabel(chat Chat) string {
+	label := m.projectLabel(chat.Project, chat.ProjectPath)
+	if chat.Root.dir != "" && chat.Root.dir != scanRoots()[0].dir {
+		label += " [" + rootLabel(chat.Root.dir) + "]"
+	}
+	return label
+}
+
+// rootBreakdown counts chats per Claude directory, e.g. "(personal/.claude:
+// 200, work/.claude: 140)", or returns "" with a single directory. With
+// skipEmpty, directories without chats are left out.
+func rootBreakdown(chats []Chat, skipEmpty bool) string {
+	roots := scanRoots()
+	if len(roots) < 2 {
+		return ""
+	}
+	counts := make(map[string]int)
+	for _, chat := range chats {
+		root := chat.Root.dir
+		if root == "" {
+			root = roots[0].dir
+		}
+		counts[root]++
+	}
+	var parts []string
+	for _, root := range roots {
+		if skipEmpty && counts[root.dir] == 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d", rootLabel(root.dir), counts[root.dir]))
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// rootLabel shortens a Claude directory for display: "/work/app/.claude"
+// becomes "app/.claude".
+func rootLabel(root string) string {
+	return filepath.Join(filepath.Base(filepath.Dir(root)), filepath.Base(root))
+}
+
+// selectable reports whether the chat at idx may be selected for deletion.
+func (m model) selectable(idx int) bool {
+	return idx < len(m.chats) && !m.chats[idx].Protected
+}
+
+// selectableCount returns how many chats can be selected (i.e. are not protected).
+func (m model) selectableCount() int {
+	n := 0
+	for i := range m.chats {
+		if m.selectable(i) {
+			n++
+		}
+	}
+	return n
+}
+
+// cursorChat returns the index into m.chats of the chat under the cursor, in
+// either layout. ok is false on a project header or an empty list.
+func (m model) cursorChat() (int, bool) {
+	if m.grouped {
+		if m.cursor < len(m.groupRows) && !m.groupRows[m.cursor].isHeader {
+			return m.groupRows[m.cursor].chatIdx, true
+		}
+		return 0, false
+	}
+	return m.cursor, m.cursor < len(m.chats)
+}
+
+// useTrash reports whether deletions move chats to the trash (use_trash).
+func (m model) useTrash() bool {
+	return m.cfg != nil && m.cfg.UseTrash
+}
+
+// trashing reports whether the pending deletion moves chats to the trash:
+// t or P in the confirm dialog overrides use_trash for that one deletion.
+func (m model) trashing() bool {
+	switch m.deleteChoice {
+	case "trash":
+		return true
+	case "permanent":
+		return false
+	}
+	return m.useTrash()
+}
+
+// deletedStatus is the success line shown after a deletion.
+func (m model) deletedStatus() string {
+	if m.trashed {
+		return fmt.Sprintf("✓ Moved %d chat(s) to trash%s", m.deleted, m.deletedNote)
+	}
+	return fmt.Sprintf("✓ Deleted %d chat(s)%s", m.deleted, m.deletedNote)
+}
+
+// helpHint is the help line under the chat list; ? lists every key.
+const helpHint = "? for help"
+
+// selectedUUIDs returns the UUIDs of the selected chats.
+func (m model) selectedUUIDs() []string {
+	var uuids []string
+	for idx := range m.selected {
+		uuids = append(uuids, m.chats[idx].UUID)
+	}
+	return uuids
+}
+
+// selectUUIDs replaces the selection with every selectable chat whose UUID
+// is in uuids and returns how many were found. Copies of a UUID in several
+// projects are all selected.
+func (m *model) selectUUIDs(uuids []string) int {
+	want := make(map[string]bool, len(uuids))
+	for _, uuid := range uuids {
+		want[uuid] = true
+	}
+	m.selected = make(map[int]bool)
+	m.autoSelected = false
+	found := make(map[string]bool)
+	for i, chat := range m.chats {
+		if want[chat.UUID] && m.selectable(i) {
+			m.selected[i] = true
+			found[chat.UUID] = true
+		}
+	}
+	return len(found)
+}
+
+// cursorProject returns the project of the row under the cursor: the chat's
+// project, or the header's in grouped view.
+func (m model) cursorProject() (string, bool) {
+	if m.grouped {
+		if m.cursor < len(m.groupRows) {
+			return m.groupRows[m.cursor].project, true
+		}
+		return "", false
+	}
+	if m.cursor < len(m.chats) {
+		return m.chats[m.cursor].Project, true
+	}
+	return "", false
+}
+
+// toggleProjectSelection selects every selectable chat of project, or
+// deselects them all when they are already selected.
+func (m *model) toggleProjectSelection(project string) {
+	indices := m.chatIndicesForProject(project)
+	allSelected := true
+	for _, idx := range indices {
+		if !m.selected[idx] {
+			allSelected = false
+			break
+		}
+	}
+	for _, idx := range indices {
+		if allSelected {
+			delete(m.selected, idx)
+		} else {
+			m.selected[idx] = true
+		}
+	}
+}
+
+// flashStatus shows a success message in the status line for two seconds.
+func (m model) flashStatus(text string) (tea.Model, tea.Cmd) {
+	m.copyTimer++
+	currentTimer := m.copyTimer
+	m.copiedMsg = text
+	m.error = ""
+	m.deleted = 0
+	return m, tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
+		return clearCopiedMsg{id: currentTimer}
+	})
 }
 
 // rebuildGroupRows creates the virtual row list from chats grouped by project.
@@ -159,9 +780,17 @@ func (m *model) rebuildGroupRows() {
 		chatsByProject[chat.Project] = append(chatsByProject[chat.Project], i)
 	}
 
+	if m.sizeSort && m.chatSizes != nil {
+		sizes := m.projectSizes()
+		sort.SliceStable(projects, func(i, j int) bool {
+			return sizes[projects[i]] > sizes[projects[j]]
+		})
+	}
+
 	var rows []groupRow
 	for _, proj := range projects {
-		rows = append(rows, groupRow{isHeader: true, project: proj, chatIdx: -1})
+		path := m.chats[chatsByProject[proj][0]].ProjectPath
+		rows = append(rows, groupRow{isHeader: true, project: proj, chatIdx: -1, path: path})
 		if m.expandedProjects[proj] {
 			for _, idx := range chatsByProject[proj] {
 				rows = append(rows, groupRow{isHeader: false, project: proj, chatIdx: idx})
@@ -171,11 +800,123 @@ func (m *model) rebuildGroupRows() {
 	m.groupRows = rows
 }
 
-// chatIndicesForProject returns all chat indices belonging to a project.
+// chatSizeKey identifies a chat in chatSizes; a UUID alone is not unique
+// across projects.
+func chatSizeKey(chat Chat) string {
+	return chat.Root.dir + "\x00" + chat.Project + "\x00" + chat.UUID
+}
+
+// refreshSummary describes how the list changed across a refresh, e.g.
+// "2 new chats, 1 grew by 40 lines". Chats are matched like chatSizeKey.
+func refreshSummary(before, after []Chat) string {
+	old := make(map[string]int, len(before))
+	for _, chat := range before {
+		old[chatSizeKey(chat)] = chat.LineCount
+	}
+	added, grew, growth := 0, 0, 0
+	for _, chat := range after {
+		key := chatSizeKey(chat)
+		lines, ok := old[key]
+		if !ok {
+			added++
+			continue
+		}
+		delete(old, key)
+		if chat.LineCount > lines {
+			grew++
+			growth += chat.LineCount - lines
+		}
+	}
+	plural := func(n int, word string) string {
+		if n == 1 {
+			return word
+		}
+		return word + "s"
+	}
+	var parts []string
+	if added > 0 {
+		parts = append(parts, fmt.Sprintf("%d new %s", added, plural(added, "chat")))
+	}
+	if grew > 0 {
+		parts = append(parts, fmt.Sprintf("%d grew by %d %s", grew, growth, plural(growth, "line")))
+	}
+	if len(old) > 0 {
+		parts = append(parts, fmt.Sprintf("%d gone", len(old)))
+	}
+	if len(parts) == 0 {
+		return "No changes since the last scan"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// projectSizes sums chatSizes per project over the current list. Chats
+// scanned after the sizes were computed count as 0.
+func (m model) projectSizes() map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, chat := range m.chats {
+		sizes[chat.Project] += m.chatSizes[chatSizeKey(chat)]
+	}
+	return sizes
+}
+
+// startSizes measures the listed chats whose size isn't known yet; with
+// refresh every size is measured again. It returns nil when there is nothing
+// to measure or a computation is already running.
+func (m *model) startSizes(refresh bool) tea.Cmd {
+	if refresh {
+		m.chatSizes = nil
+		m.sizing = false
+		m.sizeGen++
+	}
+	if m.chatSizes == nil {
+		m.chatSizes = make(map[string]int64)
+	}
+	if m.sizing {
+		return nil
+	}
+	var missing []Chat
+	for _, chat := range m.chats {
+		if _, ok := m.chatSizes[chatSizeKey(chat)]; !ok {
+			missing = append(missing, chat)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	m.sizing = true
+	m.sizeDone, m.sizeTotal = 0, len(missing)
+	return computeChatSizes(missing, m.sizeGen)
+}
+
+// computeChatSizes measures every related file of chats in the background and
+// streams the sizes in batches; this walks file-history and tool-results, so
+// it can take a while.
+func computeChatSizes(chats []Chat, gen int) tea.Cmd {
+	return func() tea.Msg {
+		ch := make(chan tea.Msg)
+		go func() {
+			const batchSize = 25
+			sizes := make(map[string]int64)
+			done := 0
+			measureChats(chats, func(chat Chat, size int64) {
+				sizes[chatSizeKey(chat)] = size
+				done++
+				if len(sizes) == batchSize || done == len(chats) {
+					ch <- chatSizesMsg{sizes: sizes, done: done, total: len(chats), gen: gen, next: ch}
+					sizes = make(map[string]int64)
+				}
+			})
+		}()
+		return <-ch
+	}
+}
+
+// chatIndicesForProject returns the selectable chat indices belonging to a
+// project. Protected chats are skipped so project-wide actions never pick them.
 func (m model) chatIndicesForProject(project string) []int {
 	var indices []int
 	for i, chat := range m.chats {
-		if chat.Project == project {
+		if chat.Project == project && m.selectable(i) {
 			indices = append(indices, i)
 		}
 	}
@@ -200,7 +941,38 @@ func (m model) renderTabBar() string {
 	if m.tab != tabChats {
 		return left
 	}
-	stats := dimStyle.Render(fmt.Sprintf("Total: %d | Selected: %d", len(m.chats), len(m.selected)))
+	statsText := fmt.Sprintf("Total: %d", len(m.chats))
+	if breakdown := rootBreakdown(m.chats, false); breakdown != "" {
+		statsText += " " + breakdown
+	}
+	statsText += fmt.Sprintf(" | Selected: %d", len(m.selected))
+	if len(m.staged) > 0 {
+		statsText += fmt.Sprintf(" | Staged: %d", len(m.staged))
+	}
+	if len(m.history) > 0 {
+		statsText += " | " + m.sessionStatus()
+	}
+	if m.count != "" {
+		statsText += " | Go to: " + m.count
+	}
+	if m.scanning {
+		statsText += " | " + m.scanStatus()
+	} else if m.sizing {
+		statsText += fmt.Sprintf(" | Sizes %s %d/%d", progressBar(m.sizeDone, m.sizeTotal, 10), m.sizeDone, m.sizeTotal)
+	}
+	if filters := m.activeFilters(); len(filters) > 0 {
+		statsText += " | [" + strings.Join(filters, "] [") + "]"
+		if m.filtersResettable() {
+			statsText += " Esc: reset"
+		}
+	}
+	if m.readOnly {
+		statsText = "READ-ONLY | " + statsText
+	}
+	if m.updating {
+		statsText = "UPDATING | " + statsText
+	}
+	stats := dimStyle.Render(statsText)
 	width := m.width
 	if width < 75 {
 		width = 75
@@ -214,8 +986,15 @@ func (m model) renderTabBar() string {
 
 func (m model) visibleHeight() int {
 	fixed := 9 // tabbar(1) + sep(1) + col-header(1) + sep(1) + bottom-sep(1) + help(1) + scroll(0-1) + status(0-1)
-	if m.width < compactModeWidth {
-		fixed = 10 // compact: +1 for extra help line
+	dialog := m.confirmDelete || m.selectAllPending || m.artifactMenu || m.promptAction != promptNone
+	if m.width < compactModeWidth && dialog {
+		fixed = 10 // compact: a dialog may wrap onto a second line
+	}
+	if m.confirmDelete {
+		fixed += len(m.confirmRecap()) // recap lines above the confirm question
+	}
+	if m.helpHidden() {
+		fixed -= 1 // the help line
 	}
 	h := m.height - fixed
 	if h < 1 {
@@ -224,47 +1003,285 @@ func (m model) visibleHeight() int {
 	return h
 }
 
+// listHeight is how many list rows fit once the expanded chat's files (l)
+// have taken theirs.
+func (m model) listHeight() int {
+	return max(m.visibleHeight()-len(m.expandedRows(m.width)), 1)
+}
+
+// relatedFootprint returns the existing related files of chat with their
+// sizes, for expanding it in the list.
+func relatedFootprint(chat Chat) []expandedFile {
+	root := chat.root()
+	var files []expandedFile
+	for _, file := range findRelatedFiles(root, chat.UUID, chat.Project) {
+		if _, err := os.Lstat(file); err != nil {
+			continue
+		}
+		name := file
+		if rel, err := filepath.Rel(root.dir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+		files = append(files, expandedFile{name: name, size: pathSize(file)})
+	}
+	return files
+}
+
+// expandedRows renders the related files of the chat under the cursor as an
+// indented tree below its row when it is expanded (l), nil otherwise. The
+// tree takes at most half the list; the rest is summed up as "… N more".
+func (m model) expandedRows(width int) []string {
+	idx, ok := m.cursorChat()
+	if !ok || m.expanded == "" || m.chats[idx].UUID != m.expanded {
+		return nil
+	}
+	files := m.expandedFiles
+	if len(files) == 0 {
+		return []string{dimStyle.Render("      └─ (no files)")}
+	}
+	more := 0
+	if limit := max(m.visibleHeight()/2, 1); len(files) > limit {
+		more = len(files) - (limit - 1)
+		files = files[:limit-1]
+	}
+	var rows []string
+	for i, f := range files {
+		branch := "├─"
+		if i == len(files)-1 && more == 0 {
+			branch = "└─"
+		}
+		line := fmt.Sprintf("      %s %9s  %s", branch, formatBytes(f.size), f.name)
+		rows = append(rows, dimStyle.Render(runewidth.Truncate(line, max(width, 20), "…")))
+	}
+	if more > 0 {
+		rows = append(rows, dimStyle.Render(fmt.Sprintf("      └─ … %d more", more)))
+	}
+	return rows
+}
+
 func (m model) Init() tea.Cmd {
-	return nil
+	if m.scanning {
+		return tea.Batch(tea.SetWindowTitle(m.windowTitle()), startScan())
+	}
+	return tea.SetWindowTitle(m.windowTitle())
 }
 
+// windowTitle is the terminal window/tab title reflecting the list state.
+func (m model) windowTitle() string {
+	return fmt.Sprintf("claude-chats — %d chats, %d selected", len(m.chats), len(m.selected))
+}
+
+// Update wraps update to keep the terminal title in sync: whenever a message
+// changes the chat count or selection, a SetWindowTitle command is added.
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	before := m.windowTitle()
+	next, cmd := m.update(msg)
+	if after := next.(model).windowTitle(); after != before {
+		cmd = tea.Batch(cmd, tea.SetWindowTitle(after))
+	}
+	// Once sizes are known, chats a rescan brings in (R, filters) are
+	// measured too.
+	if nm := next.(model); nm.chatSizes != nil && !nm.sizing && !nm.scanning {
+		if sizes := nm.startSizes(false); sizes != nil {
+			next, cmd = nm, tea.Batch(cmd, sizes)
+		}
+	}
+	// Whichever key asked to confirm a big deletion, analyze it meanwhile.
+	if nm := next.(model); nm.confirmDelete && !m.confirmDelete && len(nm.selected) >= preflightMin {
+		next, cmd = nm, tea.Batch(cmd, nm.startPreflight())
+	}
+	return next, cmd
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 		return m, nil
 
+	case updateProgressMsg:
+		m.updating = msg.installing
+		return m, nil
+
+	case interruptMsg:
+		if m.deleting {
+			return m.stopAndQuit()
+		}
+		return m, tea.Quit
+
 	case tea.KeyMsg:
+		// While a deletion runs only quitting is possible, and it waits for
+		// the chat in progress so no chat is left half-deleted.
+		if m.deleting {
+			if s := msg.String(); s == "ctrl+c" || s == "q" {
+				return m.stopAndQuit()
+			}
+			return m, nil
+		}
+
+		// An open overlay captures every key until it is closed
+		if m.overlay != overlayNone {
+			return m.updateOverlay(msg)
+		}
+
+		// So does the text prompt
+		if m.promptAction != promptNone {
+			return m.updatePrompt(msg)
+		}
+
 		// Confirmation dialog intercepts esc before global keys
 		if m.confirmDelete {
+			if m.stepQueue != nil {
+				return m.updateStep(msg)
+			}
 			switch msg.String() {
-			case "enter":
-				return m, m.deleteSelectedChats()
-			case "esc", "n":
-				m.confirmDelete = false
-				if m.autoSelected {
-					m.selected = make(map[int]bool)
-					m.autoSelected = false
+			case "s":
+				m.stepOrig = m.selectedIndices()
+				m.stepQueue = m.selectedIndices()
+				m.stepTotal = len(m.stepQueue)
+				m.cwdConfirmed = false
+				return m, nil
+			case "enter", "t", "P":
+				switch msg.String() {
+				case "t":
+					m.deleteChoice = "trash"
+				case "P":
+					m.deleteChoice = "permanent"
+				}
+				// Deleting chats of the project we're sitting in takes a
+				// second key press.
+				if !m.cwdConfirmed && m.selectedInCWD() > 0 {
+					m.cwdConfirmed = true
+					return m, nil
+				}
+				if m.updating {
+					m.error = updateInProgress
+					return m, nil
 				}
+				m.cwdConfirmed = false
+				m.deleting = true
+				m.stopDelete = new(atomic.Bool)
+				return m, m.deleteSelectedChats(m.stopDelete)
+			case "esc", "n", "q":
+				m.cancelDelete()
 			}
 			return m, nil
 		}
 
-		// Global keys
-		switch msg.String() {
-		case "ctrl+c", "q", "esc":
-			return m, tea.Quit
-		case "left":
-			if m.tab > 0 {
-				m.tab--
+		// The artifact filter menu takes the next key: a digit picks a
+		// filter, anything else closes the menu.
+		if m.artifactMenu {
+			m.artifactMenu = false
+			if s := msg.String(); len(s) == 1 && s[0] >= '0' && int(s[0]-'0') < len(artifactFilters) {
+				m.artifact = int(s[0] - '0')
+				uuids := m.selectedUUIDs()
+				m.loadChats()
+				m.selectUUIDs(uuids)
+				m.cursor = 0
+				m.scrollOffset = 0
+				if m.grouped {
+					m.rebuildGroupRows()
+				}
 			}
 			return m, nil
-		case "right":
-			if m.tab < len(tabs)-1 {
-				m.tab++
+		}
+
+		// Digits build a count (vim style): Enter then jumps to that row,
+		// % to that percentage of the list, j/k move that many rows. Any
+		// other key drops the count.
+		if m.tab == tabChats {
+			if s := msg.String(); len(s) == 1 && s[0] >= '0' && s[0] <= '9' && (s != "0" || m.count != "") {
+				m.count += s
+				return m, nil
+			}
+			if m.count != "" {
+				n, _ := strconv.Atoi(m.count)
+				m.count = ""
+				switch msg.String() {
+				case "enter":
+					m.moveCursorTo(n - 1)
+					return m, nil
+				case "%":
+					rows := len(m.chats)
+					if m.grouped {
+						rows = len(m.groupRows)
+					}
+					m.moveCursorTo(percentRow(n, rows))
+					return m, nil
+				case "down", "j":
+					m.moveCursorTo(m.cursor + n)
+					return m, nil
+				case "up", "k":
+					m.moveCursorTo(m.cursor - n)
+					return m, nil
+				case "esc":
+					return m, nil
+				}
+			}
+		}
+
+		// Select-all on a big list waits for a second a; Esc, n and q only
+		// cancel it, any other key cancels and then acts as usual.
+		if m.selectAllPending {
+			m.selectAllPending = false
+			switch msg.String() {
+			case "a":
+				m.selectAll()
+				return m, nil
+			case "esc", "n", "q":
+				return m, nil
 			}
+		}
+
+		// The "Empty trash" confirmation takes every key, like the delete one
+		if m.trashPending != nil {
+			return m.updateEmptyTrash(msg)
+		}
+
+		// Global keys
+		switch msg.String() {
+		case "ctrl+c", "q", "esc":
+			// With filters on, Esc clears them instead of quitting
+			if msg.String() == "esc" && m.tab == tabChats && m.filtersResettable() {
+				m.resetFilters()
+				return m, nil
+			}
+			return m, tea.Quit
+		case "?":
+			m.openOverlay(overlayHelp, "Keyboard Shortcuts", helpLines(m.readOnly))
 			return m, nil
+		case "H":
+			m.openOverlay(overlayHistory, "Deleted This Session", m.historyLines())
+			return m, nil
+		case "left":
+			if m.tab > 0 {
+				m.tab--
+			}
+			if m.tab == tabTrash {
+				return m, m.loadTrash()
+			}
+			return m, nil
+		case "right":
+			if m.tab < len(tabs)-1 {
+				m.tab++
+			}
+			if m.tab == tabTrash {
+				return m, m.loadTrash()
+			}
+			return m, nil
+		case "u":
+			// Straight to the trash and back
+			if m.tab == tabTrash {
+				m.tab = tabChats
+				return m, nil
+			}
+			m.tab = tabTrash
+			return m, m.loadTrash()
+		}
+
+		if m.tab == tabTrash {
+			return m.updateTrash(msg)
 		}
 
 		// Settings tab
@@ -279,12 +1296,20 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.settingsCursor++
 				}
 			case "enter":
+				if m.settingsCursor == settingEmptyTrash {
+					return m.startEmptyTrash()
+				}
 				if m.cfg != nil {
 					switch m.settingsCursor {
 					case settingAutoUpdates:
 						m.cfg.AutoUpdates = !m.cfg.AutoUpdates
+						saveState("auto_updates", m.cfg.AutoUpdates)
+					case settingUseTrash:
+						m.cfg.UseTrash = !m.cfg.UseTrash
+						saveState("use_trash", m.cfg.UseTrash)
 					case settingGroupByProject:
 						m.cfg.GroupByProject = !m.cfg.GroupByProject
+						saveState("group_by_project", m.cfg.GroupByProject)
 						m.grouped = m.cfg.GroupByProject
 						if m.grouped {
 							m.rebuildGroupRows()
@@ -296,12 +1321,305 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							m.scrollOffset = 0
 						}
 					}
-					saveConfig(m.cfg)
 				}
 			}
 			return m, nil
 		}
 
+		// Chats tab: keys shared by both layouts
+		if m.readOnly && (msg.String() == "d" || msg.String() == "X" || msg.String() == "D") {
+			m.error = "read-only mode: deletion is disabled"
+			return m, nil
+		}
+		if m.updating && (msg.String() == "d" || msg.String() == "X" || msg.String() == "D") {
+			m.error = updateInProgress
+			return m, nil
+		}
+		if msg.String() == "R" {
+			m.reloadConfig()
+			return m, nil
+		}
+		if msg.String() == "a" {
+			// Select all / deselect all toggle
+			if len(m.chats) == 0 {
+				return m, nil // Nothing to select
+			}
+			if len(m.selected) == m.selectableCount() {
+				m.autoSelected = false
+				m.selected = make(map[int]bool)
+			} else if m.selectableCount() > m.cfg.selectAllConfirmAbove() {
+				m.selectAllPending = true
+			} else {
+				m.selectAll()
+			}
+			return m, nil
+		}
+		if msg.String() == "e" {
+			if idx, ok := m.cursorChat(); ok {
+				uuid := m.chats[idx].UUID
+				name := fmt.Sprintf("claude-chat-%s.md", uuid[:min(8, len(uuid))])
+				m.openPrompt(promptExportMarkdown, "Export to", name, idx)
+			}
+			return m, nil
+		}
+		if msg.String() == "E" {
+			if idx, ok := m.cursorChat(); ok {
+				m.openPrompt(promptExportJSONL, "Copy JSONL to", m.chats[idx].UUID+".jsonl", idx)
+			}
+			return m, nil
+		}
+		if msg.String() == "v" {
+			if idx, ok := m.cursorChat(); ok {
+				chat := m.chats[idx]
+				if chat.PlanPath == "" {
+					m.error = "This chat has no plan file"
+					return m, nil
+				}
+				data, err := os.ReadFile(chat.PlanPath)
+				if err != nil {
+					m.error = fmt.Sprintf("Failed to read plan: %v", err)
+					return m, nil
+				}
+				lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+				m.openOverlay(overlayPlan, "Plan: "+chat.PlanPath, lines)
+			}
+			return m, nil
+		}
+		if msg.String() == "C" {
+			indices := m.selectedIndices()
+			if len(indices) != 2 {
+				m.error = "Select exactly two chats to compare them"
+				return m, nil
+			}
+			width := m.width
+			if width < 75 {
+				width = 75
+			}
+			m.openOverlay(overlayCompare, "Compare", compareLines(m.chats[indices[0]], m.chats[indices[1]], width))
+			return m, nil
+		}
+		if msg.String() == "i" {
+			if idx, ok := m.cursorChat(); ok {
+				m.openOverlay(overlayIndexEntry, "Index Entry: "+m.chats[idx].UUID, indexEntryLines(m.chats[idx]))
+			}
+			return m, nil
+		}
+		if msg.String() == "z" {
+			if !m.grouped {
+				m.error = "Ranking projects by size needs the grouped view (Settings tab)"
+				return m, nil
+			}
+			m.sizeSort = !m.sizeSort
+			var cmd tea.Cmd
+			if m.sizeSort && !m.sizing {
+				cmd = m.startSizes(false)
+			}
+			m.rebuildGroupRows()
+			return m, cmd
+		}
+		if msg.String() == "N" {
+			if idx, ok := m.cursorChat(); ok {
+				m.openPrompt(promptNote, "Note (empty removes)", m.chats[idx].Note, idx)
+			}
+			return m, nil
+		}
+		if msg.String() == "T" {
+			if idx, ok := m.cursorChat(); ok {
+				label := ""
+				if m.chats[idx].DerivedTitle != "" {
+					label = m.chats[idx].Title
+				}
+				m.openPrompt(promptChatLabel, "Label (empty restores the title)", label, idx)
+			}
+			return m, nil
+		}
+		if msg.String() == "S" {
+			if len(m.selected) == 0 {
+				m.error = "Nothing selected to save"
+				return m, nil
+			}
+			m.openPrompt(promptSaveSelection, "Save selection as", "", -1)
+			return m, nil
+		}
+		if msg.String() == "L" {
+			names, err := selectionSetNames()
+			if err != nil {
+				m.error = fmt.Sprintf("cannot read %s: %v", selectionsPath(), err)
+				return m, nil
+			}
+			if len(names) == 0 {
+				m.error = "No saved selections"
+				return m, nil
+			}
+			label := fmt.Sprintf("Load selection (%s)", strings.Join(names, ", "))
+			m.openPrompt(promptLoadSelection, label, "", -1)
+			return m, nil
+		}
+		if msg.String() == "A" {
+			if project, ok := m.cursorProject(); ok {
+				m.autoSelected = false
+				m.toggleProjectSelection(project)
+			}
+			return m, nil
+		}
+		if msg.String() == "D" {
+			// Delete exactly what the filters leave in the list; the
+			// selection is replaced, so nothing filtered out can ride along.
+			m.selected = make(map[int]bool)
+			for i := range m.chats {
+				if m.selectable(i) {
+					m.selected[i] = true
+				}
+			}
+			if len(m.selected) == 0 {
+				m.error = "No deletable chats in view"
+				return m, nil
+			}
+			m.autoSelected = true
+			m.deleteInView = true
+			m.confirmDelete = true
+			return m, nil
+		}
+		if msg.String() == "X" {
+			// Retire a whole project: delete all its chats, then its directory.
+			project, ok := m.cursorProject()
+			if !ok {
+				return m, nil
+			}
+			indices := m.chatIndicesForProject(project)
+			if len(indices) == 0 {
+				m.error = "No deletable chats in this project"
+				return m, nil
+			}
+			m.selected = make(map[int]bool)
+			for _, idx := range indices {
+				m.selected[idx] = true
+			}
+			m.autoSelected = true
+			m.deleteProject = project
+			m.confirmDelete = true
+			if m.grouped {
+				m.adjustScrollGrouped()
+			} else {
+				m.adjustScroll()
+			}
+			return m, nil
+		}
+		if msg.String() == "p" {
+			m.fullPaths = !m.fullPaths
+			// The choice sticks across runs
+			if m.cfg != nil {
+				m.cfg.FullPaths = m.fullPaths
+				if err := saveState("full_paths", m.fullPaths); err != nil {
+					m.error = fmt.Sprintf("Failed to save the setting: %v", err)
+				}
+			}
+			return m, nil
+		}
+		switch msg.String() {
+		case ">", "shift+right":
+			m.hOffset = min(m.hOffset+hScrollStep, max(m.hOverflow(), 0))
+			return m, nil
+		case "<", "shift+left":
+			m.hOffset = max(min(m.hOffset, m.hOverflow())-hScrollStep, 0)
+			return m, nil
+		}
+		if msg.String() == "l" {
+			if idx, ok := m.cursorChat(); ok {
+				if chat := m.chats[idx]; m.expanded == chat.UUID {
+					m.expanded, m.expandedFiles = "", nil
+				} else {
+					m.expanded, m.expandedFiles = chat.UUID, relatedFootprint(chat)
+				}
+				if m.grouped {
+					m.adjustScrollGrouped()
+				} else {
+					m.adjustScroll()
+				}
+			}
+			return m, nil
+		}
+		if msg.String() == "#" {
+			m.lineNumbers = (m.lineNumbers + 1) % len(lineNumberModes)
+			return m.flashStatus("Line numbers: " + lineNumberModes[m.lineNumbers])
+		}
+		if msg.String() == "t" || msg.String() == "w" {
+			if msg.String() == "t" {
+				m.hideTrivial = !m.hideTrivial
+			} else {
+				m.window = (m.window + 1) % len(timeWindows)
+			}
+			m.loadChats()
+			m.selected = make(map[int]bool)
+			m.autoSelected = false
+			m.cursor = 0
+			m.scrollOffset = 0
+			if m.grouped {
+				m.rebuildGroupRows()
+			}
+			return m, nil
+		}
+
+		if msg.String() == "m" {
+			m.artifactMenu = true
+			return m, nil
+		}
+		if msg.String() == "M" {
+			m.openPrompt(promptMessageFilter, "Messages (e.g. <=2, >=100; empty shows all)", m.msgFilter, -1)
+			return m, nil
+		}
+
+		if msg.String() == "h" {
+			m.hideHelp = !m.hideHelp
+			if m.grouped {
+				m.adjustScrollGrouped()
+			} else {
+				m.adjustScroll()
+			}
+			return m, nil
+		}
+
+		if msg.String() == "Z" {
+			m.focus = !m.focus
+			if m.focus {
+				return m.flashStatus("Focus mode: only the cursor row at full contrast (Z to leave)")
+			}
+			return m.flashStatus("Focus mode off")
+		}
+
+		if msg.String() == "x" {
+			if idx, ok := m.cursorChat(); ok {
+				return m.toggleStaged(idx)
+			}
+			return m, nil
+		}
+		if msg.String() == "V" {
+			return m.reviewStaged()
+		}
+		if msg.String() == "o" {
+			uuids := m.selectedUUIDs()
+			if m.reviewing == nil {
+				if len(uuids) == 0 {
+					m.error = "Nothing selected to review"
+					return m, nil
+				}
+				m.reviewing = make(map[string]bool, len(uuids))
+				for _, uuid := range uuids {
+					m.reviewing[uuid] = true
+				}
+			} else {
+				m.reviewing = nil
+			}
+			m.loadChats()
+			m.selectUUIDs(uuids)
+			m.cursor = 0
+			m.scrollOffset = 0
+			if m.grouped {
+				m.rebuildGroupRows()
+			}
+			return m, nil
+		}
+
 		// Chats tab: grouped mode
 		if m.grouped {
 			return m.updateGrouped(msg)
@@ -369,22 +1687,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.autoSelected = false
 			if m.selected[m.cursor] {
 				delete(m.selected, m.cursor)
-			} else {
+			} else if m.selectable(m.cursor) {
 				m.selected[m.cursor] = true
-			}
-
-		case "a":
-			// Select all / deselect all toggle
-			if len(m.chats) == 0 {
-				return m, nil // Nothing to select
-			}
-			m.autoSelected = false
-			if len(m.selected) == len(m.chats) {
-				m.selected = make(map[int]bool)
-			} else {
-				for i := range m.chats {
-					m.selected[i] = true
-				}
+			} else if m.cursor < len(m.chats) {
+				m.error = "Chat is protected by the exclude file"
 			}
 
 		case "d":
@@ -392,23 +1698,32 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// (via Space or a), delete those. Otherwise auto-select the
 			// chat under the cursor for this single gesture.
 			if len(m.selected) == 0 && m.cursor < len(m.chats) {
+				if !m.selectable(m.cursor) {
+					m.error = "Chat is protected by the exclude file"
+					return m, nil
+				}
 				m.selected[m.cursor] = true
 				m.autoSelected = true
 			}
 			if len(m.selected) > 0 {
 				m.confirmDelete = true
+				m.adjustScroll()
 			}
 
 		case "r":
-			// Refresh
-			m.chats = findAllChats()
+			// Refresh, then say briefly what changed on disk
+			before := m.chats
+			m.loadChats()
 			m.selected = make(map[int]bool)
 			m.autoSelected = false
 			m.cursor = 0
 			m.scrollOffset = 0
-			m.error = ""
-			m.deleted = 0
-			m.copiedMsg = ""
+			var sizes tea.Cmd
+			if m.chatSizes != nil {
+				sizes = m.startSizes(true)
+			}
+			next, flash := m.flashStatus(refreshSummary(before, m.chats))
+			return next, tea.Batch(flash, sizes)
 
 		case "c":
 			// Copy UUID to clipboard
@@ -417,14 +1732,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if err := copyToClipboard(uuid); err != nil {
 					m.error = fmt.Sprintf("Failed to copy: %v", err)
 				} else {
-					m.copyTimer++
-					currentTimer := m.copyTimer
-					m.copiedMsg = fmt.Sprintf("Chat UUID copied: %s", uuid)
-					m.error = ""
-					m.deleted = 0
-					return m, tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
-						return clearCopiedMsg{id: currentTimer}
-					})
+					return m.flashStatus(fmt.Sprintf("Chat UUID copied: %s", uuid))
 				}
 			}
 		}
@@ -432,30 +1740,120 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case deleteCompleteMsg:
 		m.deleting = false
 		m.deleted = msg.count
+		now := time.Now()
+		for _, chat := range msg.chats {
+			m.history = append(m.history, historyEntry{chat: chat, at: now})
+			if _, ok := m.staged[chat.UUID]; ok && setStaged(chat.UUID, false) == nil {
+				delete(m.staged, chat.UUID)
+			}
+		}
+		m.sessionFreed += msg.freed
 		m.deleteTimer++
 		currentTimer := m.deleteTimer
-		m.chats = findAllChats()
+		m.reviewing = nil
+		m.loadChats()
 		m.selected = make(map[int]bool)
 		m.autoSelected = false
 		m.cursor = 0
 		m.scrollOffset = 0
 		m.confirmDelete = false
+		m.deleteProject = ""
+		m.deleteInView = false
+		m.deleteChoice = ""
 		if m.grouped {
 			m.rebuildGroupRows()
 		}
 		// Clear other status messages
 		m.error = ""
 		m.copiedMsg = ""
-		if len(m.chats) == 0 {
+		m.deletedNote = msg.note
+		if breakdown := rootBreakdown(msg.chats, true); breakdown != "" {
+			m.deletedNote = " " + breakdown + m.deletedNote
+		}
+		m.trashed = msg.trashed
+		if len(msg.leftovers) > 0 {
+			m.error = fmt.Sprintf("verification found %d leftover(s) after delete, e.g. %s", len(msg.leftovers), msg.leftovers[0])
+		} else if msg.hookErr != "" {
+			m.error = fmt.Sprintf("%d chat(s) deleted, but %s", msg.count, msg.hookErr)
+		}
+		if msg.err != "" {
+			m.error = fmt.Sprintf("deletion stopped after %d of %d chat(s): %s", msg.count, msg.total, msg.err)
+		}
+		if m.quitPending {
+			verb := "deleted"
+			if msg.trashed {
+				verb = "moved to trash"
+			}
+			m.exitReport = fmt.Sprintf("Interrupted: %s %d of %d chat(s) before quitting.", verb, msg.count, msg.total)
+			if msg.err != "" {
+				m.exitReport = fmt.Sprintf("Interrupted: deletion stopped with an error after %d of %d chat(s): %s", msg.count, msg.total, msg.err)
+			}
+			return m, tea.Quit
+		}
+		if len(m.chats) == 0 && m.filteredOut() == 0 && msg.err == "" {
 			return m, tea.Quit
 		}
 		return m, tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
 			return clearDeleteMsg{id: currentTimer}
 		})
 
-	case errMsg:
-		m.deleting = false
-		m.error = string(msg)
+	case scanProgressMsg:
+		if m.scanning {
+			m.scanned = append(m.scanned, msg.chats...)
+			m.scanDone, m.scanTotal = msg.done, msg.total
+			m.showScanned(nil)
+		}
+		// After a full rescan (r, a filter, a deletion) the batches are
+		// stale, but draining them lets the scanner finish.
+		return m, waitForScan(msg.next)
+
+	case scanDoneMsg:
+		if m.scanning {
+			m.scanning = false
+			m.showScanned(msg.err)
+			if msg.err == nil {
+				m.pruneStaged(m.scanned)
+			}
+			m.scanned = nil
+			return m, m.startSizes(false)
+		}
+		return m, nil
+
+	case trashLoadedMsg:
+		m.setTrash(msg)
+		return m, nil
+
+	case trashRestoredMsg:
+		return m.finishRestore(msg)
+
+	case trashEmptiedMsg:
+		return m.finishEmptyTrash(msg)
+
+	case preflightMsg:
+		if msg.gen == m.preflightGen && m.confirmDelete {
+			m.preflight = &msg.report
+		}
+		return m, nil
+
+	case chatSizesMsg:
+		if msg.gen != m.sizeGen {
+			if msg.done < msg.total {
+				return m, waitForScan(msg.next)
+			}
+			return m, nil
+		}
+		for key, size := range msg.sizes {
+			m.chatSizes[key] = size
+		}
+		m.sizeDone = msg.done
+		if m.grouped && m.sizeSort {
+			m.rebuildGroupRows()
+		}
+		if msg.done < msg.total {
+			return m, waitForScan(msg.next)
+		}
+		m.sizing = false
+		return m, nil
 
 	case clearCopiedMsg:
 		if msg.id == m.copyTimer {
@@ -471,8 +1869,42 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// percentRow returns the row index pct percent of the way through a list of
+// rows, rounding up like Vim's N%: 50% of 50 rows is row 25.
+func percentRow(pct, rows int) int {
+	pct = min(pct, 100)
+	return (pct*rows+99)/100 - 1
+}
+
+// scrollInfo is the scroll indicator for rows start..end-1 of total, with
+// how far down the list the last visible row is.
+func scrollInfo(start, end, total int) string {
+	return fmt.Sprintf("[%d-%d/%d] %d%%", start+1, end, total, end*100/total)
+}
+
+// moveCursorTo puts the cursor on row (0-based) of the current layout,
+// clamped to the list.
+func (m *model) moveCursorTo(row int) {
+	rows := len(m.chats)
+	if m.grouped {
+		rows = len(m.groupRows)
+	}
+	if row >= rows {
+		row = rows - 1
+	}
+	if row < 0 {
+		row = 0
+	}
+	m.cursor = row
+	if m.grouped {
+		m.adjustScrollGrouped()
+	} else {
+		m.adjustScroll()
+	}
+}
+
 func (m *model) adjustScroll() {
-	visibleHeight := m.visibleHeight()
+	visibleHeight := m.listHeight()
 	// confirmDelete dialog replaces help text, no additional space needed
 
 	if m.cursor < m.scrollOffset {
@@ -483,11 +1915,84 @@ func (m *model) adjustScroll() {
 }
 
 const (
-	settingAutoUpdates   = 0
+	settingAutoUpdates    = 0
 	settingGroupByProject = 1
-	settingsCount        = 2
+	settingUseTrash       = 2
+	settingEmptyTrash     = 3
+	settingsCount         = 4
 )
 
+// startEmptyTrash measures the trash and asks to confirm emptying it.
+func (m model) startEmptyTrash() (tea.Model, tea.Cmd) {
+	if m.readOnly {
+		m.trashHint = "read-only mode: emptying the trash is disabled"
+		return m, nil
+	}
+	if m.updating {
+		m.trashHint = "update in progress: emptying the trash is disabled until it finishes"
+		return m, nil
+	}
+	if m.emptying {
+		return m, nil
+	}
+	batches, err := listTrashBatches()
+	switch {
+	case err != nil:
+		m.trashHint = "Error: " + err.Error()
+	case len(batches) == 0:
+		m.trashHint = "(trash is empty)"
+	default:
+		m.trashPending = batches
+	}
+	return m, nil
+}
+
+// trashEmptiedMsg reports the batches removed after the "Empty trash"
+// confirmation.
+type trashEmptiedMsg struct {
+	removed int
+	freed   int64
+	err     error
+}
+
+// updateEmptyTrash handles the "Empty trash" confirmation. The batches are
+// removed in the background; finishEmptyTrash reports the outcome.
+func (m model) updateEmptyTrash(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		batches := m.trashPending
+		m.trashPending = nil
+		m.emptying = true
+		m.trashHint = fmt.Sprintf("Removing %d batch(es)...", len(batches))
+		if m.tab == tabTrash {
+			m.trashStatus, m.trashHint = m.trashHint, ""
+		}
+		return m, func() tea.Msg {
+			removed, freed, err := removeTrashBatches(batches)
+			return trashEmptiedMsg{removed: removed, freed: freed, err: err}
+		}
+	case "esc", "n", "q":
+		m.trashPending = nil
+		m.trashHint = ""
+	}
+	return m, nil
+}
+
+// finishEmptyTrash reports removed batches where the confirmation was given.
+func (m model) finishEmptyTrash(msg trashEmptiedMsg) (tea.Model, tea.Cmd) {
+	m.emptying = false
+	hint := fmt.Sprintf("✓ Removed %d batch(es), freed %s", msg.removed, formatBytes(msg.freed))
+	if msg.err != nil {
+		hint = "Error: " + msg.err.Error()
+	}
+	if m.tab == tabTrash {
+		m.trashStatus, m.trashHint = hint, ""
+		return m, m.loadTrash()
+	}
+	m.trashHint = hint
+	return m, nil
+}
+
 func (m model) viewSettings() string {
 	width := m.width
 	if width < 75 {
@@ -534,89 +2039,93 @@ func (m model) viewSettings() string {
 	}
 	s.WriteString("\n")
 
+	// Trash setting
+	trashVal := "OFF"
+	trashStyle := errorStyle
+	trashHint := "  " + dimStyle.Render("(deletion is permanent)")
+	if m.useTrash() {
+		trashVal = "ON"
+		trashStyle = successStyle
+		trashHint = "  " + dimStyle.Render("(deleted chats stay recoverable in the trash)")
+	}
+	trashLine := fmt.Sprintf("  Move to trash     %s%s", trashStyle.Render(trashVal), trashHint)
+	if m.settingsCursor == settingUseTrash {
+		s.WriteString(cursorStyle.Render(trashLine))
+	} else {
+		s.WriteString(trashLine)
+	}
+	s.WriteString("\n")
+
+	// Empty trash action
+	emptyHint := dimStyle.Render("(permanently removes every trashed chat)")
+	switch {
+	case m.trashPending != nil:
+		emptyHint = m.trashPendingQuestion()
+	case m.trashHint != "":
+		emptyHint = dimStyle.Render(m.trashHint)
+	}
+	emptyLine := "  Empty trash       "
+	if m.settingsCursor == settingEmptyTrash {
+		emptyLine = cursorStyle.Render(emptyLine)
+	}
+	s.WriteString(emptyLine + emptyHint)
+	s.WriteString("\n")
+
 	s.WriteString("\n")
 	s.WriteString(dimStyle.Render(strings.Repeat("─", width)))
 	s.WriteString("\n")
-	s.WriteString(helpStyle.Render("↑/↓:Navigate | Enter:Toggle | ←/→:Switch tabs | q:Quit"))
+	s.WriteString(helpStyle.Render("↑/↓:Navigate | Enter:Toggle | ←/→:Switch tabs | ?:Help | q:Quit"))
 	s.WriteString("\n")
 	return s.String()
 }
 
 func (m model) View() string {
+	if notice := m.tooSmallNotice(); notice != "" {
+		return notice
+	}
+
+	if m.overlay != overlayNone {
+		return m.viewOverlay()
+	}
+
 	if m.tab == tabSettings {
 		return m.viewSettings()
 	}
+	if m.tab == tabTrash {
+		return m.viewTrash()
+	}
 
 	if m.grouped {
 		return m.viewGrouped()
 	}
 
 	if len(m.chats) == 0 {
-		return activeTabStyle.Render("No chats found.") + "\n\nPress q to quit.\n"
+		return m.viewEmpty()
 	}
 
 	// Calculate column widths based on terminal width
-	width := m.width
-	if width < 75 {
-		width = 75 // minimum width
-	}
-
-	compact := width < compactModeWidth
-
-	// In compact mode: hide VERSION, shorten TIMESTAMP to "MM-DD HH:MM" (11 chars)
-	// Fixed cols: indicator(4) + timestamp + version + lines(6) + gaps
-	var timestampWidth, versionWidth int
-	var fixedWidth int
-	if compact {
-		timestampWidth = 11 // "01-15 14:32"
-		versionWidth = 0
-		fixedWidth = 4 + timestampWidth + 5 + 5 // indicator + ts + lines + gaps
-	} else {
-		timestampWidth = 19 // "2025-01-15 14:32:10"
-		versionWidth = 8
-		fixedWidth = 44 // indicator(4) + ts(19) + version(8) + lines(5) + gaps(8)
-	}
-
-	linesWidth := 5
-	remaining := width - fixedWidth
-	titleWidth := remaining * 60 / 100 // 60% for title
-	projectWidth := remaining - titleWidth
-
-	if titleWidth < 30 {
-		titleWidth = 30
-	}
-	if projectWidth < 10 {
-		projectWidth = 10
-	}
+	l := m.flatLayout()
+	width, compact, shortDates, gutterWidth := l.width, l.compact, l.shortDates, l.gutterWidth
+	timestampWidth, versionWidth, linesWidth := l.timestampWidth, l.versionWidth, l.linesWidth
+	titleWidth, projectWidth := l.titleWidth, l.projectWidth
 
 	var s strings.Builder
 
 	// Header
 	s.WriteString(m.renderTabBar())
 	s.WriteString("\n")
-
-
-
-
+	tableStart := s.Len()
 	s.WriteString(dimStyle.Render(strings.Repeat("─", width)))
 	s.WriteString("\n")
 
 	// Column headers
-	var header string
-	if compact {
-		headerFmt := fmt.Sprintf("    %%-*s  %%-%ds  %%-%ds  %%-%ds", linesWidth, titleWidth, projectWidth)
-		header = fmt.Sprintf(headerFmt, timestampWidth, "TIMESTAMP", "LINES", "TITLE", "PROJECT")
-	} else {
-		headerFmt := fmt.Sprintf("    %%-*s  %%-%ds  %%-%ds  %%-%ds  %%-%ds", versionWidth, linesWidth, titleWidth, projectWidth)
-		header = fmt.Sprintf(headerFmt, timestampWidth, "TIMESTAMP", "VERSION", "LINES", "TITLE", "PROJECT")
-	}
-	s.WriteString(dimStyle.Render(header))
+	s.WriteString(dimStyle.Render(strings.Repeat(" ", gutterWidth) + l.header))
 	s.WriteString("\n")
 	s.WriteString(dimStyle.Render(strings.Repeat("─", width)))
 	s.WriteString("\n")
 
-	// Chat list
-	visibleHeight := m.visibleHeight()
+	// Chat list, less the rows taken by an expanded chat's files
+	visibleHeight := m.listHeight()
 	// confirmDelete dialog replaces help text, no additional space needed
 
 	start := m.scrollOffset
@@ -626,112 +2135,525 @@ func (m model) View() string {
 	}
 
 	for i := start; i < end; i++ {
-		chat := m.chats[i]
-
-		// Truncate fields using visual width
-		var timestamp string
-		if compact {
-			// "2025-01-15 14:32:10" -> "01-15 14:32"
-			if len(chat.Timestamp) >= 16 {
-				timestamp = chat.Timestamp[5:16] // "MM-DD HH:MM"
+		key := rowKey{uuid: m.chats[i].UUID, project: m.chats[i].Project, width: width, gutter: gutterWidth,
+			fullPaths: m.fullPaths, selected: m.selected[i], cursor: i == m.cursor, size: m.sizeCell(m.chats[i]), staged: m.isStaged(i),
+			focus: m.focus}
+		s.WriteString(m.gutter(i, gutterWidth))
+		s.WriteString(m.cachedRow(key, func() string {
+			chat := m.chats[i]
+
+			// Truncate fields using visual width
+			var timestamp string
+			if shortDates {
+				// "2025-01-15 14:32:10" -> "01-15 14:32"
+				if len(chat.Timestamp) >= 16 {
+					timestamp = chat.Timestamp[5:16] // "MM-DD HH:MM"
+				} else {
+					timestamp = runewidth.Truncate(chat.Timestamp, timestampWidth, "")
+				}
 			} else {
 				timestamp = runewidth.Truncate(chat.Timestamp, timestampWidth, "")
 			}
-		} else {
-			timestamp = runewidth.Truncate(chat.Timestamp, timestampWidth, "")
-		}
-		version := runewidth.Truncate(chat.Version, versionWidth-1, "")
-		// TODO: msg column - maybe re-enable later
-		// msg := fmt.Sprintf("%d", chat.MessageCount)
-		// if chat.MessageCount == 0 {
-		// 	msg = "-"
-		// }
-		var lines string
-		switch {
-		case chat.LineCount == 0:
-			lines = "-"
-		case chat.LineCount >= 10000:
-			lines = fmt.Sprintf("%dk", chat.LineCount/1000)
-		default:
-			lines = fmt.Sprintf("%d", chat.LineCount)
+			version := runewidth.Truncate(chat.Version, versionWidth-1, "")
+			// TODO: msg column - maybe re-enable later
+			// msg := fmt.Sprintf("%d", chat.MessageCount)
+			// if chat.MessageCount == 0 {
+			// 	msg = "-"
+			// }
+			var lines string
+			switch {
+			case chat.LineCount == 0:
+				lines = "-"
+			case chat.LineCount >= 10000:
+				lines = fmt.Sprintf("%dk", chat.LineCount/1000)
+			default:
+				lines = fmt.Sprintf("%d", chat.LineCount)
+			}
+
+			title := displayTitle(chatTitleLabel(chat), titleWidth)
+			projectClean := strings.NewReplacer("\n", " ").Replace(m.chatProjectLabel(chat))
+			project := truncateLeft(projectClean, projectWidth-2)
+
+			// Selection indicator
+			indicator := "[ ]"
+			if m.selected[i] {
+				indicator = "[✓]"
+			} else if m.isStaged(i) {
+				indicator = "[x]"
+			} else if chat.Protected {
+				indicator = "[P]"
+			}
+
+			var line string
+			if compact {
+				lineFmt := fmt.Sprintf("%%s %%-*s  %%-%ds  %%s  %%s  %%s", linesWidth)
+				line = fmt.Sprintf(lineFmt, indicator, timestampWidth, timestamp, lines,
+					padRight(planMark(chat), planWidth), padRight(title, titleWidth), padRight(project, projectWidth))
+			} else {
+				lineFmt := fmt.Sprintf("%%s %%-*s  %%-%ds  %%-%ds  %%s  %%s  %%s  %%s", versionWidth, linesWidth)
+				line = fmt.Sprintf(lineFmt, indicator, timestampWidth, timestamp, version, lines, padRight(m.sizeCell(chat), sizeWidth),
+					padRight(planMark(chat), planWidth), padRight(title, titleWidth), padRight(project, projectWidth))
+			}
+
+			// Apply styles
+			style := lipgloss.NewStyle()
+			if chat.Problem != "" {
+				style = warningStyle
+			}
+			if chat.Protected {
+				style = dimStyle
+			}
+			if m.selected[i] {
+				style = selectedStyle
+			}
+			if m.focus {
+				style = dimStyle
+			}
+			if i == m.cursor {
+				style = cursorStyle
+			}
+
+			return style.Render(line)
+		}))
+		s.WriteString("\n")
+		if i == m.cursor {
+			for _, row := range m.expandedRows(width - gutterWidth) {
+				s.WriteString(strings.Repeat(" ", gutterWidth) + row + "\n")
+			}
 		}
+	}
+
+	// Scroll indicator
+	if len(m.chats) > visibleHeight {
+		s.WriteString(dimStyle.Render(scrollInfo(start, end, len(m.chats))))
+		s.WriteString("\n")
+	}
+
+	// Bottom separator
+	s.WriteString(dimStyle.Render(strings.Repeat("─", width)))
+	s.WriteString("\n")
+	// On narrow terminals only the part of the list at hOffset is shown
+	out := s.String()
+	s.Reset()
+	s.WriteString(out[:tableStart] + m.scrollTable(out[tableStart:]))
+
+	// Status messages (below separator)
+	if m.error != "" {
+		s.WriteString(errorStyle.Render("Error: " + m.error))
+		s.WriteString("\n")
+	} else if m.deleted > 0 {
+		s.WriteString(successStyle.Render(m.deletedStatus()))
+		s.WriteString("\n")
+	} else if m.copiedMsg != "" {
+		s.WriteString(successStyle.Render("✓ " + m.copiedMsg))
+		s.WriteString("\n")
+	}
+
+	// Help / Confirmation dialog
+	if m.confirmDelete {
+		s.WriteString(m.renderConfirm(width))
+	} else if m.selectAllPending {
+		s.WriteString(m.renderSelectAllConfirm())
+	} else if m.artifactMenu {
+		s.WriteString(m.renderArtifactMenu())
+	} else if m.promptAction != promptNone {
+		s.WriteString(m.renderPrompt())
+	} else if !m.helpHidden() {
+		s.WriteString(helpStyle.Render(helpHint))
+		s.WriteString("\n")
+	}
+
+	return s.String()
+}
+
+// helpHidden reports whether the footer leaves out the help line(s): with h
+// on and no dialog, prompt or menu taking their place.
+func (m model) helpHidden() bool {
+	return m.hideHelp && !m.confirmDelete && !m.selectAllPending && !m.artifactMenu && m.promptAction == promptNone
+}
+
+// tableLayout holds the column widths of the chat list, computed from the
+// terminal width by flatLayout and groupedLayout.
+type tableLayout struct {
+	width          int // laid-out width; never below minTableWidth
+	compact        bool
+	shortDates     bool // "MM-DD HH:MM" timestamps (compact flat list)
+	timestampWidth int
+	versionWidth   int // 0 in compact mode
+	linesWidth     int
+	titleWidth     int
+	projectWidth   int // flat list only
+	gutterWidth    int
+	header         string // column headers, without the gutter
+}
+
+// minTableWidth is the narrowest layout; narrower terminals scroll the list
+// sideways with < and >, hScrollStep columns at a time.
+const (
+	minTableWidth = 75
+	hScrollStep   = 10
+)
+
+func (m model) layoutWidth() int {
+	return max(m.width, minTableWidth)
+}
+
+// flatLayout lays out the flat list.
+func (m model) flatLayout() tableLayout {
+	l := tableLayout{width: m.layoutWidth(), linesWidth: 5}
+	l.compact = l.width < compactModeWidth
+
+	// In compact mode: hide VERSION, shorten TIMESTAMP to "MM-DD HH:MM" (11 chars)
+	// unless a custom date_format is set (its layout is kept as configured).
+	// Fixed cols: indicator(4) + timestamp + version + lines(6) + gaps
+	l.shortDates = l.compact && dateFormat == defaultDateFormat
+	l.timestampWidth = dateColumnWidth() // 19 for "2025-01-15 14:32:10"
+	var fixedWidth int
+	if l.compact {
+		if l.shortDates {
+			l.timestampWidth = 11 // "01-15 14:32"
+		}
+		fixedWidth = 4 + l.timestampWidth + 5 + planWidth + 7 // indicator + ts + lines + plan + gaps
+	} else {
+		l.versionWidth = 8
+		fixedWidth = 4 + l.timestampWidth + l.versionWidth + 5 + sizeWidth + planWidth + 12 // indicator + ts + version + lines + size + plan + gaps
+	}
+	l.gutterWidth = m.gutterWidth(len(m.chats))
+	fixedWidth += l.gutterWidth
+
+	remaining := l.width - fixedWidth
+	l.titleWidth = remaining * 60 / 100 // 60% for title
+	l.projectWidth = remaining - l.titleWidth
+
+	if l.titleWidth < 30 {
+		l.titleWidth = 30
+	}
+	if l.projectWidth < 10 {
+		l.projectWidth = 10
+	}
+
+	if l.compact {
+		headerFmt := fmt.Sprintf("    %%-*s  %%-%ds  %%-%ds  %%-%ds  %%-%ds", l.linesWidth, planWidth, l.titleWidth, l.projectWidth)
+		l.header = fmt.Sprintf(headerFmt, l.timestampWidth, "TIMESTAMP", "LINES", "PLAN", "TITLE", "PROJECT")
+	} else {
+		headerFmt := fmt.Sprintf("    %%-*s  %%-%ds  %%-%ds  %%-%ds  %%-%ds  %%-%ds  %%-%ds", l.versionWidth, l.linesWidth, sizeWidth, planWidth, l.titleWidth, l.projectWidth)
+		l.header = fmt.Sprintf(headerFmt, l.timestampWidth, "TIMESTAMP", "VERSION", "LINES", "SIZE", "PLAN", "TITLE", "PROJECT")
+	}
+	return l
+}
+
+// groupedLayout lays out the project view.
+func (m model) groupedLayout() tableLayout {
+	l := tableLayout{width: m.layoutWidth(), linesWidth: 5}
+	l.compact = l.width < compactModeWidth
+
+	// Column widths for chat rows (indented by 2 for nesting). Project view always
+	// shows the full timestamp — dropping the PROJECT column frees the space — so
+	// only VERSION is hidden on narrow terminals, not the date.
+	l.timestampWidth = dateColumnWidth()
+	var fixedWidth int
+	if l.compact {
+		fixedWidth = 4 + 2 + l.timestampWidth + 5 + planWidth + 7 // indicator + indent + ts + lines + plan + gaps
+	} else {
+		l.versionWidth = 8
+		fixedWidth = 4 + 2 + l.timestampWidth + l.versionWidth + 5 + sizeWidth + planWidth + 12 // + version + size + extra gaps
+	}
+	l.gutterWidth = m.gutterWidth(len(m.groupRows))
+	fixedWidth += l.gutterWidth
+
+	remaining := l.width - fixedWidth
+	l.titleWidth = remaining * 65 / 100 // project column omitted here, so give title a larger share than the flat list's 60%
+	if l.titleWidth < 30 {
+		l.titleWidth = 30
+	}
+
+	// Leading 5 spaces align with the indented chat rows: indicator (3) + the
+	// 2-space nesting indent.
+	if l.compact {
+		headerFmt := fmt.Sprintf("     %%-*s  %%-%ds  %%-%ds  %%-%ds", l.linesWidth, planWidth, l.titleWidth)
+		l.header = fmt.Sprintf(headerFmt, l.timestampWidth, "TIMESTAMP", "LINES", "PLAN", "TITLE")
+	} else {
+		headerFmt := fmt.Sprintf("     %%-*s  %%-%ds  %%-%ds  %%-%ds  %%-%ds  %%-%ds", l.versionWidth, l.linesWidth, sizeWidth, planWidth, l.titleWidth)
+		l.header = fmt.Sprintf(headerFmt, l.timestampWidth, "TIMESTAMP", "VERSION", "LINES", "SIZE", "PLAN", "TITLE")
+	}
+	return l
+}
+
+// hOverflow is how many columns of the list don't fit the terminal: the most
+// the list can be scrolled sideways.
+func (m model) hOverflow() int {
+	l := m.flatLayout()
+	if m.grouped {
+		l = m.groupedLayout()
+	}
+	if m.width <= 0 {
+		return 0
+	}
+	return max(l.width, l.gutterWidth+runewidth.StringWidth(l.header)) - m.width
+}
+
+// scrollTable shows the window of the list lines that fits the terminal,
+// hOffset columns from the left, when the list is wider than the terminal.
+func (m model) scrollTable(table string) string {
+	over := m.hOverflow()
+	if over <= 0 {
+		return table
+	}
+	lines := strings.Split(table, "\n")
+	for i, line := range lines {
+		lines[i] = cutANSI(line, min(m.hOffset, over), m.width)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sizeWidth is the SIZE column width, enough for formatBytes ("1023.9 MB").
+// The column is left out in compact mode.
+const sizeWidth = 9
+
+// sizeCell is the SIZE column text: the chat's size on disk, "…" while it is
+// still being measured, empty before any measuring started.
+func (m model) sizeCell(chat Chat) string {
+	if size, ok := m.chatSizes[chatSizeKey(chat)]; ok {
+		return formatBytes(size)
+	}
+	if m.chatSizes != nil {
+		return "…"
+	}
+	return ""
+}
+
+// lineNumberModes names the line number gutter modes, in the order # cycles
+// through them.
+var lineNumberModes = []string{"off", "absolute", "relative"}
+
+const (
+	numbersOff = iota
+	numbersAbsolute
+	numbersRelative
+)
+
+// gutterWidth is the line number gutter width for a list of rows: the widest
+// number plus a space, so the columns don't shift while scrolling. It is 0
+// with line numbers off.
+func (m model) gutterWidth(rows int) int {
+	if m.lineNumbers == numbersOff {
+		return 0
+	}
+	return len(strconv.Itoa(rows)) + 1
+}
+
+// gutter is row i's line number, counted from 1 like the scroll indicator.
+// In relative mode the other rows show their distance from the cursor, as
+// Vim's relativenumber does, which is the count for j/k.
+func (m model) gutter(i, width int) string {
+	if width == 0 {
+		return ""
+	}
+	n := i + 1
+	if m.lineNumbers == numbersRelative && i != m.cursor {
+		n = i - m.cursor
+		if n < 0 {
+			n = -n
+		}
+	}
+	text := fmt.Sprintf("%*d ", width-1, n)
+	if i == m.cursor {
+		return cursorStyle.Render(text)
+	}
+	return dimStyle.Render(text)
+}
+
+// planWidth is the PLAN column width; planMark fills it.
+const planWidth = 4
+
+// planMark flags chats that have a plan file (plans/<slug>.md).
+func planMark(chat Chat) string {
+	if chat.PlanPath != "" {
+		return "✓"
+	}
+	return ""
+}
 
-		titleClean := strings.NewReplacer("\n", " ").Replace(chat.Title)
-		title := runewidth.Truncate(titleClean, titleWidth, "..")
-		projectClean := strings.NewReplacer("\n", " ").Replace(chat.Project)
-		project := truncateLeft(projectClean, projectWidth-2)
+// dateColumnWidth is the TIMESTAMP column width for the active date format:
+// the width of a formatted reference time, at least the header's.
+func dateColumnWidth() int {
+	ref := time.Date(2025, 12, 28, 23, 59, 58, 0, time.Local)
+	if w := runewidth.StringWidth(ref.Format(dateFormat)); w > len("TIMESTAMP") {
+		return w
+	}
+	return len("TIMESTAMP")
+}
 
-		// Selection indicator
-		indicator := "[ ]"
-		if m.selected[i] {
-			indicator = "[✓]"
-		}
+// rowKey identifies a rendered chat row. It holds everything besides the chat
+// itself that changes the row's text or style, so a cached row can be reused
+// verbatim. Column widths follow from width.
+type rowKey struct {
+	uuid, project string
+	grouped       bool
+	width         int
+	fullPaths     bool
+	selected      bool
+	cursor        bool
+	size          string // SIZE cell; changes as sizes arrive
+	gutter        int    // line number gutter width, taken from the title
+	staged        bool
+	focus         bool
+}
 
-		var line string
-		if compact {
-			lineFmt := fmt.Sprintf("%%s %%-*s  %%-%ds  %%-%ds  %%-%ds", linesWidth, titleWidth, projectWidth)
-			line = fmt.Sprintf(lineFmt, indicator, timestampWidth, timestamp, lines, title, project)
-		} else {
-			lineFmt := fmt.Sprintf("%%s %%-*s  %%-%ds  %%-%ds  %%-%ds  %%-%ds", versionWidth, linesWidth, titleWidth, projectWidth)
-			line = fmt.Sprintf(lineFmt, indicator, timestampWidth, timestamp, version, lines, title, project)
-		}
+// rowCache memoizes rendered rows between frames so key repeat only restyles
+// the rows whose state changed. Chats don't change between scans, so
+// loadChats starts a fresh cache.
+type rowCache map[rowKey]string
 
-		// Apply styles
-		style := lipgloss.NewStyle()
-		if m.selected[i] {
-			style = selectedStyle
-		}
-		if i == m.cursor {
-			style = cursorStyle
+// rowCacheMax bounds the cache; resizing creates a new key per row and width.
+const rowCacheMax = 4096
+
+// cachedRow returns the cached rendering for key, calling render on a miss.
+// View has a value receiver, but the map is shared with the model it came
+// from, so entries persist across frames.
+func (m model) cachedRow(key rowKey, render func() string) string {
+	if m.rowCache == nil {
+		return render()
+	}
+	if row, ok := m.rowCache[key]; ok {
+		return row
+	}
+	if len(m.rowCache) >= rowCacheMax {
+		for k := range m.rowCache {
+			delete(m.rowCache, k)
 		}
+	}
+	row := render()
+	m.rowCache[key] = row
+	return row
+}
 
-		s.WriteString(style.Render(line))
-		s.WriteString("\n")
+// viewEmpty renders the no-chats screen, or the scan error when the list is
+// empty only because the projects directory could not be read.
+func (m model) viewEmpty() string {
+	if m.scanning {
+		return activeTabStyle.Render(m.scanStatus()) + "\n\nPress q to quit.\n"
+	}
+	if m.scanErr != "" {
+		return errorStyle.Render("Error: "+m.scanErr) + "\n\nPress q to quit.\n"
+	}
+	if m.unreviewed > 0 {
+		return activeTabStyle.Render("None of the reviewed chats are left.") + "\n\nPress o to show all chats, q to quit.\n"
+	}
+	if m.outsideWindow > 0 {
+		msg := fmt.Sprintf("No chats in the window \"%s\" (%d older).", timeWindows[m.window].label, m.outsideWindow)
+		return activeTabStyle.Render(msg) + "\n\nPress w to widen it, q to quit.\n"
+	}
+	if m.lacking > 0 {
+		msg := fmt.Sprintf("No chats with %s (%d without).", artifactFilters[m.artifact].label, m.lacking)
+		return activeTabStyle.Render(msg) + "\n\nPress m 0 or Esc to show all, q to quit.\n"
+	}
+	if m.msgMismatch > 0 {
+		msg := fmt.Sprintf("No chats with %s messages (%d others).", m.msgFilter, m.msgMismatch)
+		return activeTabStyle.Render(msg) + "\n\nPress M to change the filter, Esc to show all, q to quit.\n"
+	}
+	if m.hiddenCount > 0 {
+		msg := fmt.Sprintf("All %d chats are below %d lines and hidden.", m.hiddenCount, m.hideThreshold())
+		return activeTabStyle.Render(msg) + "\n\nPress t to show them, q to quit.\n"
+	}
+	if len(m.history) > 0 {
+		return activeTabStyle.Render("No chats left.") + " " + dimStyle.Render(m.sessionStatus()) + "\n\nPress q to quit.\n"
 	}
+	return activeTabStyle.Render("No chats found.") + "\n\nPress q to quit.\n"
+}
 
-	// Scroll indicator
-	if len(m.chats) > visibleHeight {
-		scrollInfo := fmt.Sprintf("[%d-%d/%d]", start+1, end, len(m.chats))
-		s.WriteString(dimStyle.Render(scrollInfo))
-		s.WriteString("\n")
+// isStaged reports whether the chat at idx is on the staging list (x).
+func (m model) isStaged(idx int) bool {
+	_, ok := m.staged[m.chats[idx].UUID]
+	return ok
+}
+
+// pruneStaged drops staged UUIDs the scan didn't find, such as chats deleted
+// outside the app, so the Staged count and V only cover chats that exist.
+// staged.json keeps them: a -project run doesn't see every chat.
+func (m *model) pruneStaged(chats []Chat) {
+	found := make(map[string]bool, len(chats))
+	for _, chat := range chats {
+		found[chat.UUID] = true
+	}
+	for uuid := range m.staged {
+		if !found[uuid] {
+			delete(m.staged, uuid)
+		}
 	}
+}
 
-	// Bottom separator
-	s.WriteString(dimStyle.Render(strings.Repeat("─", width)))
-	s.WriteString("\n")
+// toggleStaged adds the chat at idx to the staging list or takes it off.
+// Protected chats can't be staged, as they can't be deleted.
+func (m model) toggleStaged(idx int) (tea.Model, tea.Cmd) {
+	chat := m.chats[idx]
+	if chat.Protected {
+		m.error = "Protected chats can't be staged for deletion"
+		return m, nil
+	}
+	staged := !m.isStaged(idx)
+	if err := setStaged(chat.UUID, staged); err != nil {
+		m.error = fmt.Sprintf("Failed to save the staging list: %v", err)
+		return m, nil
+	}
+	if staged {
+		if m.staged == nil {
+			m.staged = make(map[string]string)
+		}
+		m.staged[chat.UUID] = time.Now().Format(time.RFC3339)
+		return m.flashStatus(fmt.Sprintf("Staged for deletion (%d staged, V to review)", len(m.staged)))
+	}
+	delete(m.staged, chat.UUID)
+	return m.flashStatus(fmt.Sprintf("Unstaged (%d staged)", len(m.staged)))
+}
 
-	// Status messages (below separator)
-	if m.error != "" {
-		s.WriteString(errorStyle.Render("Error: " + m.error))
-		s.WriteString("\n")
-	} else if m.deleted > 0 {
-		s.WriteString(successStyle.Render(fmt.Sprintf("✓ Deleted %d chat(s)", m.deleted)))
-		s.WriteString("\n")
-	} else if m.copiedMsg != "" {
-		s.WriteString(successStyle.Render("✓ " + m.copiedMsg))
-		s.WriteString("\n")
+// reviewStaged selects every staged chat and shows only them, as review
+// mode (o) does for a selection, so d deletes the lot after a last look.
+func (m model) reviewStaged() (tea.Model, tea.Cmd) {
+	if len(m.staged) == 0 {
+		m.error = "Nothing staged: x stages the chat under the cursor"
+		return m, nil
+	}
+	m.reviewing = make(map[string]bool, len(m.staged))
+	uuids := make([]string, 0, len(m.staged))
+	for uuid := range m.staged {
+		m.reviewing[uuid] = true
+		uuids = append(uuids, uuid)
+	}
+	m.loadChats()
+	m.selectUUIDs(uuids)
+	m.cursor = 0
+	m.scrollOffset = 0
+	if m.grouped {
+		m.rebuildGroupRows()
 	}
+	return m.flashStatus(fmt.Sprintf("%d staged chat(s) selected: d deletes them, o shows all chats", len(m.selected)))
+}
 
-	// Help / Confirmation dialog
-	if m.confirmDelete {
-		s.WriteString(errorStyle.Render(fmt.Sprintf("Delete %d chat(s)?", len(m.selected))))
-		s.WriteString(" ")
-		s.WriteString(helpStyle.Render("[ENTER=Yes] [ESC=No]"))
-		s.WriteString("\n")
-	} else if compact {
-		actionsLine := "Actions:    <Space>: Toggle | a: Toggle All | d: Delete | c: Copy | r: Refresh | q: Quit"
-		navLine := "Navigation: ↑/↓: Chats | ←/→: Tabs | f/b: PgDn/PgUp | F/B: Half | g/G: Home/End"
-		s.WriteString(helpStyle.Render(actionsLine))
-		s.WriteString("\n")
-		s.WriteString(helpStyle.Render(navLine))
-		s.WriteString("\n")
-	} else {
-		help := "↑/↓:Chats | ←/→:Tabs | <Space>:Toggle | a:Toggle All | c:Copy ID | d:Delete | r:Refresh | f/b:PgUp/PgDn | g/G:Home/End | q/esc:Quit"
-		s.WriteString(helpStyle.Render(help))
-		s.WriteString("\n")
+// tooSmallNotice asks for a bigger terminal when the current one is below
+// min_width x min_height, where the layout would break; "" otherwise, and
+// before the first size is known.
+func (m model) tooSmallNotice() string {
+	minWidth, minHeight := m.cfg.minTerminalSize()
+	if m.width == 0 || m.height == 0 || (m.width >= minWidth && m.height >= minHeight) {
+		return ""
 	}
+	msg := fmt.Sprintf("Terminal too small: resize to at least %dx%d (now %dx%d).", minWidth, minHeight, m.width, m.height)
+	return errorStyle.Render(strings.Join(wrapText(msg, m.width), "\n")) + "\n" + dimStyle.Render("q quits") + "\n"
+}
 
-	return s.String()
+// sessionStatus totals the deletions of this run, e.g. "Session: 23 deleted,
+// 4.1 GB freed".
+func (m model) sessionStatus() string {
+	return fmt.Sprintf("Session: %d deleted, %s freed", len(m.history), formatBytes(m.sessionFreed))
+}
+
+// scanStatus reports the startup scan's progress.
+func (m model) scanStatus() string {
+	if m.scanTotal == 0 {
+		return "Scanning..."
+	}
+	return fmt.Sprintf("Scanning... %d/%d files", m.scanDone, m.scanTotal)
 }
 
 // updateGrouped handles key events in grouped view mode.
@@ -812,47 +2734,20 @@ func (m model) updateGrouped(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			row := m.groupRows[m.cursor]
 			if row.isHeader {
 				// Toggle all chats in this project
-				indices := m.chatIndicesForProject(row.project)
-				allSelected := true
-				for _, idx := range indices {
-					if !m.selected[idx] {
-						allSelected = false
-						break
-					}
-				}
-				if allSelected {
-					for _, idx := range indices {
-						delete(m.selected, idx)
-					}
-				} else {
-					for _, idx := range indices {
-						m.selected[idx] = true
-					}
-				}
+				m.toggleProjectSelection(row.project)
 			} else {
 				// Toggle individual chat
 				chatIdx := row.chatIdx
 				if m.selected[chatIdx] {
 					delete(m.selected, chatIdx)
-				} else {
+				} else if m.selectable(chatIdx) {
 					m.selected[chatIdx] = true
+				} else {
+					m.error = "Chat is protected by the exclude file"
 				}
 			}
 		}
 
-	case "a":
-		if len(m.chats) == 0 {
-			return m, nil
-		}
-		m.autoSelected = false
-		if len(m.selected) == len(m.chats) {
-			m.selected = make(map[int]bool)
-		} else {
-			for i := range m.chats {
-				m.selected[i] = true
-			}
-		}
-
 	case "d":
 		// Explicit selection wins: only auto-select when nothing is selected.
 		// On a project header we pick every chat in that project (works for
@@ -863,8 +2758,10 @@ func (m model) updateGrouped(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				for _, idx := range m.chatIndicesForProject(row.project) {
 					m.selected[idx] = true
 				}
-			} else {
+			} else if m.selectable(row.chatIdx) {
 				m.selected[row.chatIdx] = true
+			} else {
+				m.error = "Chat is protected by the exclude file"
 			}
 			if len(m.selected) > 0 {
 				m.autoSelected = true
@@ -872,18 +2769,23 @@ func (m model) updateGrouped(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		if len(m.selected) > 0 {
 			m.confirmDelete = true
+			m.adjustScrollGrouped()
 		}
 
 	case "r":
-		m.chats = findAllChats()
+		before := m.chats
+		m.loadChats()
 		m.selected = make(map[int]bool)
 		m.autoSelected = false
 		m.cursor = 0
 		m.scrollOffset = 0
-		m.error = ""
-		m.deleted = 0
-		m.copiedMsg = ""
 		m.rebuildGroupRows()
+		var sizes tea.Cmd
+		if m.chatSizes != nil {
+			sizes = m.startSizes(true)
+		}
+		next, flash := m.flashStatus(refreshSummary(before, m.chats))
+		return next, tea.Batch(flash, sizes)
 
 	case "c":
 		if m.cursor < rowCount && !m.groupRows[m.cursor].isHeader {
@@ -893,14 +2795,7 @@ func (m model) updateGrouped(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				if err := copyToClipboard(uuid); err != nil {
 					m.error = fmt.Sprintf("Failed to copy: %v", err)
 				} else {
-					m.copyTimer++
-					currentTimer := m.copyTimer
-					m.copiedMsg = fmt.Sprintf("Chat UUID copied: %s", uuid)
-					m.error = ""
-					m.deleted = 0
-					return m, tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
-						return clearCopiedMsg{id: currentTimer}
-					})
+					return m.flashStatus(fmt.Sprintf("Chat UUID copied: %s", uuid))
 				}
 			}
 		}
@@ -910,7 +2805,7 @@ func (m model) updateGrouped(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) adjustScrollGrouped() {
-	visibleHeight := m.visibleHeight()
+	visibleHeight := m.listHeight()
 	if m.cursor < m.scrollOffset {
 		m.scrollOffset = m.cursor
 	} else if m.cursor >= m.scrollOffset+visibleHeight {
@@ -933,62 +2828,31 @@ func (m model) selectedCountForProject(project string) (selected, total int) {
 
 func (m model) viewGrouped() string {
 	if len(m.chats) == 0 {
-		return activeTabStyle.Render("No chats found.") + "\n\nPress q to quit.\n"
-	}
-
-	width := m.width
-	if width < 75 {
-		width = 75
-	}
-
-	compact := width < compactModeWidth
-
-	// Column widths for chat rows (indented by 2 for nesting). Project view always
-	// shows the full timestamp — dropping the PROJECT column frees the space — so
-	// only VERSION is hidden on narrow terminals, not the date.
-	timestampWidth := 19
-	var versionWidth, fixedWidth int
-	if compact {
-		versionWidth = 0
-		fixedWidth = 4 + 2 + timestampWidth + 5 + 5 // indicator + indent + ts + lines + gaps
-	} else {
-		versionWidth = 8
-		fixedWidth = 4 + 2 + timestampWidth + versionWidth + 5 + 8 // + version + extra gap
+		return m.viewEmpty()
 	}
 
-	linesWidth := 5
-	remaining := width - fixedWidth
-	titleWidth := remaining * 65 / 100 // project column omitted here, so give title a larger share than the flat list's 60%
-	if titleWidth < 30 {
-		titleWidth = 30
-	}
+	l := m.groupedLayout()
+	width, compact, gutterWidth := l.width, l.compact, l.gutterWidth
+	timestampWidth, versionWidth, linesWidth, titleWidth := l.timestampWidth, l.versionWidth, l.linesWidth, l.titleWidth
 
 	var s strings.Builder
 
 	// Header
 	s.WriteString(m.renderTabBar())
 	s.WriteString("\n")
+	tableStart := s.Len()
 	s.WriteString(dimStyle.Render(strings.Repeat("─", width)))
 	s.WriteString("\n")
 
 	// Column headers, same columns as the flat list minus PROJECT (it is the
-	// group header). Leading 5 spaces align with the indented chat rows below:
-	// indicator (3) + the 2-space nesting indent.
-	var header string
-	if compact {
-		headerFmt := fmt.Sprintf("     %%-*s  %%-%ds  %%-%ds", linesWidth, titleWidth)
-		header = fmt.Sprintf(headerFmt, timestampWidth, "TIMESTAMP", "LINES", "TITLE")
-	} else {
-		headerFmt := fmt.Sprintf("     %%-*s  %%-%ds  %%-%ds  %%-%ds", versionWidth, linesWidth, titleWidth)
-		header = fmt.Sprintf(headerFmt, timestampWidth, "TIMESTAMP", "VERSION", "LINES", "TITLE")
-	}
-	s.WriteString(dimStyle.Render(header))
+	// group header).
+	s.WriteString(dimStyle.Render(strings.Repeat(" ", gutterWidth) + l.header))
 	s.WriteString("\n")
 	s.WriteString(dimStyle.Render(strings.Repeat("─", width)))
 	s.WriteString("\n")
 
-	// Rows
-	visibleHeight := m.visibleHeight()
+	// Rows, less the ones taken by an expanded chat's files
+	visibleHeight := m.listHeight()
 	rowCount := len(m.groupRows)
 
 	start := m.scrollOffset
@@ -997,8 +2861,13 @@ func (m model) viewGrouped() string {
 		end = rowCount
 	}
 
+	var sizes map[string]int64
+	if m.sizeSort && m.chatSizes != nil {
+		sizes = m.projectSizes()
+	}
 	for i := start; i < end; i++ {
 		row := m.groupRows[i]
+		s.WriteString(m.gutter(i, gutterWidth))
 
 		if row.isHeader {
 			// Project header row
@@ -1016,12 +2885,16 @@ func (m model) viewGrouped() string {
 				indicator = "[~]"
 			}
 
-			projectClean := strings.NewReplacer("\n", " ").Replace(row.project)
-			countInfo := dimStyle.Render(fmt.Sprintf("(%d chats, %d selected)", total, sel))
+			projectClean := strings.NewReplacer("\n", " ").Replace(m.projectLabel(row.project, row.path))
+			info := fmt.Sprintf("(%d chats, %d selected)", total, sel)
+			if sizes != nil {
+				info = fmt.Sprintf("(%d chats, %d selected, %s)", total, sel, formatBytes(sizes[row.project]))
+			}
+			countInfo := dimStyle.Render(info)
 			left := fmt.Sprintf("%s %s %s", indicator, arrow, projectClean)
 
 			// Anchor the count info to the right edge (same gap trick as the tab bar).
-			gap := width - lipgloss.Width(left) - lipgloss.Width(countInfo)
+			gap := width - gutterWidth - lipgloss.Width(left) - lipgloss.Width(countInfo)
 			if gap < 1 {
 				gap = 1
 			}
@@ -1031,78 +2904,108 @@ func (m model) viewGrouped() string {
 			if sel > 0 && sel == total {
 				style = selectedStyle
 			}
+			if m.focus {
+				style = dimStyle
+			}
 			if i == m.cursor {
 				style = cursorStyle
 			}
 			s.WriteString(style.Render(line))
 			s.WriteString("\n")
 		} else {
-			// Chat row (indented under project)
-			chat := m.chats[row.chatIdx]
-
-			// Full timestamp in both layouts (project view has the room for it).
-			timestamp := runewidth.Truncate(chat.Timestamp, timestampWidth, "")
-
-			var version string
-			if versionWidth > 0 {
-				version = runewidth.Truncate(chat.Version, versionWidth-1, "")
-			}
-			var lines string
-			switch {
-			case chat.LineCount == 0:
-				lines = "-"
-			case chat.LineCount >= 10000:
-				lines = fmt.Sprintf("%dk", chat.LineCount/1000)
-			default:
-				lines = fmt.Sprintf("%d", chat.LineCount)
-			}
+			key := rowKey{uuid: m.chats[row.chatIdx].UUID, project: m.chats[row.chatIdx].Project, grouped: true,
+				width: width, gutter: gutterWidth, selected: m.selected[row.chatIdx], cursor: i == m.cursor, size: m.sizeCell(m.chats[row.chatIdx]),
+				staged: m.isStaged(row.chatIdx), focus: m.focus}
+			s.WriteString(m.cachedRow(key, func() string {
+				// Chat row (indented under project)
+				chat := m.chats[row.chatIdx]
+
+				// Full timestamp in both layouts (project view has the room for it).
+				timestamp := runewidth.Truncate(chat.Timestamp, timestampWidth, "")
+
+				var version string
+				if versionWidth > 0 {
+					version = runewidth.Truncate(chat.Version, versionWidth-1, "")
+				}
+				var lines string
+				switch {
+				case chat.LineCount == 0:
+					lines = "-"
+				case chat.LineCount >= 10000:
+					lines = fmt.Sprintf("%dk", chat.LineCount/1000)
+				default:
+					lines = fmt.Sprintf("%d", chat.LineCount)
+				}
 
-			titleClean := strings.NewReplacer("\n", " ").Replace(chat.Title)
-			title := runewidth.Truncate(titleClean, titleWidth, "..")
+				title := displayTitle(chatTitleLabel(chat), titleWidth)
 
-			indicator := "[ ]"
-			if m.selected[row.chatIdx] {
-				indicator = "[✓]"
-			}
+				indicator := "[ ]"
+				if m.selected[row.chatIdx] {
+					indicator = "[✓]"
+				} else if m.isStaged(row.chatIdx) {
+					indicator = "[x]"
+				} else if chat.Protected {
+					indicator = "[P]"
+				}
 
-			var line string
-			if compact {
-				lineFmt := fmt.Sprintf("%%s  %%-*s  %%-%ds  %%-%ds", linesWidth, titleWidth)
-				line = fmt.Sprintf(lineFmt, indicator, timestampWidth, timestamp, lines, title)
-			} else {
-				lineFmt := fmt.Sprintf("%%s  %%-*s  %%-%ds  %%-%ds  %%-%ds", versionWidth, linesWidth, titleWidth)
-				line = fmt.Sprintf(lineFmt, indicator, timestampWidth, timestamp, version, lines, title)
-			}
+				var line string
+				if compact {
+					lineFmt := fmt.Sprintf("%%s  %%-*s  %%-%ds  %%s  %%s", linesWidth)
+					line = fmt.Sprintf(lineFmt, indicator, timestampWidth, timestamp, lines,
+						padRight(planMark(chat), planWidth), padRight(title, titleWidth))
+				} else {
+					lineFmt := fmt.Sprintf("%%s  %%-*s  %%-%ds  %%-%ds  %%s  %%s  %%s", versionWidth, linesWidth)
+					line = fmt.Sprintf(lineFmt, indicator, timestampWidth, timestamp, version, lines, padRight(m.sizeCell(chat), sizeWidth),
+						padRight(planMark(chat), planWidth), padRight(title, titleWidth))
+				}
 
-			style := lipgloss.NewStyle()
-			if m.selected[row.chatIdx] {
-				style = selectedStyle
-			}
+				style := lipgloss.NewStyle()
+				if chat.Problem != "" {
+					style = warningStyle
+				}
+				if chat.Protected {
+					style = dimStyle
+				}
+				if m.selected[row.chatIdx] {
+					style = selectedStyle
+				}
+				if m.focus {
+					style = dimStyle
+				}
+				if i == m.cursor {
+					style = cursorStyle
+				}
+				return style.Render(line)
+			}))
+			s.WriteString("\n")
 			if i == m.cursor {
-				style = cursorStyle
+				for _, row := range m.expandedRows(width - gutterWidth) {
+					s.WriteString(strings.Repeat(" ", gutterWidth) + row + "\n")
+				}
 			}
-			s.WriteString(style.Render(line))
-			s.WriteString("\n")
 		}
 	}
 
 	// Scroll indicator
 	if rowCount > visibleHeight {
-		scrollInfo := fmt.Sprintf("[%d-%d/%d]", start+1, end, rowCount)
-		s.WriteString(dimStyle.Render(scrollInfo))
+		s.WriteString(dimStyle.Render(scrollInfo(start, end, rowCount)))
 		s.WriteString("\n")
 	}
 
 	// Bottom separator
 	s.WriteString(dimStyle.Render(strings.Repeat("─", width)))
 	s.WriteString("\n")
+	// On narrow terminals only the part of the list at hOffset is shown
+	out := s.String()
+	s.Reset()
+	s.WriteString(out[:tableStart] + m.scrollTable(out[tableStart:]))
 
 	// Status messages
 	if m.error != "" {
 		s.WriteString(errorStyle.Render("Error: " + m.error))
 		s.WriteString("\n")
 	} else if m.deleted > 0 {
-		s.WriteString(successStyle.Render(fmt.Sprintf("✓ Deleted %d chat(s)", m.deleted)))
+		s.WriteString(successStyle.Render(m.deletedStatus()))
 		s.WriteString("\n")
 	} else if m.copiedMsg != "" {
 		s.WriteString(successStyle.Render("✓ " + m.copiedMsg))
@@ -1111,38 +3014,360 @@ func (m model) viewGrouped() string {
 
 	// Help / Confirmation dialog
 	if m.confirmDelete {
-		s.WriteString(errorStyle.Render(fmt.Sprintf("Delete %d chat(s)?", len(m.selected))))
-		s.WriteString(" ")
-		s.WriteString(helpStyle.Render("[ENTER=Yes] [ESC=No]"))
-		s.WriteString("\n")
-	} else if compact {
-		actionsLine := "Actions:    <Space>: Toggle | Enter: Expand | a: Toggle All | d: Delete | c: Copy | r: Refresh | q: Quit"
-		navLine := "Navigation: ↑/↓: Items | ←/→: Tabs | f/b: PgDn/PgUp | F/B: Half | g/G: Home/End"
-		s.WriteString(helpStyle.Render(actionsLine))
-		s.WriteString("\n")
-		s.WriteString(helpStyle.Render(navLine))
-		s.WriteString("\n")
-	} else {
-		help := "↑/↓:Items | ←/→:Tabs | Enter:Expand | <Space>:Toggle | a:Toggle All | c:Copy ID | d:Delete | r:Refresh | q/esc:Quit"
-		s.WriteString(helpStyle.Render(help))
+		s.WriteString(m.renderConfirm(width))
+	} else if m.selectAllPending {
+		s.WriteString(m.renderSelectAllConfirm())
+	} else if m.artifactMenu {
+		s.WriteString(m.renderArtifactMenu())
+	} else if m.promptAction != promptNone {
+		s.WriteString(m.renderPrompt())
+	} else if !m.helpHidden() {
+		s.WriteString(helpStyle.Render(helpHint))
 		s.WriteString("\n")
 	}
 
 	return s.String()
 }
 
-func (m model) deleteSelectedChats() tea.Cmd {
+// historyLines renders the session's deletions, newest first.
+func (m model) historyLines() []string {
+	if len(m.history) == 0 {
+		return []string{"No chats deleted in this session."}
+	}
+	var lines []string
+	for i := len(m.history) - 1; i >= 0; i-- {
+		e := m.history[i]
+		lines = append(lines, fmt.Sprintf("%s  %s  %s  (%s)",
+			e.at.Format("15:04:05"), e.chat.UUID, e.chat.Title, m.chatProjectLabel(e.chat)))
+	}
+	return lines
+}
+
+// chatTitleLabel is the title shown in the list, led by the chat's note.
+func chatTitleLabel(chat Chat) string {
+	title := chat.Title
+	if chat.DerivedTitle != "" {
+		title = "✱ " + title
+	}
+	if chat.Compacted {
+		title = "∑ " + title
+	}
+	if chat.Note != "" {
+		title = "✎ " + chat.Note + " — " + title
+	}
+	if chat.Problem != "" {
+		title = "⚠ " + chat.Problem + " — " + title
+	}
+	return title
+}
+
+// displayTitle flattens a title to one line and truncates it to width columns.
+func displayTitle(title string, width int) string {
+	clean := strings.NewReplacer("\n", " ").Replace(title)
+	return runewidth.Truncate(clean, width, "..")
+}
+
+// confirmRecapMax is how many selected titles the confirm dialog lists before
+// collapsing the rest into "...and N more".
+const confirmRecapMax = 5
+
+// preflightMin is the batch size from which the confirm dialog adds what the
+// deletion does to shared files; smaller batches rarely hold surprises.
+const preflightMin = 20
+
+// startPreflight analyzes the shared files of the selected chats in the
+// background, for the confirm dialog (see preflightDeletion).
+func (m *model) startPreflight() tea.Cmd {
+	m.preflightGen++
+	m.preflight = nil
+	gen := m.preflightGen
+	var chats []Chat
+	for _, idx := range m.selectedIndices() {
+		chats = append(chats, m.chats[idx])
+	}
+	return func() tea.Msg {
+		return preflightMsg{report: preflightDeletion(chats), gen: gen}
+	}
+}
+
+// selectedIndices returns the selected chat indices in list order.
+func (m model) selectedIndices() []int {
+	var indices []int
+	for i := range m.chats {
+		if m.selected[i] {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// confirmRecap lists the titles of what is about to be deleted so a wrong
+// row is caught before confirming; during the one-by-one review it shows the
+// chat being decided instead. Titles are untruncated; the caller fits them.
+func (m model) confirmRecap() []string {
+	if m.stepQueue != nil {
+		chat := m.chats[m.stepQueue[0]]
+		pos := m.stepTotal - len(m.stepQueue) + 1
+		details := fmt.Sprintf("%s | %s | %d lines", m.chatProjectLabel(chat), chat.Timestamp, chat.LineCount)
+		if chat.PlanPath != "" {
+			details += " | plan"
+		}
+		if m.inCWD(chat) {
+			details += " | current directory"
+		}
+		return []string{
+			fmt.Sprintf("[%d/%d] %s", pos, m.stepTotal, chatTitleLabel(chat)),
+			"      " + details,
+		}
+	}
+	indices := m.selectedIndices()
+	var lines []string
+	for n, idx := range indices {
+		if n == confirmRecapMax {
+			lines = append(lines, fmt.Sprintf("...and %d more", len(indices)-confirmRecapMax))
+			break
+		}
+		chat := m.chats[idx]
+		line := fmt.Sprintf("• %s (%s)", chat.Title, m.chatProjectLabel(chat))
+		if m.inCWD(chat) {
+			line += " [current directory]"
+		}
+		lines = append(lines, line)
+	}
+	if len(indices) >= preflightMin {
+		if m.preflight == nil {
+			lines = append(lines, "Shared files: analyzing...")
+		} else {
+			lines = append(lines, "Shared files: "+m.preflight.summary())
+		}
+	}
+	return lines
+}
+
+// workingDir returns the current directory, or "" if it can't be determined.
+func workingDir() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return dir
+}
+
+// inCWD reports whether chat belongs to the project the tool was started in:
+// its decoded project path is the working directory or one of its parents.
+func (m model) inCWD(chat Chat) bool {
+	if m.cwd == "" || chat.ProjectPath == "" {
+		return false
+	}
+	return m.cwd == chat.ProjectPath || strings.HasPrefix(m.cwd, chat.ProjectPath+string(filepath.Separator))
+}
+
+// selectedInCWD counts the selected chats that belong to the working
+// directory's project.
+func (m model) selectedInCWD() int {
+	n := 0
+	for _, idx := range m.selectedIndices() {
+		if m.inCWD(m.chats[idx]) {
+			n++
+		}
+	}
+	return n
+}
+
+// renderArtifactMenu lists the artifact filters, picked by digit.
+func (m model) renderArtifactMenu() string {
+	var items []string
+	for i, f := range artifactFilters[1:] {
+		items = append(items, fmt.Sprintf("%d %s", i+1, f.label))
+	}
+	items = append(items, "0 all")
+	return errorStyle.Render("Only chats with:") + " " + helpStyle.Render(strings.Join(items, "  ")) + "\n"
+}
+
+// renderSelectAllConfirm asks to acknowledge selecting a big list.
+func (m model) renderSelectAllConfirm() string {
+	question := fmt.Sprintf("Select all %d chats?", m.selectableCount())
+	return errorStyle.Render(question) + " " + helpStyle.Render("[a=Yes] [any other key=No]") + "\n"
+}
+
+// selectAll selects every selectable chat.
+func (m *model) selectAll() {
+	m.autoSelected = false
+	for i := range m.chats {
+		if m.selectable(i) {
+			m.selected[i] = true
+		}
+	}
+}
+
+// renderConfirm renders the recap and the confirmation question.
+func (m model) renderConfirm(width int) string {
+	var s strings.Builder
+	for _, line := range m.confirmRecap() {
+		line = displayTitle(line, width-2)
+		// Shared files kept or orphaned are what a big batch might surprise with
+		if p := m.preflight; p != nil && (p.Shared > 0 || p.Orphaned > 0) && strings.HasPrefix(line, "Shared files:") {
+			line = warningStyle.Render(line)
+		}
+		s.WriteString("  " + line)
+		s.WriteString("\n")
+	}
+	// Name the real consequence: a permanent delete must never read as undoable.
+	verb, suffix, consequence := "Permanently delete", "", "(cannot be undone)"
+	if m.trashing() {
+		verb, suffix, consequence = "Move", " to trash", "(recoverable)"
+	}
+	question := fmt.Sprintf("%s %d chat(s)%s?", verb, len(m.selected), suffix)
+	if m.deleteProject != "" {
+		project := m.projectLabel(m.deleteProject, m.projectPathOf(m.deleteProject))
+		question = fmt.Sprintf("%s project %s and all %d chat(s)%s?", verb, project, len(m.selected), suffix)
+	}
+	if m.deleteInView {
+		scope := "no filters active"
+		if filters := m.activeFilters(); len(filters) > 0 {
+			scope = "[" + strings.Join(filters, "] [") + "]"
+		}
+		question = fmt.Sprintf("%s all %d chat(s) in view (%s)%s?", verb, len(m.selected), scope, suffix)
+	}
+	if n := m.selectedInCWD(); n > 0 && m.cwdConfirmed {
+		question = fmt.Sprintf("%d of these chat(s) belong to the project you are in (%s). %s%s anyway?", n, m.cwd, verb, suffix)
+	}
+	hint := "[ENTER=Yes] [t=To trash] [P=For good] [s=One by one] [ESC/n/q=No]"
+	if m.stepQueue != nil {
+		question = fmt.Sprintf("%s this chat%s?", verb, suffix)
+		hint = "[y/ENTER=Yes] [n=Keep] [Y=Yes to rest] [N=Keep rest] [ESC=Cancel]"
+	}
+	s.WriteString(errorStyle.Render(question))
+	s.WriteString(" ")
+	s.WriteString(dimStyle.Render(consequence))
+	s.WriteString(" ")
+	s.WriteString(helpStyle.Render(hint))
+	s.WriteString("\n")
+	return s.String()
+}
+
+// cancelDelete closes the confirm dialog; a selection made by the deleting
+// key itself is dropped so the next d acts on the cursor again.
+func (m *model) cancelDelete() {
+	m.confirmDelete = false
+	m.cwdConfirmed = false
+	m.deleteProject = ""
+	m.deleteInView = false
+	m.deleteChoice = ""
+	m.preflight = nil
+	m.stepQueue = nil
+	if m.autoSelected {
+		m.selected = make(map[int]bool)
+		m.autoSelected = false
+	}
+}
+
+// updateStep handles the one-by-one review: every chat is kept (deselected)
+// or left selected, and the deletion runs once all are decided. Esc restores
+// the selection the review started from and cancels.
+func (m model) updateStep(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "enter":
+		m.stepQueue = m.stepQueue[1:]
+	case "n":
+		delete(m.selected, m.stepQueue[0])
+		m.stepQueue = m.stepQueue[1:]
+	case "Y":
+		m.stepQueue = m.stepQueue[:0]
+	case "N":
+		for _, idx := range m.stepQueue {
+			delete(m.selected, idx)
+		}
+		m.stepQueue = m.stepQueue[:0]
+	case "esc", "q":
+		m.selected = make(map[int]bool)
+		for _, idx := range m.stepOrig {
+			m.selected[idx] = true
+		}
+		m.cancelDelete()
+		return m, nil
+	default:
+		return m, nil
+	}
+	if len(m.stepQueue) > 0 {
+		return m, nil
+	}
+
+	m.stepQueue = nil
+	if len(m.selected) == 0 {
+		m.cancelDelete()
+		m.copiedMsg = "Kept every chat"
+		return m, nil
+	}
+	if m.updating {
+		// Back to the dialog with the chats decided on, to confirm later
+		m.error = updateInProgress
+		return m, nil
+	}
+	m.deleting = true
+	m.stopDelete = new(atomic.Bool)
+	return m, m.deleteSelectedChats(m.stopDelete)
+}
+
+// stopAndQuit asks the running deletion to stop after its current chat; the
+// program quits when the deletion reports back.
+func (m model) stopAndQuit() (tea.Model, tea.Cmd) {
+	m.stopDelete.Store(true)
+	m.quitPending = true
+	m.copiedMsg = "Finishing the chat being deleted, then quitting..."
+	return m, nil
+}
+
+func (m model) deleteSelectedChats(stop *atomic.Bool) tea.Cmd {
 	return func() tea.Msg {
 		var toDelete []Chat
 		for idx := range m.selected {
-			if idx < len(m.chats) {
+			if m.selectable(idx) {
 				toDelete = append(toDelete, m.chats[idx])
 			}
 		}
-		count, err := deleteChats(toDelete)
+		var res deleteResult
+		var err error
+		done := deleteCompleteMsg{trashed: m.trashing(), total: len(toDelete)}
+		if done.trashed {
+			var batch string
+			batch, res, err = moveChatsToTrash(toDelete, "delete", stop)
+			done.note = fmt.Sprintf(" (batch %s)", batch)
+		} else {
+			res, err = deleteChats(toDelete, stop)
+		}
+		logErr := appendDeletionLog(res.records)
+		// Chats are processed in order, so the first Deleted of them are gone,
+		// also when an error stopped the batch part-way
+		done.chats = toDelete[:res.Deleted]
+		done.count = res.Deleted
+		done.freed = res.FreedBytes
 		if err != nil {
-			return errMsg(err.Error())
+			done.err = err.Error()
+		}
+		if m.cfg != nil && m.cfg.VerifyDeletes {
+			done.leftovers = verifyDeletion(res, toDelete)
+		}
+		if err := runPostDeleteHook(postDeleteHook, done.chats, done.trashed); err != nil {
+			done.hookErr = err.Error()
+		}
+		if m.deleteProject != "" && len(toDelete) > 0 && err == nil {
+			removed, err := removeProjectDirIfEmpty(toDelete[0].root(), m.deleteProject)
+			switch {
+			case err != nil:
+				done.note += fmt.Sprintf(", project directory kept: %v", err)
+			case removed:
+				done.note += ", removed project directory"
+			default:
+				done.note += ", project directory kept (other files remain)"
+			}
+		}
+		if shared := res.sharedNote(); shared != "" {
+			done.note += "; " + shared
+		}
+		if logErr != nil {
+			done.note += fmt.Sprintf("; deletion log not written: %v", logErr)
 		}
-		return deleteCompleteMsg{count: count}
+		return done
 	}
 }
@@ -2,12 +2,18 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
 	"github.com/muesli/termenv"
 )
 
@@ -55,9 +61,8 @@ func makeTestChats(n int) []Chat {
 // Layout constants — update these if the View() layout changes.
 // If a test fails, it means View() gained or lost a fixed line.
 const (
-	fixedHeaderLines        = 4 // tabbar(+stats) + top-separator + col-headers + separator
-	fixedFooterLines        = 2 // bottom-separator + help (OR confirmation, same count)
-	fixedFooterLinesCompact = 3 // bottom-separator + 2 help lines (actions + navigation)
+	fixedHeaderLines = 4 // tabbar(+stats) + top-separator + col-headers + separator
+	fixedFooterLines = 2 // bottom-separator + help (OR confirmation, same count)
 
 	normalWidth  = compactModeWidth + 10 // full mode: timestamp 19, version visible, 1 help line
 	compactWidth = compactModeWidth - 30 // compact mode: timestamp 11, no version, 2 help lines
@@ -86,6 +91,70 @@ func TestView_FewChats_NoScroll(t *testing.T) {
 	}
 }
 
+func TestUpdateH_HidesHelpForMoreRows(t *testing.T) {
+	for _, width := range []int{normalWidth, compactWidth} {
+		m := makeTestModel(makeTestChats(30), width, 20)
+		before := m.visibleHeight()
+		m = send(m, keyRune('h'))
+		if got := m.visibleHeight(); got != before+1 {
+			t.Errorf("width %d: visibleHeight = %d after h, want %d", width, got, before+1)
+		}
+		out := m.View()
+		if strings.Contains(stripANSI(out), helpHint) {
+			t.Errorf("width %d: help line still shown:\n%s", width, stripANSI(out))
+		}
+		if got := viewLineCount(out); got > m.height {
+			t.Errorf("width %d: view has %d lines, more than the height %d", width, got, m.height)
+		}
+
+		// Dialogs still get their line.
+		m = send(m, keyRune('d'))
+		if !strings.Contains(stripANSI(m.View()), "[ESC/n/q=No]") {
+			t.Errorf("width %d: confirm dialog hidden along with the help", width)
+		}
+	}
+}
+
+func TestUpdateHash_CyclesLineNumbers(t *testing.T) {
+	m := makeTestModel(makeTestChats(30), normalWidth, 20)
+	for i := 0; i < 3; i++ {
+		m = send(m, keyRune('j'))
+	}
+	rowOf := func(out, title string) string {
+		for _, line := range strings.Split(stripANSI(out), "\n") {
+			if strings.Contains(line, title+" ") {
+				return line
+			}
+		}
+		t.Fatalf("no row for %q:\n%s", title, stripANSI(out))
+		return ""
+	}
+	plain := rowOf(m.View(), "Chat number 0")
+
+	m = send(m, keyRune('#'))
+	out := m.View()
+	if row := rowOf(out, "Chat number 0"); !strings.HasPrefix(row, " 1 [ ]") {
+		t.Errorf("absolute numbers: first row = %q", row)
+	}
+	if row := rowOf(out, "Chat number 0"); lipgloss.Width(row) != lipgloss.Width(plain) {
+		t.Errorf("the gutter should come out of the title, row width %d, want %d", lipgloss.Width(row), lipgloss.Width(plain))
+	}
+
+	m = send(m, keyRune('#'))
+	out = m.View()
+	if row := rowOf(out, "Chat number 0"); !strings.HasPrefix(row, " 3 [ ]") {
+		t.Errorf("relative numbers: first row = %q, want its distance from the cursor", row)
+	}
+	if row := rowOf(out, "Chat number 3"); !strings.HasPrefix(row, " 4 [ ]") {
+		t.Errorf("relative numbers: cursor row = %q, want its own number", row)
+	}
+
+	m = send(m, keyRune('#'))
+	if row := rowOf(m.View(), "Chat number 0"); row != plain {
+		t.Errorf("line numbers off: row = %q, want %q", row, plain)
+	}
+}
+
 func TestView_ManyChats_WithScroll(t *testing.T) {
 	// 30 chats, height=20 → visibleHeight=11 → scroll indicator shown
 	chats := makeTestChats(30)
@@ -126,17 +195,37 @@ func TestView_WithDeletedMessage(t *testing.T) {
 }
 
 func TestView_ConfirmDialog_ReplacesHelp(t *testing.T) {
-	// confirmDelete replaces help line — total count must be SAME as normal
+	// confirmDelete replaces the help line with the question, plus one recap
+	// line per selected title above it.
 	chats := makeTestChats(5)
 	m := makeTestModel(chats, normalWidth, 20)
 	m.selected[0] = true
 	m.confirmDelete = true
-	// expected: header(4) + chats(5) + scroll(0) + status(0) + footer(2) = 11
-	// same as TestView_FewChats_NoScroll — confirmation replaces help but separator stays
-	expected := fixedHeaderLines + 5 + 0 + 0 + fixedFooterLines
+	// expected: header(4) + chats(5) + scroll(0) + status(0) + recap(1) + footer(2) = 12
+	expected := fixedHeaderLines + 5 + 0 + 0 + 1 + fixedFooterLines
 	got := viewLineCount(m.View())
 	if got != expected {
-		t.Errorf("confirm dialog: expected %d lines (same as help), got %d", expected, got)
+		t.Errorf("confirm dialog: expected %d lines (help + recap), got %d", expected, got)
+	}
+}
+
+// The recap lists at most confirmRecapMax titles plus a "more" line, and the
+// list shrinks to make room so the view never outgrows the terminal.
+func TestView_ConfirmRecap_FitsTerminal(t *testing.T) {
+	chats := makeTestChats(30)
+	m := makeTestModel(chats, normalWidth, 20)
+	for i := 0; i < 8; i++ {
+		m.selected[i] = true
+	}
+	m.confirmDelete = true
+
+	out := stripANSI(m.View())
+	if !strings.Contains(out, "Chat number 4") || !strings.Contains(out, "...and 3 more") {
+		t.Errorf("recap missing titles or overflow line:\n%s", out)
+	}
+	// Recap rows are taken from the list, not pushed below the screen.
+	if got := viewLineCount(m.View()); got > m.height {
+		t.Errorf("confirm view has %d lines, taller than terminal height %d", got, m.height)
 	}
 }
 
@@ -145,6 +234,7 @@ func TestView_SmallTerminal_UsesMinVisibleHeight(t *testing.T) {
 	// 3 chats < 10 (fallback visible height) → no scroll indicator
 	chats := makeTestChats(3)
 	m := makeTestModel(chats, normalWidth, 5)
+	m.cfg = &Config{MinHeight: -1} // draw the list anyway
 	// expected: header(4) + chats(3) + scroll(0) + status(0) + footer(2) = 9
 	expected := fixedHeaderLines + 3 + 0 + 0 + fixedFooterLines
 	got := viewLineCount(m.View())
@@ -153,6 +243,19 @@ func TestView_SmallTerminal_UsesMinVisibleHeight(t *testing.T) {
 	}
 }
 
+func TestView_TooSmallTerminal_ShowsNotice(t *testing.T) {
+	m := makeTestModel(makeTestChats(3), 30, 8)
+	out := stripANSI(m.View())
+	if !strings.Contains(out, "Terminal too small") || !strings.Contains(out, "40x12") || strings.Contains(out, "Chat number") {
+		t.Errorf("a 30x8 terminal should get the notice instead of the list:\n%s", out)
+	}
+
+	m.cfg = &Config{MinWidth: 20, MinHeight: 6}
+	if out := stripANSI(m.View()); strings.Contains(out, "Terminal too small") {
+		t.Errorf("min_width/min_height should lower the limit:\n%s", out)
+	}
+}
+
 func TestView_TitleWithNewline_DoesNotBreakLayout(t *testing.T) {
 	// Regression: if chat.Title contains \n it used to create extra lines,
 	// breaking the entire UI layout. getChatTitle cleans this via cleanSystemTags,
@@ -202,15 +305,18 @@ func TestView_ProjectWithNewline_DoesNotBreakLayout(t *testing.T) {
 	}
 }
 
-func TestView_CompactMode_TwoHelpLines(t *testing.T) {
-	// width < compactModeWidth: compact mode renders 2 help lines instead of 1
+func TestView_CompactMode_OneHelpLine(t *testing.T) {
+	// width < compactModeWidth: the help line is "? for help" as in full mode
 	chats := makeTestChats(5)
 	m := makeTestModel(chats, compactWidth, 20)
-	// expected: header(4) + chats(5) + scroll(0) + status(0) + footer(3) = 12
-	expected := fixedHeaderLines + 5 + 0 + 0 + fixedFooterLinesCompact
-	got := viewLineCount(m.View())
-	if got != expected {
-		t.Errorf("compact mode two help lines: expected %d lines, got %d", expected, got)
+	// expected: header(4) + chats(5) + scroll(0) + status(0) + footer(2) = 11
+	expected := fixedHeaderLines + 5 + 0 + 0 + fixedFooterLines
+	output := m.View()
+	if got := viewLineCount(output); got != expected {
+		t.Errorf("compact mode help line: expected %d lines, got %d", expected, got)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(stripANSI(output)), helpHint) {
+		t.Errorf("footer should be %q:\n%s", helpHint, stripANSI(output))
 	}
 }
 
@@ -309,8 +415,8 @@ func TestViewGrouped_ConfirmDialog_ReplacesHelp(t *testing.T) {
 	m := makeGroupedModel(chats, normalWidth, 30)
 	m.selected[0] = true
 	m.confirmDelete = true
-	// Same line count as without confirm (dialog replaces help)
-	expected := fixedHeaderLines + 2 + 0 + 0 + fixedFooterLines
+	// Dialog replaces help, plus one recap line for the selected title
+	expected := fixedHeaderLines + 2 + 0 + 0 + 1 + fixedFooterLines
 	got := viewLineCount(m.View())
 	if got != expected {
 		t.Errorf("grouped confirm: expected %d lines, got %d", expected, got)
@@ -425,6 +531,16 @@ func keyRune(r rune) tea.KeyMsg {
 	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}}
 }
 
+// loadedModel builds the model and runs its startup scan to completion.
+func loadedModel(cfg *Config, protect []string) model {
+	m := initialModel(cfg, protect)
+	for cmd := startScan(); cmd != nil; {
+		next, c := m.update(cmd())
+		m, cmd = next.(model), c
+	}
+	return m
+}
+
 // Regression: d → esc must fully clear auto-selection so the next d
 // acts on the chat under the new cursor position, not the stale one.
 func TestUpdateD_AutoSelectClearsOnCancel(t *testing.T) {
@@ -474,6 +590,59 @@ func TestUpdateD_AutoSelectClearsOnCancel(t *testing.T) {
 	}
 }
 
+func TestUpdateConfirm_StepsThroughChats(t *testing.T) {
+	m := makeTestModel(makeTestChats(3), normalWidth, 20)
+	for i := 0; i < 3; i++ {
+		m.selected[i] = true
+	}
+	m = send(m, keyRune('d'))
+	m = send(m, keyRune('s'))
+	if out := stripANSI(m.View()); !strings.Contains(out, "[1/3] Chat number 0") {
+		t.Fatalf("review should show the first chat:\n%s", out)
+	}
+
+	// Esc restores the selection the review started from.
+	m = send(m, keyRune('n'))
+	m = send(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.confirmDelete || len(m.selected) != 3 {
+		t.Fatalf("esc should cancel with the selection intact: confirm=%v selected=%v", m.confirmDelete, m.selected)
+	}
+
+	m = send(m, keyRune('d'))
+	m = send(m, keyRune('s'))
+	m = send(m, keyRune('n')) // keep 0
+	if out := stripANSI(m.View()); !strings.Contains(out, "[2/3] Chat number 1") {
+		t.Errorf("review should move to the second chat:\n%s", out)
+	}
+	m = send(m, keyRune('y')) // delete 1
+	next, cmd := m.Update(keyRune('N'))
+	m = next.(model)
+	if !m.deleting || cmd == nil {
+		t.Fatalf("deciding the last chat should start the deletion, deleting=%v", m.deleting)
+	}
+	if len(m.selected) != 1 || !m.selected[1] {
+		t.Errorf("only the chat answered y should be deleted, selected=%v", m.selected)
+	}
+}
+
+func TestUpdateConfirm_QCancels(t *testing.T) {
+	m := makeTestModel(makeTestChats(3), normalWidth, 20)
+	m = send(m, keyRune('d'))
+	if out := stripANSI(m.View()); !strings.Contains(out, "[ESC/n/q=No]") {
+		t.Errorf("confirm dialog should list every cancel key:\n%s", out)
+	}
+	next, cmd := m.Update(keyRune('q'))
+	m = next.(model)
+	if m.confirmDelete || len(m.selected) != 0 {
+		t.Errorf("q should cancel the dialog: confirm=%v, selected=%v", m.confirmDelete, m.selected)
+	}
+	if cmd != nil {
+		if _, quit := cmd().(tea.QuitMsg); quit {
+			t.Error("q in the confirm dialog must not quit")
+		}
+	}
+}
+
 // Explicit selection via Space must win over cursor position: d with an
 // existing selection deletes the explicit set, ignoring where the cursor
 // is, and esc must not wipe the explicit selection.
@@ -651,8 +820,8 @@ func TestVisibleHeight(t *testing.T) {
 		{width: normalWidth, height: 40, want: 31},  // 40 - 9 = 31
 		{width: normalWidth, height: 10, want: 1},   // 10 - 9 = 1
 		{width: normalWidth, height: 9, want: 10},   // 9 - 9 = 0 < 1 → fallback 10
-		{width: compactWidth, height: 20, want: 10}, // compact: 20 - 10 = 10
-		{width: compactWidth, height: 5, want: 10},  // compact: 5 - 10 < 1 → fallback 10
+		{width: compactWidth, height: 20, want: 11}, // compact: 20 - 9 = 11
+		{width: compactWidth, height: 5, want: 10},  // compact: 5 - 9 < 1 → fallback 10
 	}
 	for _, tt := range tests {
 		m := model{width: tt.width, height: tt.height}
@@ -663,3 +832,1788 @@ func TestVisibleHeight(t *testing.T) {
 		}
 	}
 }
+
+// ? opens the help overlay over the list; esc closes it without quitting and
+// without touching the list state underneath.
+func TestUpdateHelpOverlay_OpenClose(t *testing.T) {
+	chats := makeTestChats(3)
+	m := makeTestModel(chats, normalWidth, 20)
+	m.cursor = 2
+
+	m = send(m, keyRune('?'))
+	if m.overlay != overlayHelp {
+		t.Fatalf("overlay = %d after ?, want overlayHelp", m.overlay)
+	}
+	out := stripANSI(m.View())
+	for _, want := range []string{"Navigation", "Selection", "Actions"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("help overlay missing %q section", want)
+		}
+	}
+
+	// d must not open the delete dialog while the overlay is up.
+	m = send(m, keyRune('d'))
+	if m.confirmDelete {
+		t.Fatal("d leaked through the help overlay")
+	}
+
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = next.(model)
+	if cmd != nil {
+		t.Fatal("esc on help overlay must close it, not quit")
+	}
+	if m.overlay != overlayNone {
+		t.Fatalf("overlay still open after esc: %d", m.overlay)
+	}
+	if m.cursor != 2 {
+		t.Errorf("cursor = %d after closing help, want 2", m.cursor)
+	}
+}
+
+// Protected chats (exclude file) must be skipped by every selection path:
+// Space, select-all and d's auto-select.
+func TestUpdate_ProtectedChatNeverSelected(t *testing.T) {
+	chats := makeTestChats(3)
+	chats[0].Protected = true
+	m := makeTestModel(chats, normalWidth, 20)
+
+	m = send(m, keyRune(' '))
+	if m.selected[0] {
+		t.Fatal("space selected a protected chat")
+	}
+
+	m = send(m, keyRune('d'))
+	if m.confirmDelete || len(m.selected) != 0 {
+		t.Fatalf("d on a protected chat opened confirm: selected=%v", m.selected)
+	}
+
+	m = send(m, keyRune('a'))
+	if m.selected[0] {
+		t.Fatal("select all picked a protected chat")
+	}
+	if !m.selected[1] || !m.selected[2] {
+		t.Fatalf("select all missed unprotected chats: %v", m.selected)
+	}
+
+	// Second a toggles everything selectable back off.
+	m = send(m, keyRune('a'))
+	if len(m.selected) != 0 {
+		t.Fatalf("second a should deselect all, got %v", m.selected)
+	}
+}
+
+func TestApplyTheme(t *testing.T) {
+	t.Cleanup(func() { applyTheme(nil) })
+
+	for _, preset := range []string{"", "dark", "light", "mono", "Light"} {
+		if err := applyTheme(&ThemeConfig{Preset: preset}); err != nil {
+			t.Errorf("applyTheme(%q): %v", preset, err)
+		}
+	}
+	if err := applyTheme(&ThemeConfig{Preset: "neon"}); err == nil {
+		t.Error("applyTheme with unknown preset should fail")
+	}
+	if err := applyTheme(&ThemeConfig{Preset: "mono", Error: "#ff0000"}); err != nil {
+		t.Errorf("applyTheme with override: %v", err)
+	}
+
+	// The view must still render with any theme applied.
+	m := makeTestModel(makeTestChats(3), normalWidth, 20)
+	if got, want := viewLineCount(m.View()), fixedHeaderLines+3+fixedFooterLines; got != want {
+		t.Errorf("themed view: expected %d lines, got %d", want, got)
+	}
+}
+
+// Completed deletions accumulate in the session history across batches, the
+// stats line keeps the running total and H lists them newest first.
+func TestUpdateHistory_RecordsDeletedChats(t *testing.T) {
+	m := makeTestModel(makeTestChats(3), normalWidth, 20)
+
+	first := Chat{UUID: "gone-1", Title: "first batch", Project: "p"}
+	second := Chat{UUID: "gone-2", Title: "second batch", Project: "p"}
+	next, _ := m.Update(deleteCompleteMsg{count: 1, chats: []Chat{first}, freed: 1024})
+	m = next.(model)
+	next, _ = m.Update(deleteCompleteMsg{count: 1, chats: []Chat{second}, freed: 2048})
+	m = next.(model)
+
+	if len(m.history) != 2 {
+		t.Fatalf("history has %d entries, want 2", len(m.history))
+	}
+	want := "Session: 2 deleted, " + formatBytes(3072) + " freed"
+	if !strings.Contains(stripANSI(m.View()), want) {
+		t.Errorf("the empty list should show %q:\n%s", want, stripANSI(m.View()))
+	}
+	m.chats = makeTestChats(1)
+	if !strings.Contains(stripANSI(m.View()), want) {
+		t.Errorf("stats line should show %q:\n%s", want, stripANSI(m.View()))
+	}
+
+	m = send(m, keyRune('H'))
+	if m.overlay != overlayHistory {
+		t.Fatalf("overlay = %d after H, want overlayHistory", m.overlay)
+	}
+	if !strings.Contains(m.overlayLines[0], "second batch") {
+		t.Errorf("newest deletion should be listed first, got %q", m.overlayLines[0])
+	}
+
+	// A batch an error stopped part-way still records what it deleted
+	m.overlay = overlayNone
+	third := Chat{UUID: "gone-3", Title: "stopped batch", Project: "p"}
+	next, _ = m.Update(deleteCompleteMsg{count: 1, total: 2, chats: []Chat{third}, err: "permission denied"})
+	m = next.(model)
+	if len(m.history) != 3 || !strings.Contains(m.error, "stopped after 1 of 2 chat(s): permission denied") {
+		t.Errorf("after a failed batch: %d history entries, error %q", len(m.history), m.error)
+	}
+}
+
+// Selection changes emit a window-title command; pure navigation does not.
+func TestUpdate_WindowTitleTracksSelection(t *testing.T) {
+	m := makeTestModel(makeTestChats(3), normalWidth, 20)
+
+	next, cmd := m.Update(keyRune(' '))
+	m = next.(model)
+	if cmd == nil {
+		t.Fatal("selection change should update the window title")
+	}
+	if got, want := m.windowTitle(), "claude-chats — 3 chats, 1 selected"; got != want {
+		t.Errorf("windowTitle() = %q, want %q", got, want)
+	}
+
+	if _, cmd = m.Update(tea.KeyMsg{Type: tea.KeyDown}); cmd != nil {
+		t.Error("cursor movement should not touch the window title")
+	}
+}
+
+func TestView_ScanErrorShownInsteadOfEmpty(t *testing.T) {
+	m := makeTestModel(nil, normalWidth, 20)
+	m.scanErr = "permission denied reading /x/projects"
+	out := stripANSI(m.View())
+	if strings.Contains(out, "No chats found") || !strings.Contains(out, "permission denied") {
+		t.Errorf("scan error not surfaced in empty state:\n%s", out)
+	}
+	if got := strings.Count(out, "\n"); got != 3 {
+		t.Errorf("error state: expected 3 lines, got %d", got)
+	}
+}
+
+func TestUpdateExportJSONL_CopiesRawFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "chat.jsonl")
+	raw := `{"type":"user","message":{"role":"user","content":"hi"}}` + "\n" + `{"type":"summary"}` + "\n"
+	if err := os.WriteFile(src, []byte(raw), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chats := makeTestChats(2)
+	chats[0].Path = src
+	m := makeTestModel(chats, normalWidth, 20)
+
+	m = send(m, keyRune('E'))
+	if m.promptAction != promptExportJSONL || m.promptValue != chats[0].UUID+".jsonl" {
+		t.Fatalf("E should open a prefilled copy prompt, got action=%d value=%q", m.promptAction, m.promptValue)
+	}
+	out := filepath.Join(dir, "copy.jsonl")
+	m.promptValue = out
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if got, err := os.ReadFile(out); err != nil || string(got) != raw {
+		t.Errorf("copy = %q, %v; want the file unchanged", got, err)
+	}
+
+	m = send(m, keyRune('E'))
+	m.promptValue = out
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(m.error, "already exists") {
+		t.Errorf("an existing file must not be overwritten, error %q", m.error)
+	}
+}
+
+func TestUpdateL_ExpandsRelatedFilesInline(t *testing.T) {
+	setupStorageDirs(t)
+	chats := makeTestChats(30)
+	projDir := filepath.Join(projectsDir, "test-project")
+	os.MkdirAll(projDir, 0755)
+	os.WriteFile(filepath.Join(projDir, "uuid-10.jsonl"), []byte("{}\n"), 0644)
+	os.WriteFile(filepath.Join(debugDir, "uuid-10.txt"), []byte("debug log"), 0644)
+	m := makeTestModel(chats, normalWidth, 20)
+	want := viewLineCount(m.View())
+
+	// Last visible row: expanding must scroll so its files stay on screen
+	m.moveCursorTo(m.visibleHeight() - 1)
+	m = send(m, keyRune('l'))
+	out := stripANSI(m.View())
+	if !strings.Contains(out, "├─") || !strings.Contains(out, filepath.Join("projects", "test-project", "uuid-10.jsonl")) ||
+		!strings.Contains(out, "└─") || !strings.Contains(out, filepath.Join("debug", "uuid-10.txt")) {
+		t.Fatalf("l should list the chat's files under its row:\n%s", out)
+	}
+	if !strings.Contains(out, "Chat number 10") || viewLineCount(m.View()) != want {
+		t.Errorf("the expanded row must stay in view without growing the screen:\n%s", out)
+	}
+
+	m = send(m, keyRune('k'))
+	if strings.Contains(stripANSI(m.View()), "└─") {
+		t.Error("the files should only show while the cursor is on the chat")
+	}
+	m = send(m, keyRune('j'))
+	m = send(m, keyRune('l'))
+	if strings.Contains(stripANSI(m.View()), "└─") {
+		t.Error("l again should collapse the chat")
+	}
+}
+
+func TestUpdateZ_FocusModeDimsOtherRows(t *testing.T) {
+	// Colors are needed to tell a dimmed row from a plain one.
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	defer lipgloss.SetColorProfile(termenv.Ascii)
+
+	m := makeTestModel(makeTestChats(5), normalWidth, 20)
+	m.moveCursorTo(1)
+	m.selected[3] = true
+	rowOf := func(out, title string) string {
+		for _, line := range strings.Split(out, "\n") {
+			if strings.Contains(stripANSI(line), title+" ") {
+				return line
+			}
+		}
+		t.Fatalf("no row for %q:\n%s", title, stripANSI(out))
+		return ""
+	}
+	before := m.View()
+
+	m = send(m, keyRune('Z'))
+	if !m.focus {
+		t.Fatal("Z should turn focus mode on")
+	}
+	out := m.View()
+	if rowOf(out, "Chat number 1") != rowOf(before, "Chat number 1") {
+		t.Error("the cursor row should keep its full contrast")
+	}
+	for _, title := range []string{"Chat number 0", "Chat number 3"} {
+		if row := rowOf(out, title); row != dimStyle.Render(stripANSI(row)) {
+			t.Errorf("%s should be dimmed in focus mode: %q", title, row)
+		}
+	}
+
+	m = send(m, keyRune('Z'))
+	if out := m.View(); rowOf(out, "Chat number 3") != rowOf(before, "Chat number 3") {
+		t.Error("Z again should restore the usual row styles")
+	}
+}
+
+func TestUpdateExport_PromptWritesFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "chat.jsonl")
+	if err := os.WriteFile(src, []byte(`{"type":"user","message":{"role":"user","content":"hi"}}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chats := makeTestChats(2)
+	chats[0].Path = src
+	m := makeTestModel(chats, normalWidth, 20)
+
+	m = send(m, keyRune('e'))
+	if m.promptAction != promptExportMarkdown || !strings.HasSuffix(m.promptValue, ".md") {
+		t.Fatalf("e should open a prefilled export prompt, got action=%d value=%q", m.promptAction, m.promptValue)
+	}
+	// Keys go to the prompt, not the list
+	m = send(m, keyRune('q'))
+	if !strings.HasSuffix(m.promptValue, "q") {
+		t.Errorf("typed rune not appended: %q", m.promptValue)
+	}
+
+	out := filepath.Join(dir, "out.md")
+	m.promptValue = out
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.promptAction != promptNone {
+		t.Error("enter should close the prompt")
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("export file not written: %v", err)
+	}
+	if !strings.Contains(m.copiedMsg, out) {
+		t.Errorf("status should name the file, got %q", m.copiedMsg)
+	}
+}
+
+func TestLoadChats_HideTrivial(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	line := `{"type":"user","message":{"content":"hi"}}` + "\n"
+	for name, n := range map[string]int{"short": 1, "long": 6} {
+		if err := os.WriteFile(filepath.Join(dir, name+".jsonl"), []byte(strings.Repeat(line, n)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := loadedModel(&Config{HideBelowLines: 3}, nil)
+	m.width, m.height = normalWidth, 20
+	if len(m.chats) != 1 || m.chats[0].UUID != "long" || m.hiddenCount != 1 {
+		t.Fatalf("expected only the long chat with 1 hidden, got %d chats, %d hidden", len(m.chats), m.hiddenCount)
+	}
+	if out := stripANSI(m.View()); !strings.Contains(out, "Hidden: 1") {
+		t.Errorf("stats line should report hidden chats:\n%s", out)
+	}
+
+	m = send(m, keyRune('t'))
+	if len(m.chats) != 2 || m.hiddenCount != 0 {
+		t.Errorf("t should show all chats, got %d chats, %d hidden", len(m.chats), m.hiddenCount)
+	}
+}
+
+func TestUpdateA_SelectsCurrentProject(t *testing.T) {
+	m := makeTestModel(makeTestChatsMultiProject(2, 3), normalWidth, 20)
+	m.cursor = 4 // a chat in project-B
+
+	m = send(m, keyRune('A'))
+	if len(m.selected) != 3 {
+		t.Fatalf("A selected %d chats, want the 3 in project-B", len(m.selected))
+	}
+	for idx := range m.selected {
+		if m.chats[idx].Project != "project-B" {
+			t.Errorf("selected chat %d from %s", idx, m.chats[idx].Project)
+		}
+	}
+
+	m = send(m, keyRune('A'))
+	if len(m.selected) != 0 {
+		t.Errorf("second A should deselect the project, %d still selected", len(m.selected))
+	}
+}
+
+func TestUpdateX_ConfirmsWholeProject(t *testing.T) {
+	m := makeTestModel(makeTestChatsMultiProject(2, 3), normalWidth, 30)
+	m.selected[0] = true // an unrelated explicit selection is replaced
+	m.cursor = 4
+
+	m = send(m, keyRune('X'))
+	if !m.confirmDelete || m.deleteProject != "project-B" || len(m.selected) != 3 {
+		t.Fatalf("X should confirm deleting project-B's 3 chats, got confirm=%v project=%q selected=%d",
+			m.confirmDelete, m.deleteProject, len(m.selected))
+	}
+	if out := stripANSI(m.View()); !strings.Contains(out, "Permanently delete project project-B and all 3 chat(s)?") {
+		t.Errorf("confirm should name the project:\n%s", out)
+	}
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.deleteProject != "" || len(m.selected) != 0 {
+		t.Errorf("cancel should clear the project deletion, got project=%q selected=%d", m.deleteProject, len(m.selected))
+	}
+}
+
+func TestUpdateI_ShowsIndexEntry(t *testing.T) {
+	setupStorageDirs(t)
+	projDir := filepath.Join(projectsDir, "test-project")
+	if err := os.MkdirAll(projDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	index := `{"version":1,"entries":[{"sessionId":"uuid-0","gitBranch":"main","messageCount":7}]}`
+	if err := os.WriteFile(filepath.Join(projDir, "sessions-index.json"), []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := makeTestModel(makeTestChats(2), normalWidth, 30)
+
+	m = send(m, keyRune('i'))
+	if m.overlay != overlayIndexEntry {
+		t.Fatalf("overlay = %d after i, want overlayIndexEntry", m.overlay)
+	}
+	out := strings.Join(m.overlayLines, "\n")
+	if !strings.Contains(out, `"gitBranch": "main"`) || !strings.Contains(out, `"messageCount": 7`) {
+		t.Errorf("entry not pretty-printed:\n%s", out)
+	}
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyEsc})
+	m.cursor = 1
+	m = send(m, keyRune('i'))
+	if !strings.Contains(strings.Join(m.overlayLines, "\n"), "No entry for uuid-1") {
+		t.Errorf("missing entry not reported:\n%v", m.overlayLines)
+	}
+}
+
+func TestWindowStart(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.Local)
+	if got := windowStart(0, now); !got.IsZero() {
+		t.Errorf("no window should have zero start, got %v", got)
+	}
+	if got, want := windowStart(1, now), time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("today starts at %v, want %v", got, want)
+	}
+	if got, want := windowStart(2, now), time.Date(2026, 3, 4, 0, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("7 days start at %v, want %v", got, want)
+	}
+}
+
+func TestUpdateW_CyclesTimeWindow(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, age := range map[string]time.Duration{"recent": time.Minute, "old": 60 * 24 * time.Hour} {
+		ts := time.Now().Add(-age).UTC().Format(time.RFC3339)
+		line := `{"type":"user","timestamp":"` + ts + `","message":{"content":"hi"}}` + "\n"
+		if err := os.WriteFile(filepath.Join(dir, name+".jsonl"), []byte(line), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 20
+
+	m = send(m, keyRune('w')) // Today
+	if len(m.chats) != 1 || m.chats[0].UUID != "recent" || m.outsideWindow != 1 {
+		t.Fatalf("today window: got %d chats, %d outside", len(m.chats), m.outsideWindow)
+	}
+	if out := stripANSI(m.View()); !strings.Contains(out, "Today") {
+		t.Errorf("stats line should name the window:\n%s", out)
+	}
+
+	for i := 0; i < len(timeWindows)-1; i++ {
+		m = send(m, keyRune('w'))
+	}
+	if m.window != 0 || len(m.chats) != 2 {
+		t.Errorf("cycling back should show all chats, window=%d chats=%d", m.window, len(m.chats))
+	}
+}
+
+func TestUpdateD_DeletesOnlyChatsInView(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, age := range map[string]time.Duration{"recent": time.Minute, "old": 60 * 24 * time.Hour} {
+		ts := time.Now().Add(-age).UTC().Format(time.RFC3339)
+		line := `{"type":"user","timestamp":"` + ts + `","message":{"content":"hi"}}` + "\n"
+		if err := os.WriteFile(filepath.Join(dir, name+".jsonl"), []byte(line), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 20
+	m = send(m, keyRune('w')) // Today
+
+	m = send(m, keyRune('D'))
+	if !m.confirmDelete || len(m.selected) != 1 {
+		t.Fatalf("D should confirm the one chat in view, confirm=%v selected=%d", m.confirmDelete, len(m.selected))
+	}
+	if out := stripANSI(m.View()); !strings.Contains(out, "all 1 chat(s) in view ([Today])") {
+		t.Errorf("confirmation should give the count and the filter:\n%s", out)
+	}
+
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(model)
+	next, _ = m.Update(cmd())
+	m = next.(model)
+	if _, err := os.Stat(filepath.Join(dir, "recent.jsonl")); !os.IsNotExist(err) {
+		t.Errorf("chat in view should be deleted, stat err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.jsonl")); err != nil {
+		t.Errorf("chat outside the window must survive: %v", err)
+	}
+	if m.deleteInView {
+		t.Error("deleteInView should reset after the deletion")
+	}
+}
+
+func TestUpdateD_BigBatchShowsSharedFiles(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	os.MkdirAll(dir, 0755)
+	for i := 0; i < preflightMin; i++ {
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("chat-%02d.jsonl", i)), []byte(`{"type":"user","slug":"plan","message":{"content":"hi"}}`+"\n"), 0644)
+	}
+	os.WriteFile(filepath.Join(dir, "keeper.jsonl"), []byte(`{"type":"user","slug":"plan","message":{"content":"keep"}}`+"\n"), 0644)
+	os.WriteFile(filepath.Join(plansDir, "plan.md"), []byte("# plan"), 0644)
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 40
+
+	m = send(m, keyRune('a'))
+	for i := range m.chats {
+		if m.chats[i].UUID == "keeper" {
+			delete(m.selected, i)
+		}
+	}
+	next, cmd := m.Update(keyRune('d'))
+	m = next.(model)
+	if cmd == nil || !strings.Contains(stripANSI(m.View()), "Shared files: analyzing...") {
+		t.Fatalf("a big batch should be analyzed while confirming:\n%s", stripANSI(m.View()))
+	}
+	msgs := []tea.Msg{cmd()}
+	if batch, ok := msgs[0].(tea.BatchMsg); ok { // with the window title update
+		msgs = nil
+		for _, c := range batch {
+			if c != nil {
+				msgs = append(msgs, c())
+			}
+		}
+	}
+	for _, msg := range msgs {
+		next, _ = m.Update(msg)
+		m = next.(model)
+	}
+	if out := stripANSI(m.View()); !strings.Contains(out, fmt.Sprintf("Shared files: %d file(s) to remove, 1 shared kept for other chats", preflightMin)) {
+		t.Errorf("the analysis should count the plan the kept chat still uses:\n%s", out)
+	}
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.preflight != nil {
+		t.Error("cancelling should drop the analysis")
+	}
+}
+
+func TestUpdate_ReadOnlyBlocksDeletion(t *testing.T) {
+	m := makeTestModel(makeTestChats(3), normalWidth, 20)
+	m.readOnly = true
+
+	for _, key := range []rune{'d', 'X', 'D'} {
+		m = send(m, keyRune(key))
+		if m.confirmDelete || m.error != "read-only mode: deletion is disabled" {
+			t.Errorf("%c in read-only mode: confirm=%v error=%q", key, m.confirmDelete, m.error)
+		}
+	}
+
+	out := stripANSI(m.View())
+	if strings.Contains(out, "d:Delete") || !strings.Contains(out, "READ-ONLY") {
+		t.Errorf("read-only view should hide delete help and show the mode:\n%s", out)
+	}
+	help := strings.Join(helpLines(true), "\n")
+	if strings.Contains(help, "Delete selection") || strings.Contains(help, "Confirmation dialog") {
+		t.Errorf("read-only help overlay lists deletion keys:\n%s", help)
+	}
+}
+
+func TestUpdate_UpdateInProgressBlocksDeletion(t *testing.T) {
+	m := makeTestModel(makeTestChats(3), normalWidth, 20)
+	m = send(m, keyRune('d'))
+	if !m.confirmDelete {
+		t.Fatal("d should ask to confirm")
+	}
+
+	// The update starts while the dialog is open
+	next, _ := m.Update(updateProgressMsg{installing: true})
+	m = next.(model)
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(model)
+	if m.deleting || cmd != nil || m.error != updateInProgress {
+		t.Errorf("confirming during an update: deleting=%v error=%q", m.deleting, m.error)
+	}
+	if !strings.Contains(stripANSI(m.View()), "UPDATING") {
+		t.Error("the stats line should show the update")
+	}
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyEsc})
+	for _, key := range []rune{'d', 'X', 'D'} {
+		m = send(m, keyRune(key))
+		if m.confirmDelete || m.error != updateInProgress {
+			t.Errorf("%c during an update: confirm=%v error=%q", key, m.confirmDelete, m.error)
+		}
+	}
+
+	next, _ = m.Update(updateProgressMsg{installing: false})
+	m = send(next.(model), keyRune('d'))
+	if !m.confirmDelete {
+		t.Error("deleting should work again once the update is done")
+	}
+}
+
+// Moving the cursor re-renders only the two rows whose cursor state changed;
+// the output must match an uncached render.
+func TestView_RowCacheReusesUnchangedRows(t *testing.T) {
+	m := makeTestModel(makeTestChats(5), normalWidth, 20)
+	m.rowCache = make(rowCache)
+
+	m.View()
+	if len(m.rowCache) != 5 {
+		t.Fatalf("first frame cached %d rows, want 5", len(m.rowCache))
+	}
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyDown})
+	cached := m.View()
+	if len(m.rowCache) != 7 {
+		t.Errorf("cursor move added %d rows, want 2", len(m.rowCache)-5)
+	}
+
+	m.rowCache = nil
+	if uncached := m.View(); uncached != cached {
+		t.Errorf("cached view differs from a fresh render:\n%s\n---\n%s", cached, uncached)
+	}
+}
+
+// The confirm dialog must name the real consequence of the configured mode.
+func TestView_ConfirmNamesTrashOrPermanent(t *testing.T) {
+	m := makeTestModel(makeTestChats(3), normalWidth, 20)
+	m.cfg = &Config{}
+	m = send(m, keyRune('d'))
+	if out := stripANSI(m.View()); !strings.Contains(out, "Permanently delete 1 chat(s)? (cannot be undone)") {
+		t.Errorf("permanent mode confirm:\n%s", out)
+	}
+
+	m.cfg.UseTrash = true
+	out := stripANSI(m.View())
+	if !strings.Contains(out, "Move 1 chat(s) to trash? (recoverable)") || strings.Contains(out, "Permanently") {
+		t.Errorf("trash mode confirm:\n%s", out)
+	}
+}
+
+// t and P pick trash or permanent deletion for one batch, whatever use_trash
+// says; the next dialog is back to the default.
+func TestUpdateConfirm_TrashOrPermanentKeys(t *testing.T) {
+	m := makeTestModel(makeTestChats(3), normalWidth, 20)
+	m.cfg = &Config{}
+	m = send(m, keyRune('d'))
+	next, cmd := m.Update(keyRune('t'))
+	m = next.(model)
+	if !m.deleting || cmd == nil {
+		t.Fatal("t in the confirm dialog should start the deletion")
+	}
+	if !m.trashing() {
+		t.Error("t should move to trash despite use_trash=false")
+	}
+	m.deleting = false
+	m.cancelDelete()
+
+	m.cfg.UseTrash = true
+	m = send(m, keyRune('d'))
+	next, _ = m.Update(keyRune('P'))
+	m = next.(model)
+	if !m.deleting || m.trashing() {
+		t.Error("P should delete permanently despite use_trash=true")
+	}
+	m.deleting = false
+	m.cancelDelete()
+	if !m.trashing() {
+		t.Error("the choice should only last for one deletion")
+	}
+}
+
+// p toggles full project paths and keeps the choice for the next run.
+func TestUpdateP_PersistsFullPaths(t *testing.T) {
+	origConfig := configPath
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	t.Cleanup(func() { configPath = origConfig })
+
+	chats := makeTestChats(2)
+	chats[0].ProjectPath = "/home/me/src/alpha/test-project"
+	m := makeTestModel(chats, normalWidth, 20)
+	m.cfg = &Config{ClaudeDir: "/tmp/claude"}
+	if err := saveConfig(m.cfg); err != nil {
+		t.Fatal(err)
+	}
+	m = send(m, keyRune('p'))
+	if !m.fullPaths || !strings.Contains(stripANSI(m.View()), "alpha/test-project") {
+		t.Fatalf("p should show the full project path:\n%s", stripANSI(m.View()))
+	}
+	saved, _, err := loadConfig()
+	if err != nil || !saved.FullPaths {
+		t.Fatalf("full_paths not saved: %+v, %v", saved, err)
+	}
+	if !initialModel(saved, nil).fullPaths {
+		t.Error("the next run should start with full paths")
+	}
+
+	m = send(m, keyRune('p'))
+	if saved, _, _ := loadConfig(); m.fullPaths || saved.FullPaths {
+		t.Error("p again should go back to directory names, also for the next run")
+	}
+}
+
+// Saved selections are matched by UUID, so they survive a different order.
+func TestUpdateSaveLoadSelection(t *testing.T) {
+	origConfig := configPath
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	t.Cleanup(func() { configPath = origConfig })
+
+	m := makeTestModel(makeTestChats(4), normalWidth, 20)
+	m.selected[1] = true
+	m.selected[3] = true
+	m = send(m, keyRune('S'))
+	if m.promptAction != promptSaveSelection {
+		t.Fatalf("S should open the save prompt, got action %d", m.promptAction)
+	}
+	m.promptValue = "stale"
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.error != "" {
+		t.Fatalf("save failed: %s", m.error)
+	}
+
+	// Next run: same chats in reverse order
+	chats := makeTestChats(4)
+	for i, j := 0, len(chats)-1; i < j; i, j = i+1, j-1 {
+		chats[i], chats[j] = chats[j], chats[i]
+	}
+	m = makeTestModel(chats, normalWidth, 20)
+	m = send(m, keyRune('L'))
+	if m.promptAction != promptLoadSelection || !strings.Contains(m.promptLabel, "stale") {
+		t.Fatalf("L should offer the saved set, got action %d label %q", m.promptAction, m.promptLabel)
+	}
+	m.promptValue = "stale"
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.selected) != 2 || !m.selected[0] || !m.selected[2] {
+		t.Errorf("loaded selection = %v, want indices 0 and 2 (uuid-3, uuid-1)", m.selected)
+	}
+}
+
+func TestUpdateL_UnreadableSelections(t *testing.T) {
+	origConfig := configPath
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	t.Cleanup(func() { configPath = origConfig })
+	if err := os.WriteFile(selectionsPath(), []byte("{broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := makeTestModel(makeTestChats(2), normalWidth, 20)
+	m = send(m, keyRune('L'))
+	if m.promptAction == promptLoadSelection {
+		t.Fatal("L should not open the load prompt when selections.json cannot be read")
+	}
+	if !strings.Contains(m.error, "selections.json") {
+		t.Errorf("error = %q, want it to name selections.json", m.error)
+	}
+}
+
+func TestUpdateV_PreviewsPlan(t *testing.T) {
+	dir := t.TempDir()
+	plan := filepath.Join(dir, "brave-fox.md")
+	if err := os.WriteFile(plan, []byte("# Plan\n\n1. Do the thing\n- run **all** tests\n```\n# not a heading\n```\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chats := makeTestChats(2)
+	chats[0].PlanPath = plan
+	m := makeTestModel(chats, normalWidth, 30)
+
+	if !strings.Contains(stripANSI(m.View()), "PLAN") {
+		t.Error("PLAN column header missing")
+	}
+
+	m = send(m, keyRune('v'))
+	if m.overlay != overlayPlan {
+		t.Fatalf("overlay = %d after v, want overlayPlan", m.overlay)
+	}
+	if !strings.Contains(strings.Join(m.overlayLines, "\n"), "1. Do the thing") {
+		t.Errorf("plan not shown:\n%v", m.overlayLines)
+	}
+	out := stripANSI(m.View())
+	if !strings.Contains(out, "• run all tests") || strings.Contains(out, "# Plan") || !strings.Contains(out, "# not a heading") {
+		t.Errorf("plan should get light Markdown styling, code blocks left alone:\n%s", out)
+	}
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyEsc})
+	m.cursor = 1
+	m = send(m, keyRune('v'))
+	if m.overlay == overlayPlan || m.error == "" {
+		t.Errorf("chat without plan: overlay = %d, error = %q", m.overlay, m.error)
+	}
+}
+
+func TestUpdateC_ComparesTwoChats(t *testing.T) {
+	dir := t.TempDir()
+	chats := makeTestChats(3)
+	for i, reply := range []string{"use a mutex", "use a channel"} {
+		chats[i].Path = filepath.Join(dir, chats[i].UUID+".jsonl")
+		data := `{"type":"user","message":{"content":"how to sync?"}}` + "\n" +
+			`{"type":"assistant","message":{"content":[{"type":"text","text":"` + reply + `"}]}}` + "\n"
+		if err := os.WriteFile(chats[i].Path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := makeTestModel(chats, normalWidth, 30)
+
+	m = send(m, keyRune('C'))
+	if m.overlay == overlayCompare || m.error == "" {
+		t.Fatalf("C without two selected chats: overlay = %d, error = %q", m.overlay, m.error)
+	}
+
+	m.selected[0], m.selected[1] = true, true
+	m = send(m, keyRune('C'))
+	if m.overlay != overlayCompare {
+		t.Fatalf("overlay = %d after C, want overlayCompare", m.overlay)
+	}
+	found := false
+	for _, line := range m.overlayLines {
+		if strings.Contains(line, "use a mutex") && strings.Contains(line, "│ use a channel") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("replies should be side by side:\n%s", strings.Join(m.overlayLines, "\n"))
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	got := wrapText("one two three\nfour abcdefghij", 7)
+	want := []string{"one two", "three", "four", "abcdefg", "hij"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("wrapText() = %q, want %q", got, want)
+	}
+}
+
+func TestCutANSI(t *testing.T) {
+	tests := []struct {
+		s          string
+		from, cols int
+		want       string
+	}{
+		{"abcdef", 2, 3, "cde"},
+		{"\x1b[1mabc\x1b[0mdef", 1, 3, "\x1b[1mbc\x1b[0md"},
+		{"a修b", 2, 2, " b"},
+		{"ab", 5, 3, ""},
+	}
+	for _, tt := range tests {
+		if got := cutANSI(tt.s, tt.from, tt.cols); got != tt.want {
+			t.Errorf("cutANSI(%q, %d, %d) = %q, want %q", tt.s, tt.from, tt.cols, got, tt.want)
+		}
+	}
+}
+
+// On terminals narrower than the layout, > and < scroll the list sideways
+// instead of letting the terminal cut it off.
+func TestUpdateAngle_ScrollsNarrowTableSideways(t *testing.T) {
+	m := makeTestModel(makeTestChats(3), 50, 20)
+	headerOf := func(m model) string {
+		for _, line := range strings.Split(stripANSI(m.View()), "\n") {
+			if strings.Contains(line, "LINES") || strings.Contains(line, "PROJECT") {
+				return line
+			}
+		}
+		return ""
+	}
+	rowWidth := func(m model) int {
+		for _, line := range strings.Split(stripANSI(m.View()), "\n") {
+			if strings.Contains(line, "[ ]") {
+				return runewidth.StringWidth(line)
+			}
+		}
+		return -1
+	}
+	if !strings.Contains(headerOf(m), "TIMESTAMP") || rowWidth(m) > 50 {
+		t.Fatalf("the list should start at the left edge and fit the terminal: %q", headerOf(m))
+	}
+	m = send(m, keyRune('>'))
+	if m.hOffset != hScrollStep || strings.Contains(headerOf(m), "TIMESTAMP") || rowWidth(m) > 50 {
+		t.Errorf("> should scroll right by %d columns, offset %d: %q", hScrollStep, m.hOffset, headerOf(m))
+	}
+	for i := 0; i < 20; i++ {
+		m = send(m, keyRune('>'))
+	}
+	if m.hOffset != m.hOverflow() || !strings.Contains(headerOf(m), "PROJECT") {
+		t.Errorf("scrolling should stop at the right edge, offset %d of %d: %q", m.hOffset, m.hOverflow(), headerOf(m))
+	}
+	for i := 0; i < 20; i++ {
+		m = send(m, keyRune('<'))
+	}
+	if m.hOffset != 0 || !strings.Contains(headerOf(m), "TIMESTAMP") {
+		t.Errorf("< should scroll back to the left edge, offset %d", m.hOffset)
+	}
+
+	wide := send(makeTestModel(makeTestChats(3), normalWidth, 20), keyRune('>'))
+	if wide.hOffset != 0 {
+		t.Error("a list that fits should not scroll")
+	}
+}
+
+func TestView_WideCharTitlesKeepColumnsAligned(t *testing.T) {
+	chats := makeTestChats(3)
+	chats[1].Title = "修复数据库连接池的超时问题并添加重试逻辑以及更多的中文标题内容"
+	chats[2].Title = "Fix 🚀 deploy 🎉 pipeline"
+	for _, width := range []int{normalWidth, compactWidth} {
+		m := makeTestModel(chats, width, 30)
+		var cols []int
+		for _, line := range strings.Split(stripANSI(m.View()), "\n") {
+			if !strings.Contains(line, "test-project") {
+				continue
+			}
+			idx := strings.Index(line, "test-project")
+			cols = append(cols, runewidth.StringWidth(line[:idx]))
+		}
+		if len(cols) != 3 {
+			t.Fatalf("width %d: found %d chat rows, want 3", width, len(cols))
+		}
+		if cols[1] != cols[0] || cols[2] != cols[0] {
+			t.Errorf("width %d: PROJECT column starts at %v, want all equal", width, cols)
+		}
+	}
+}
+
+func TestUpdateO_ReviewsSelection(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if err := os.WriteFile(filepath.Join(dir, name+".jsonl"), []byte(`{"type":"user"}`+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 20
+
+	m = send(m, keyRune('o'))
+	if m.reviewing != nil || m.error == "" {
+		t.Fatal("o with nothing selected should refuse")
+	}
+	m.error = ""
+
+	m.selectUUIDs([]string{"b", "d"})
+	m = send(m, keyRune('o'))
+	if len(m.chats) != 2 || len(m.selected) != 2 {
+		t.Fatalf("review mode: %d chats, %d selected, want 2 and 2", len(m.chats), len(m.selected))
+	}
+	if !strings.Contains(stripANSI(m.View()), "Reviewing selection") {
+		t.Error("stats line should show review mode")
+	}
+
+	// Deselect a mistake; it stays visible until review mode is left
+	m.cursor = 0
+	m = send(m, keyRune(' '))
+	if len(m.chats) != 2 || len(m.selected) != 1 {
+		t.Fatalf("after deselect: %d chats, %d selected", len(m.chats), len(m.selected))
+	}
+
+	m = send(m, keyRune('o'))
+	if len(m.chats) != 4 || len(m.selected) != 1 {
+		t.Errorf("after o off: %d chats, %d selected, want 4 and 1", len(m.chats), len(m.selected))
+	}
+}
+
+func TestTrashTab_RestoresAndDeletesBatches(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	os.MkdirAll(dir, 0755)
+	for _, uuid := range []string{"older", "newer"} {
+		os.WriteFile(filepath.Join(dir, uuid+".jsonl"), []byte(`{"type":"user","message":{"content":"`+uuid+` chat"}}`+"\n"), 0644)
+		chat := scanChatFile(chatFile{path: filepath.Join(dir, uuid+".jsonl"), project: "proj", messages: -1})
+		if _, _, err := moveChatsToTrash([]Chat{chat}, "test", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 20
+	// run sends msg and the messages its commands produce, as the program would
+	var run func(msg tea.Msg)
+	run = func(msg tea.Msg) {
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for _, c := range batch {
+				if c != nil {
+					run(c())
+				}
+			}
+			return
+		}
+		next, cmd := m.Update(msg)
+		m = next.(model)
+		if cmd != nil {
+			run(cmd())
+		}
+	}
+
+	next, cmd := m.Update(keyRune('u'))
+	m = next.(model)
+	if !m.trashLoading || !strings.Contains(stripANSI(m.View()), "Reading the trash") {
+		t.Error("the trash should be read in the background")
+	}
+	run(cmd())
+	if m.tab != tabTrash || len(m.trashBatches) != 2 {
+		t.Fatalf("u should open the Trash tab with both batches: tab=%d batches=%d", m.tab, len(m.trashBatches))
+	}
+	out := stripANSI(m.View())
+	if !strings.Contains(out, "• newer chat (proj)") || strings.Index(out, "newer chat") > strings.Index(out, "older chat") {
+		t.Errorf("batches should list their chats, newest first:\n%s", out)
+	}
+
+	next, cmd = m.Update(keyRune('r'))
+	m = next.(model)
+	if !m.restoring || len(m.chats) != 0 {
+		t.Fatalf("r should restore in the background: restoring=%v chats=%d", m.restoring, len(m.chats))
+	}
+	run(cmd())
+	if len(m.chats) != 1 || m.chats[0].UUID != "newer" || len(m.trashBatches) != 1 {
+		t.Fatalf("r should restore the batch under the cursor: chats=%+v batches=%d", m.chats, len(m.trashBatches))
+	}
+
+	m = send(m, keyRune('d'))
+	if out := stripANSI(m.View()); !strings.Contains(out, "Delete 1 chat(s) in 1 batch(es) for good") {
+		t.Fatalf("d should ask before deleting for good:\n%s", out)
+	}
+	run(tea.KeyMsg{Type: tea.KeyEnter})
+	if batches, _ := listTrashBatches(); len(batches) != 0 || len(m.trashBatches) != 0 || !strings.HasPrefix(m.trashStatus, "✓ Removed 1 batch(es)") {
+		t.Errorf("enter should delete the batch: on disk %d, listed %d, status %q", len(batches), len(m.trashBatches), m.trashStatus)
+	}
+
+	m = send(m, keyRune('u'))
+	if m.tab != tabChats {
+		t.Errorf("u again should return to the chats, tab=%d", m.tab)
+	}
+}
+
+func TestUpdateX_StagesAcrossRuns(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	os.MkdirAll(dir, 0755)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		os.WriteFile(filepath.Join(dir, name+".jsonl"), []byte(`{"type":"user"}`+"\n"), 0644)
+	}
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 20
+	stage := func(uuid string) {
+		for i, chat := range m.chats {
+			if chat.UUID == uuid {
+				m.cursor = i
+			}
+		}
+		m = send(m, keyRune('x'))
+	}
+
+	m = send(m, keyRune('V'))
+	if m.reviewing != nil || m.error == "" {
+		t.Fatal("V with nothing staged should refuse")
+	}
+	m.error = ""
+	stage("b")
+	stage("d")
+	if len(m.selected) != 0 {
+		t.Error("staging must not touch the selection")
+	}
+	out := stripANSI(m.View())
+	if strings.Count(out, "[x]") != 2 || !strings.Contains(out, "Staged: 2") {
+		t.Errorf("staged chats should be marked and counted:\n%s", out)
+	}
+
+	// A new run picks the list up again
+	m = loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 20
+	m = send(m, keyRune('V'))
+	if len(m.chats) != 2 || len(m.selected) != 2 || m.reviewing == nil {
+		t.Fatalf("V: %d chats, %d selected, want the 2 staged ones in review", len(m.chats), len(m.selected))
+	}
+	stage("d") // unstage; it stays selected for this review
+	next, _ := m.Update(deleteCompleteMsg{count: 1, chats: []Chat{{UUID: "b", Project: "proj"}}})
+	m = next.(model)
+	if staged, _ := loadStaged(); len(staged) != 0 || len(m.staged) != 0 {
+		t.Errorf("deleted and unstaged chats should leave the list, %v left", staged)
+	}
+
+	// Chats gone from disk don't count as staged
+	setStaged("gone", true)
+	setStaged("c", true)
+	m = loadedModel(&Config{}, nil)
+	if len(m.staged) != 1 || !strings.Contains(m.renderTabBar(), "Staged: 1") {
+		t.Errorf("staged after the scan = %v, want only c", m.staged)
+	}
+
+	// An unreadable list is reported instead of silently starting empty
+	os.WriteFile(stagedPath(), []byte("{broken"), 0644)
+	if m = loadedModel(&Config{}, nil); !strings.Contains(m.error, "staged.json") {
+		t.Errorf("error = %q, want the staged.json read error", m.error)
+	}
+}
+
+func TestUpdateM_FiltersByMessageCount(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	os.MkdirAll(dir, 0755)
+	const user, reply = `{"type":"user","message":{"content":"q"}}`, `{"type":"assistant","message":{"content":"a"}}`
+	os.WriteFile(filepath.Join(dir, "short.jsonl"), []byte(user+"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "medium.jsonl"), []byte(user+"\n"+reply+"\n"+user+"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "indexed.jsonl"), []byte(user+"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "sessions-index.json"), []byte(`{"version":1,"entries":[{"sessionId":"indexed","messageCount":120}]}`), 0644)
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 20
+
+	filter := func(expr string) []string {
+		m = send(m, keyRune('M'))
+		m.promptValue = expr
+		m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+		var uuids []string
+		for _, chat := range m.chats {
+			uuids = append(uuids, chat.UUID)
+		}
+		sort.Strings(uuids)
+		return uuids
+	}
+	if got := filter("<= 2"); !slices.Equal(got, []string{"short"}) {
+		t.Errorf("<=2 kept %v, want the one-message chat (the index's count wins)", got)
+	}
+	if !strings.Contains(stripANSI(m.View()), "[Messages <=2]") {
+		t.Error("stats line should show the message filter")
+	}
+	if got := filter(">=3"); !slices.Equal(got, []string{"indexed", "medium"}) {
+		t.Errorf(">=3 kept %v", got)
+	}
+	if filter("about 5"); m.error == "" || m.msgFilter != ">=3" {
+		t.Errorf("an invalid filter should be refused and keep the old one, error %q", m.error)
+	}
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if len(m.chats) != 3 || m.msgFilter != "" {
+		t.Errorf("Esc should clear the filter, %d chats shown", len(m.chats))
+	}
+}
+
+func TestUpdate_QuitDuringDeleteWaitsForCurrentChat(t *testing.T) {
+	m := makeTestModel(makeTestChats(3), normalWidth, 20)
+	m.selected[0] = true
+	m.selected[1] = true
+	m.confirmDelete = true
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(model)
+	if !m.deleting || cmd == nil {
+		t.Fatal("enter should start the deletion")
+	}
+
+	next, cmd = m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	m = next.(model)
+	if cmd != nil || !m.stopDelete.Load() || !m.quitPending {
+		t.Fatal("ctrl+c during deletion should request a stop, not quit")
+	}
+
+	next, cmd = m.update(deleteCompleteMsg{count: 1, total: 2, chats: m.chats[:1]})
+	m = next.(model)
+	if cmd == nil {
+		t.Fatal("program should quit once the deletion reports back")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Errorf("expected tea.Quit, got %T", cmd())
+	}
+	if !strings.Contains(m.exitReport, "deleted 1 of 2") {
+		t.Errorf("exitReport = %q", m.exitReport)
+	}
+}
+
+func TestUpdateN_NoteProtectsChat(t *testing.T) {
+	setupStorageDirs(t)
+	origConfig := configPath
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	t.Cleanup(func() { configPath = origConfig })
+
+	dir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "abc.jsonl"), []byte(`{"type":"user"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 20
+
+	m = send(m, keyRune('N'))
+	if m.promptAction != promptNote {
+		t.Fatalf("N should open the note prompt, got action %d", m.promptAction)
+	}
+	m.promptValue = "keep - important refactor"
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.chats[0].Note != "keep - important refactor" || !m.chats[0].Protected {
+		t.Fatalf("chat after note: %+v", m.chats[0])
+	}
+	if !strings.Contains(stripANSI(m.View()), "✎ keep") {
+		t.Error("note not shown in the list")
+	}
+
+	// Persists across runs; clearing it lifts the protection
+	m = loadedModel(&Config{}, nil)
+	if m.chats[0].Note == "" {
+		t.Fatal("note not persisted")
+	}
+	m = send(m, keyRune('N'))
+	m.promptValue = ""
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.chats[0].Note != "" || m.chats[0].Protected {
+		t.Errorf("chat after clearing note: %+v", m.chats[0])
+	}
+
+	// protect_noted: false keeps noted chats deletable
+	setNote("abc", "just a note")
+	off := false
+	m = loadedModel(&Config{ProtectNoted: &off}, nil)
+	if m.chats[0].Protected {
+		t.Error("protect_noted false should not protect noted chats")
+	}
+
+	// An unreadable notes file protects every chat rather than none
+	if err := os.WriteFile(notesPath(), []byte("{broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m = loadedModel(&Config{}, nil)
+	if !m.chats[0].Protected || !strings.Contains(m.error, "notes.json") {
+		t.Errorf("broken notes: protected=%v error=%q", m.chats[0].Protected, m.error)
+	}
+
+	// The notes are read once per scan, not for every batch it delivers
+	os.Remove(notesPath())
+	m = initialModel(&Config{}, nil)
+	setNote("abc", "written mid-scan")
+	for cmd := startScan(); cmd != nil; {
+		next, c := m.update(cmd())
+		m, cmd = next.(model), c
+	}
+	if m.chats[0].Note != "" {
+		t.Errorf("scan batches re-read notes.json: note %q", m.chats[0].Note)
+	}
+	m = send(m, keyRune('r'))
+	if m.chats[0].Note != "written mid-scan" {
+		t.Errorf("r should re-read notes.json, note = %q", m.chats[0].Note)
+	}
+}
+
+func TestUpdateT_LabelsChat(t *testing.T) {
+	setupStorageDirs(t)
+	origConfig := configPath
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	t.Cleanup(func() { configPath = origConfig })
+
+	dir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	line := `{"type":"user","message":{"content":"fix it"}}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "abc.jsonl"), []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 20
+
+	m = send(m, keyRune('T'))
+	if m.promptAction != promptChatLabel || m.promptValue != "" {
+		t.Fatalf("T should open an empty label prompt, got action %d value %q", m.promptAction, m.promptValue)
+	}
+	m.promptValue = "Auth refactor"
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.chats[0].Title != "Auth refactor" || m.chats[0].DerivedTitle != "fix it" {
+		t.Fatalf("chat after label: %+v", m.chats[0])
+	}
+	if !strings.Contains(stripANSI(m.View()), "✱ Auth refactor") {
+		t.Error("label not marked in the list")
+	}
+
+	// The scan applies labels, so non-interactive modes see them too.
+	chats, _ := findAllChats()
+	if chats[0].Title != "Auth refactor" {
+		t.Errorf("findAllChats title = %q, want the label", chats[0].Title)
+	}
+
+	m = send(m, keyRune('T'))
+	if m.promptValue != "Auth refactor" {
+		t.Errorf("prompt should be prefilled with the label, got %q", m.promptValue)
+	}
+	m.promptValue = ""
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.chats[0].Title != "fix it" || m.chats[0].DerivedTitle != "" {
+		t.Errorf("clearing the label should restore the title: %+v", m.chats[0])
+	}
+}
+
+func TestUpdateM_FiltersByArtifact(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	for _, uuid := range []string{"plain", "agents", "history"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, uuid+".jsonl"), []byte(`{"type":"user"}`+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.MkdirAll(filepath.Join(dir, "agents", "subagents"), 0755)
+	os.MkdirAll(filepath.Join(fileHistoryDir, "history"), 0755)
+
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 20
+	m = send(m, keyRune('m'))
+	if out := stripANSI(m.View()); !strings.Contains(out, "2 subagents") {
+		t.Fatalf("m should show the filter menu:\n%s", out)
+	}
+	m = send(m, keyRune('2'))
+	if len(m.chats) != 1 || m.chats[0].UUID != "agents" || m.lacking != 2 {
+		t.Fatalf("subagents filter: got %d chats, %d lacking", len(m.chats), m.lacking)
+	}
+	if out := stripANSI(m.View()); !strings.Contains(out, "[Has: subagents]") {
+		t.Errorf("stats line should name the filter:\n%s", out)
+	}
+
+	m = send(m, keyRune('m'))
+	m = send(m, keyRune('4'))
+	if len(m.chats) != 1 || m.chats[0].UUID != "history" {
+		t.Errorf("file history filter: got %+v", m.chats)
+	}
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.artifact != 0 || len(m.chats) != 3 {
+		t.Errorf("esc should clear the filter, artifact=%d chats=%d", m.artifact, len(m.chats))
+	}
+}
+
+func TestCompactedChats_MarkedAndFiltered(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "fresh.jsonl"), []byte(`{"type":"user","message":{"content":"fresh start"}}`+"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "compacted.jsonl"), []byte(`{"type":"user","message":{"content":"long haul"}}`+"\n"+
+		`{"type":"summary","summary":"so far"}`+"\n"), 0644)
+
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 20
+	out := stripANSI(m.View())
+	if !strings.Contains(out, "∑ long haul") || strings.Contains(out, "∑ fresh start") {
+		t.Errorf("only the compacted chat should be marked:\n%s", out)
+	}
+
+	m = send(m, keyRune('m'))
+	m = send(m, keyRune('7'))
+	if len(m.chats) != 1 || m.chats[0].UUID != "compacted" {
+		t.Errorf("summary filter: got %+v", m.chats)
+	}
+}
+
+func TestUpdateEsc_ResetsActiveFilters(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	line := `{"type":"user"}` + "\n"
+	for name, n := range map[string]int{"short": 1, "long": 6} {
+		if err := os.WriteFile(filepath.Join(dir, name+".jsonl"), []byte(strings.Repeat(line, n)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := loadedModel(&Config{HideBelowLines: 3}, nil)
+	m.width, m.height = normalWidth, 20
+	m.selectUUIDs([]string{"long"})
+
+	out := stripANSI(m.View())
+	if !strings.Contains(out, "[Hidden: 1]") || !strings.Contains(out, "Esc: reset") {
+		t.Fatalf("stats line should flag the active filter:\n%s", out)
+	}
+
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = next.(model)
+	if m.hideTrivial || len(m.chats) != 2 || len(m.selected) != 1 {
+		t.Fatalf("esc should clear filters and keep the selection: hide=%v chats=%d selected=%d",
+			m.hideTrivial, len(m.chats), len(m.selected))
+	}
+	if cmd != nil {
+		if _, ok := cmd().(tea.QuitMsg); ok {
+			t.Fatal("esc with filters on must not quit")
+		}
+	}
+	if strings.Contains(stripANSI(m.View()), "Esc: reset") {
+		t.Error("indicator should disappear once filters are cleared")
+	}
+}
+
+func TestUpdate_CountJumpsAndMoves(t *testing.T) {
+	m := makeTestModel(makeTestChats(50), normalWidth, 20)
+
+	m = send(m, keyRune('2'))
+	m = send(m, keyRune('5'))
+	if !strings.Contains(stripANSI(m.View()), "Go to: 25") {
+		t.Error("typed count should be shown")
+	}
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.cursor != 24 || m.count != "" {
+		t.Fatalf("25 Enter: cursor = %d, count = %q; want row 25 (index 24)", m.cursor, m.count)
+	}
+	if m.cursor < m.scrollOffset || m.cursor >= m.scrollOffset+m.visibleHeight() {
+		t.Errorf("cursor %d scrolled out of view (offset %d)", m.cursor, m.scrollOffset)
+	}
+
+	m = send(m, keyRune('3'))
+	m = send(m, keyRune('k'))
+	if m.cursor != 21 {
+		t.Errorf("3k: cursor = %d, want 21", m.cursor)
+	}
+	m = send(m, keyRune('9'))
+	m = send(m, keyRune('9'))
+	m = send(m, keyRune('j'))
+	if m.cursor != 49 {
+		t.Errorf("99j should clamp to the last row, cursor = %d", m.cursor)
+	}
+
+	m = send(m, keyRune('5'))
+	m = send(m, keyRune('0'))
+	m = send(m, keyRune('%'))
+	if m.cursor != 24 {
+		t.Errorf("50%%: cursor = %d, want 24 (row 25 of 50)", m.cursor)
+	}
+	if !strings.Contains(stripANSI(m.View()), "/50] ") {
+		t.Error("scroll indicator should show a percentage")
+	}
+	m = send(m, keyRune('2'))
+	m = send(m, keyRune('0'))
+	m = send(m, keyRune('0'))
+	m = send(m, keyRune('%'))
+	if m.cursor != 49 {
+		t.Errorf("200%% should clamp to the last row, cursor = %d", m.cursor)
+	}
+
+	// Esc drops a pending count without quitting
+	m = send(m, keyRune('4'))
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = next.(model)
+	if m.count != "" || cmd != nil {
+		t.Errorf("esc with a count: count = %q, cmd = %v", m.count, cmd)
+	}
+}
+
+func TestUpdateD_CurrentProjectNeedsSecondEnter(t *testing.T) {
+	chats := makeTestChats(3)
+	chats[1].ProjectPath = "/work/app"
+	m := makeTestModel(chats, normalWidth, 30)
+	m.cwd = "/work/app/internal"
+
+	m.cursor = 1
+	m = send(m, keyRune('d'))
+	out := stripANSI(m.View())
+	if !strings.Contains(out, "[current directory]") {
+		t.Errorf("recap should flag the current project's chat:\n%s", out)
+	}
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.deleting || !m.confirmDelete {
+		t.Fatal("first Enter must ask again instead of deleting")
+	}
+	if out := stripANSI(m.View()); !strings.Contains(out, "belong to the project you are in (/work/app/internal)") {
+		t.Errorf("second question missing:\n%s", out)
+	}
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.deleting {
+		t.Error("second Enter should start the deletion")
+	}
+
+	// Chats of other projects keep the single confirmation
+	m = makeTestModel(chats, normalWidth, 30)
+	m.cwd = "/work/app"
+	m = send(m, keyRune('d'))
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.deleting {
+		t.Error("chat outside the current project should delete on the first Enter")
+	}
+}
+
+func TestUpdateZ_RanksProjectsBySize(t *testing.T) {
+	setupStorageDirs(t)
+	write := func(project, uuid string, size int) {
+		t.Helper()
+		dir := filepath.Join(projectsDir, project)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		data := `{"type":"user"}` + "\n" + strings.Repeat("x", size)
+		if err := os.WriteFile(filepath.Join(dir, uuid+".jsonl"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("small", "s1", 10)
+	write("big", "b1", 5000)
+	write("big", "b2", 5000)
+	// Make the small project the most recent so it leads by default
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(projectsDir, "small", "s1.jsonl"), later, later)
+
+	m := loadedModel(&Config{GroupByProject: true}, nil)
+	m.width, m.height = normalWidth, 20
+	if m.groupRows[0].project != "small" {
+		t.Fatalf("default order should be by recency, first = %s", m.groupRows[0].project)
+	}
+
+	// Sizes are measured after the startup scan, so z ranks right away.
+	if len(m.chatSizes) != 3 || m.sizing {
+		t.Fatalf("startup should measure every chat, sizes = %v", m.chatSizes)
+	}
+	m = send(m, keyRune('z'))
+	if m.groupRows[0].project != "big" {
+		t.Errorf("z should rank the biggest project first, got %s", m.groupRows[0].project)
+	}
+	out := stripANSI(m.View())
+	if !strings.Contains(out, "Sort: size") || !strings.Contains(out, "KB)") {
+		t.Errorf("size ranking not shown:\n%s", out)
+	}
+}
+
+func TestUpdateR_SummarizesChanges(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	line := `{"type":"user"}` + "\n"
+	write := func(name string, lines int) {
+		if err := os.WriteFile(filepath.Join(dir, name+".jsonl"), []byte(strings.Repeat(line, lines)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("grows", 10)
+	write("same", 10)
+	write("goes", 10)
+
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 30
+	write("grows", 50)
+	write("new-1", 10)
+	write("new-2", 10)
+	os.Remove(filepath.Join(dir, "goes.jsonl"))
+
+	m = send(m, keyRune('r'))
+	want := "2 new chats, 1 grew by 40 lines, 1 gone"
+	if m.copiedMsg != want {
+		t.Errorf("refresh summary = %q, want %q", m.copiedMsg, want)
+	}
+	if out := stripANSI(m.View()); !strings.Contains(out, want) {
+		t.Errorf("summary should show in the status line:\n%s", out)
+	}
+
+	m = send(m, keyRune('r'))
+	if m.copiedMsg != "No changes since the last scan" {
+		t.Errorf("unchanged refresh summary = %q", m.copiedMsg)
+	}
+}
+
+func TestChatSizes_FillInAfterScan(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 30; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("chat-%02d.jsonl", i))
+		if err := os.WriteFile(name, []byte(`{"type":"user"}`+"\n"+strings.Repeat("x", 2048)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := initialModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 40
+	var cmd tea.Cmd
+	for cmd = startScan(); m.scanning; {
+		next, c := m.update(cmd())
+		m, cmd = next.(model), c
+	}
+	if cmd == nil || !m.sizing {
+		t.Fatal("the end of the scan should start measuring sizes")
+	}
+	out := stripANSI(m.View())
+	if !strings.Contains(out, "SIZE") || !strings.Contains(out, "…") || !strings.Contains(out, "Sizes [") {
+		t.Errorf("sizes should show as pending with a progress bar:\n%s", out)
+	}
+
+	next, cmd := m.update(cmd())
+	m = next.(model)
+	if m.sizeDone == 0 || m.sizeDone == m.sizeTotal || cmd == nil {
+		t.Fatalf("sizes should arrive in batches, got %d/%d", m.sizeDone, m.sizeTotal)
+	}
+	for cmd != nil {
+		next, cmd = m.update(cmd())
+		m = next.(model)
+	}
+	out = stripANSI(m.View())
+	if m.sizing || strings.Contains(out, "…") || !strings.Contains(out, "2.0 KB") {
+		t.Errorf("every size should be filled in:\n%s", out)
+	}
+}
+
+func TestStartupScan_StreamsProgress(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 30; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("chat-%02d.jsonl", i))
+		if err := os.WriteFile(name, []byte(`{"type":"user"}`+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := initialModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 20
+	if !strings.Contains(stripANSI(m.View()), "Scanning...") {
+		t.Errorf("the list should show the scan before any chat arrives:\n%s", m.View())
+	}
+
+	next, cmd := m.update(startScan()())
+	m = next.(model)
+	if len(m.chats) == 0 || len(m.chats) == 30 {
+		t.Fatalf("first batch should list some but not all chats, got %d", len(m.chats))
+	}
+	if out := stripANSI(m.View()); !strings.Contains(out, fmt.Sprintf("Scanning... %d/30 files", len(m.chats))) {
+		t.Errorf("progress missing from the stats line:\n%s", out)
+	}
+
+	m.selected[0] = true
+	selected := m.chats[0].UUID
+	for cmd != nil {
+		next, cmd = m.update(cmd())
+		m = next.(model)
+	}
+	if m.scanning || len(m.chats) != 30 {
+		t.Fatalf("scan should finish with every chat: scanning=%v, %d chats", m.scanning, len(m.chats))
+	}
+	if uuids := m.selectedUUIDs(); len(uuids) != 1 || uuids[0] != selected {
+		t.Errorf("selection should follow the chat as the list grows, got %v", uuids)
+	}
+	if strings.Contains(stripANSI(m.View()), "Scanning") {
+		t.Error("progress should disappear once the scan is done")
+	}
+}
+
+func TestSettingsEmptyTrash_ConfirmsWithSize(t *testing.T) {
+	origConfig := configPath
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	t.Cleanup(func() { configPath = origConfig })
+	dir := filepath.Join(trashDir(), "20260101-120000")
+	os.MkdirAll(dir, 0755)
+	writeTrashManifest(dir, trashManifest{Created: time.Now(), Chats: []trashedChat{{UUID: "a"}}})
+
+	m := makeTestModel(makeTestChats(1), normalWidth, 20)
+	m.tab = tabSettings
+	m.settingsCursor = settingEmptyTrash
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if out := stripANSI(m.View()); !strings.Contains(out, "Delete 1 chat(s) in 1 batch(es) for good, freeing") {
+		t.Fatalf("Enter should ask to confirm with the reclaimed size:\n%s", out)
+	}
+
+	// q cancels instead of quitting
+	next, cmd := m.Update(keyRune('q'))
+	m = next.(model)
+	if cmd != nil || m.trashPending != nil {
+		t.Fatal("q should cancel the confirmation")
+	}
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	next, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(model)
+	if !m.emptying || cmd == nil {
+		t.Fatal("confirming should remove the batch in the background")
+	}
+	next, _ = m.Update(cmd())
+	m = next.(model)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("confirming should remove the batch")
+	}
+	if out := stripANSI(m.View()); !strings.Contains(out, "Removed 1 batch(es)") {
+		t.Errorf("outcome missing:\n%s", out)
+	}
+}
+
+func TestUpdateA_LargeListNeedsSecondA(t *testing.T) {
+	m := makeTestModel(makeTestChats(5), normalWidth, 20)
+	m.cfg = &Config{SelectAllConfirmAbove: 3}
+
+	m = send(m, keyRune('a'))
+	if len(m.selected) != 0 || !m.selectAllPending {
+		t.Fatalf("a above the threshold should ask first, selected %d", len(m.selected))
+	}
+	if out := stripANSI(m.View()); !strings.Contains(out, "Select all 5 chats?") {
+		t.Errorf("confirmation not shown:\n%s", out)
+	}
+	m = send(m, keyRune('a'))
+	if len(m.selected) != 5 || m.selectAllPending {
+		t.Errorf("second a should select all, got %d", len(m.selected))
+	}
+
+	// Deselecting never asks; any other key cancels a pending select-all.
+	m = send(m, keyRune('a'))
+	if len(m.selected) != 0 {
+		t.Fatal("a should deselect all without asking")
+	}
+	m = send(m, keyRune('a'))
+	m = send(m, tea.KeyMsg{Type: tea.KeyDown})
+	if len(m.selected) != 0 || m.selectAllPending || m.cursor != 1 {
+		t.Errorf("down should cancel and still move: selected %d, cursor %d", len(m.selected), m.cursor)
+	}
+
+	m.cfg.SelectAllConfirmAbove = -1
+	m = send(m, keyRune('a'))
+	if len(m.selected) != 5 {
+		t.Error("a negative threshold should never ask")
+	}
+}
+
+func TestUpdateR_ReloadsConfig(t *testing.T) {
+	setupStorageDirs(t)
+	origConfig, origRoots, origFormat := configPath, claudeRoots, dateFormat
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	t.Cleanup(func() {
+		configPath, claudeRoots, dateFormat = origConfig, origRoots, origFormat
+		applyTheme(nil)
+	})
+
+	other := t.TempDir()
+	dir := filepath.Join(other, "projects", "proj")
+	os.MkdirAll(dir, 0755)
+	line := `{"type":"user","timestamp":"2026-03-04T12:00:00Z","message":{"content":"from the other dir"}}`
+	if err := os.WriteFile(filepath.Join(dir, "moved.jsonl"), []byte(line+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = normalWidth, 20
+	if len(m.chats) != 0 {
+		t.Fatalf("expected an empty start, got %d chats", len(m.chats))
+	}
+
+	cfg := fmt.Sprintf(`{"claude_dir":%q,"date_format":"2006/01/02","theme":{"preset":"nope"},"update_channel":"nightly"}`, other)
+	if err := os.WriteFile(configPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m = send(m, keyRune('R'))
+	if len(m.chats) != 1 || m.chats[0].UUID != "moved" {
+		t.Fatalf("R should rescan the new claude_dir, got %+v", m.chats)
+	}
+	if m.chats[0].Timestamp != "2026/03/04" {
+		t.Errorf("new date format not applied: %q", m.chats[0].Timestamp)
+	}
+	if !strings.Contains(m.error, "unknown theme preset") {
+		t.Errorf("bad theme should be reported, error = %q", m.error)
+	}
+	if !strings.Contains(m.error, `unknown channel "nightly"`) {
+		t.Errorf("unknown update_channel should be reported, error = %q", m.error)
+	}
+}
+
+func TestView_StatsBreakDownByRoot(t *testing.T) {
+	origRoots := claudeRoots
+	claudeRoots = []string{"/home/me/.claude", "/work/app/.claude"}
+	t.Cleanup(func() { claudeRoots = origRoots })
+
+	chats := makeTestChats(3)
+	chats[2].Root = newClaudeRoot("/work/app/.claude")
+	m := makeTestModel(chats, 160, 20)
+	if out := stripANSI(m.View()); !strings.Contains(out, "Total: 3 (me/.claude: 2, app/.claude: 1)") {
+		t.Errorf("stats line should break the total down by root:\n%s", out)
+	}
+
+	next, _ := m.update(deleteCompleteMsg{count: 1, chats: chats[2:], total: 1})
+	if status := next.(model).deletedStatus(); !strings.Contains(status, "Deleted 1 chat(s) (app/.claude: 1)") {
+		t.Errorf("deletion summary should name the root, got %q", status)
+	}
+
+	claudeRoots = nil
+	if out := stripANSI(m.View()); strings.Contains(out, "Total: 3 (") {
+		t.Error("a single root needs no breakdown")
+	}
+}
+
+func TestExcludeProjects_SkipsScanAndShowsCount(t *testing.T) {
+	setupStorageDirs(t)
+	excludeProjects = []string{"archived-*"}
+	t.Cleanup(func() { excludeProjects = nil })
+
+	for _, project := range []string{"live", "archived-monorepo"} {
+		dir := filepath.Join(projectsDir, project)
+		os.MkdirAll(dir, 0755)
+		if err := os.WriteFile(filepath.Join(dir, project+"-chat.jsonl"), []byte(`{"type":"user"}`+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := loadedModel(&Config{}, nil)
+	m.width, m.height = 160, 20
+	if len(m.chats) != 1 || m.chats[0].Project != "live" {
+		t.Fatalf("excluded project should not be scanned, got %+v", m.chats)
+	}
+	out := stripANSI(m.View())
+	if !strings.Contains(out, "[Excluded: 1 project(s)]") || strings.Contains(out, "Esc: reset") {
+		t.Errorf("stats line should note the fixed exclusion:\n%s", out)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Notes are short free-text annotations on chats, stored by UUID next to the
+// config so they survive rescans and runs. Noted chats are protected unless
+// protect_noted is set to false.
+
+// notesPath returns the file holding the chat notes.
+func notesPath() string {
+	return filepath.Join(filepath.Dir(configPath), "notes.json")
+}
+
+// loadNotes reads every note, keyed by UUID. A missing file means no notes.
+func loadNotes() (map[string]string, error) {
+	return readUUIDMap(notesPath())
+}
+
+// setNote stores note for uuid; an empty note removes it.
+func setNote(uuid, note string) error {
+	return setUUIDValue(notesPath(), uuid, note)
+}
+
+// readUUIDMap reads a JSON object of strings keyed by chat UUID, as used for
+// notes and labels. A missing file is an empty map.
+func readUUIDMap(path string) (map[string]string, error) {
+	values := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// setUUIDValue stores value for uuid in the map file at path; an empty value
+// removes the entry.
+func setUUIDValue(path, uuid, value string) error {
+	values, err := readUUIDMap(path)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		delete(values, uuid)
+	} else {
+		values[uuid] = value
+	}
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// applyNotes copies notes onto chats and, with protect set, protects every
+// noted chat.
+func applyNotes(chats []Chat, notes map[string]string, protect bool) {
+	for i := range chats {
+		if note, ok := notes[chats[i].UUID]; ok {
+			chats[i].Note = note
+			if protect {
+				chats[i].Protected = true
+			}
+		}
+	}
+}
+
+// notedUUIDs returns the UUIDs that have a note, for use as protection
+// patterns outside the TUI.
+func notedUUIDs(notes map[string]string) []string {
+	uuids := make([]string, 0, len(notes))
+	for uuid := range notes {
+		uuids = append(uuids, uuid)
+	}
+	return uuids
+}
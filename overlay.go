@@ -0,0 +1,377 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// Overlays take over the whole screen until dismissed. They are read-only text
+// views: the model builds the lines once when opening, the overlay only scrolls.
+const (
+	overlayNone = iota
+	overlayHelp
+	overlayHistory
+	overlayIndexEntry
+	overlayPlan
+	overlayCompare
+)
+
+// helpSection is one category of the keyboard help overlay.
+type helpSection struct {
+	title string
+	keys  [][2]string // {key, description}
+}
+
+var helpSections = []helpSection{
+	{
+		title: "Navigation",
+		keys: [][2]string{
+			{"↑/k, ↓/j", "Move cursor up / down"},
+			{"f/PgDn, b/PgUp", "Page down / up"},
+			{"F, B", "Half page down / up"},
+			{"g/Home, G/End, N%", "Jump to first / last row / N% down"},
+			{"←, →", "Switch tabs"},
+		},
+	},
+	{
+		title: "Selection",
+		keys: [][2]string{
+			{"Space", "Toggle chat (or whole project on a header)"},
+			{"a", "Toggle select all"},
+			{"A", "Toggle every chat of the current project"},
+			{"o", "Show only selected chats (review mode)"},
+			{"S, L", "Save / load a named selection"},
+			{"Enter", "Expand / collapse project (grouped view)"},
+		},
+	},
+	{
+		title: "Actions",
+		keys: [][2]string{
+			{"d", "Delete selection (or the chat under the cursor)"},
+			{"D", "Delete every chat in view (what the filters show)"},
+			{"X", "Delete the current project and its directory"},
+			{"x, V", "Stage chat for deletion / select and review the staged"},
+			{"c", "Copy chat UUID to clipboard"},
+			{"e", "Export chat to Markdown"},
+			{"E", "Copy the chat's raw JSONL to a file"},
+			{"N", "Add / edit the chat's note (noted chats are protected)"},
+			{"T", "Label the chat with your own title"},
+			{"r", "Refresh list from disk"},
+			{"R", "Reload the config file"},
+			{"H", "Show chats deleted this session"},
+			{"i", "Show the chat's raw sessions-index.json entry"},
+			{"v", "Preview the chat's plan file"},
+			{"C", "Compare the two selected chats side by side"},
+			{"?", "Show this help"},
+			{"q, Esc, Ctrl+C", "Quit"},
+		},
+	},
+	{
+		title: "View",
+		keys: [][2]string{
+			{"t", "Hide / show trivial chats"},
+			{"w", "Cycle time window (all, today, 7 days, 30 days)"},
+			{"m", "Only chats with a plan, subagents, file history, ..."},
+			{"M", "Only chats with so many messages, e.g. <=2 or >=100"},
+			{"p", "Toggle full project paths"},
+			{"l", "Expand / collapse the chat's related files inline"},
+			{"#", "Line numbers: off, absolute, relative to the cursor"},
+			{"<, >", "Scroll the list sideways on narrow terminals"},
+			{"z", "Rank projects by total size (grouped view)"},
+			{"h", "Hide / show the help line for more rows"},
+			{"Z", "Focus mode: dim every row but the cursor's"},
+			{"u", "Trash tab: restore or delete trashed chats"},
+		},
+	},
+	{
+		title: "Confirmation dialog",
+		keys: [][2]string{
+			{"Enter", "Confirm deletion"},
+			{"t", "Move to trash this time, whatever use_trash says"},
+			{"P", "Delete permanently this time, whatever use_trash says"},
+			{"s", "Decide chat by chat: y delete, n keep, Y/N the rest"},
+			{"Esc, n, q", "Cancel"},
+		},
+	},
+}
+
+// deletionKeys are hidden from the help overlay in read-only mode, together
+// with the confirmation dialog section.
+var deletionKeys = map[string]bool{"d": true, "D": true, "X": true}
+
+// helpLines renders helpSections as plain overlay lines with aligned keys.
+func helpLines(readOnly bool) []string {
+	keyWidth := 0
+	for _, sec := range helpSections {
+		for _, k := range sec.keys {
+			if w := runewidth.StringWidth(k[0]); w > keyWidth {
+				keyWidth = w
+			}
+		}
+	}
+
+	var lines []string
+	for _, sec := range helpSections {
+		if readOnly && sec.title == "Confirmation dialog" {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, sec.title)
+		for _, k := range sec.keys {
+			if readOnly && deletionKeys[k[0]] {
+				continue
+			}
+			pad := strings.Repeat(" ", keyWidth-runewidth.StringWidth(k[0]))
+			lines = append(lines, fmt.Sprintf("  %s%s  %s", k[0], pad, k[1]))
+		}
+	}
+	return lines
+}
+
+// indexEntryLines pretty-prints the sessions-index.json entry of chat, for
+// diagnosing drift between the index and the JSONL files.
+func indexEntryLines(chat Chat) []string {
+	root := chat.root()
+	indexPath := filepath.Join(root.projects, chat.Project, "sessions-index.json")
+	lines := []string{indexPath, ""}
+	if chat.Note != "" {
+		lines = append([]string{"Note: " + chat.Note, ""}, lines...)
+	}
+	if chat.DerivedTitle != "" {
+		lines = append([]string{"Label: " + chat.Title, "Title: " + chat.DerivedTitle, ""}, lines...)
+	}
+	if chat.Problem != "" {
+		lines = append([]string{"Problem: " + chat.Problem + " (" + chat.Path + ")", ""}, lines...)
+	}
+	if chat.Compacted {
+		lines = append([]string{"Compacted: the chat has a summary record (/compact)", ""}, lines...)
+	}
+
+	entry := findIndexEntry(root, chat.UUID, chat.Project)
+	if entry == nil {
+		return append(lines, "No entry for "+chat.UUID+" (the index may be absent; recent Claude Code versions don't write it).")
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return append(lines, "Error: "+err.Error())
+	}
+	lines = append(lines, strings.Split(string(data), "\n")...)
+	if entry.FileMtime > 0 {
+		mtime := time.UnixMilli(entry.FileMtime).Local().Format("2006-01-02 15:04:05")
+		lines = append(lines, "", "fileMtime: "+mtime)
+	}
+	return lines
+}
+
+// compareTurns is how many turns of each chat the compare overlay shows.
+const compareTurns = 6
+
+// compareLines renders a and b in two columns fitting width: title, project,
+// last activity and size on top, then the opening turns of each.
+func compareLines(a, b Chat, width int) []string {
+	colWidth := (width - 3) / 2
+	if colWidth < 10 {
+		colWidth = 10
+	}
+	column := func(chat Chat) []string {
+		lines := wrapText(chatTitleLabel(chat), colWidth)
+		lines = append(lines,
+			truncateLeft(chat.Project, colWidth),
+			fmt.Sprintf("%s, %d lines", chat.Timestamp, chat.LineCount),
+			strings.Repeat("─", colWidth))
+
+		file, err := os.Open(chat.Path)
+		if err != nil {
+			return append(lines, "Error: "+err.Error())
+		}
+		defer file.Close()
+		n := 0
+		err = eachTurn(file, func(heading, text string) bool {
+			if n > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, heading+":")
+			lines = append(lines, wrapText(text, colWidth)...)
+			n++
+			return n < compareTurns
+		})
+		if err != nil {
+			lines = append(lines, "Error: "+err.Error())
+		}
+		return lines
+	}
+
+	left, right := column(a), column(b)
+	lines := make([]string, max(len(left), len(right)))
+	for i := range lines {
+		var l, r string
+		if i < len(left) {
+			l = runewidth.Truncate(left[i], colWidth, "")
+		}
+		if i < len(right) {
+			r = runewidth.Truncate(right[i], colWidth, "")
+		}
+		lines[i] = padRight(l, colWidth) + " │ " + r
+	}
+	return lines
+}
+
+// openOverlay shows lines full-screen under title, scrolled to the top.
+func (m *model) openOverlay(kind int, title string, lines []string) {
+	m.overlay = kind
+	m.overlayTitle = title
+	m.overlayLines = lines
+	m.overlayScroll = 0
+}
+
+// overlayHeight is the number of content lines that fit on screen:
+// title(1) + separator(1) + bottom separator(1) + help(1) are fixed.
+func (m model) overlayHeight() int {
+	h := m.height - 4
+	if h < 1 {
+		h = 10
+	}
+	return h
+}
+
+// updateOverlay handles keys while an overlay is open. Any close key returns to
+// the view underneath with its state untouched.
+func (m model) updateOverlay(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	maxScroll := len(m.overlayLines) - m.overlayHeight()
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q", "?":
+		m.overlay = overlayNone
+		m.overlayLines = nil
+	case "up", "k":
+		if m.overlayScroll > 0 {
+			m.overlayScroll--
+		}
+	case "down", "j":
+		if m.overlayScroll < maxScroll {
+			m.overlayScroll++
+		}
+	case "f", "pgdown":
+		m.overlayScroll += m.overlayHeight()
+		if m.overlayScroll > maxScroll {
+			m.overlayScroll = maxScroll
+		}
+	case "b", "pgup":
+		m.overlayScroll -= m.overlayHeight()
+		if m.overlayScroll < 0 {
+			m.overlayScroll = 0
+		}
+	case "g", "home":
+		m.overlayScroll = 0
+	case "G", "end":
+		m.overlayScroll = maxScroll
+	}
+	return m, nil
+}
+
+func (m model) viewOverlay() string {
+	width := m.width
+	if width < 75 {
+		width = 75
+	}
+
+	var s strings.Builder
+	s.WriteString(activeTabStyle.Render(m.overlayTitle))
+	s.WriteString("\n")
+	s.WriteString(dimStyle.Render(strings.Repeat("─", width)))
+	s.WriteString("\n")
+
+	height := m.overlayHeight()
+	start := m.overlayScroll
+	end := start + height
+	if end > len(m.overlayLines) {
+		end = len(m.overlayLines)
+	}
+	// A code fence above the first visible line still applies to it.
+	inCode := false
+	if m.overlay == overlayPlan {
+		for _, line := range m.overlayLines[:start] {
+			_, inCode = markdownLine(line, inCode)
+		}
+	}
+	for _, line := range m.overlayLines[start:end] {
+		// Overlay content may come from files on disk; keep one line per row.
+		line = strings.NewReplacer("\n", " ", "\r", "").Replace(line)
+		line = runewidth.Truncate(line, width, "..")
+		if m.overlay == overlayPlan {
+			line, inCode = markdownLine(line, inCode)
+		}
+		s.WriteString(line)
+		s.WriteString("\n")
+	}
+
+	s.WriteString(dimStyle.Render(strings.Repeat("─", width)))
+	s.WriteString("\n")
+	help := "esc/q/?:Close"
+	if len(m.overlayLines) > height {
+		help = fmt.Sprintf("↑/↓:Scroll | f/b:PgDn/PgUp | esc/q/?:Close   [%d-%d/%d]", start+1, end, len(m.overlayLines))
+	}
+	s.WriteString(helpStyle.Render(help))
+	s.WriteString("\n")
+	return s.String()
+}
+
+var (
+	mdBold       = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	mdInlineCode = regexp.MustCompile("`([^`]+)`")
+	mdListItem   = regexp.MustCompile(`^(\s*)[-*+] `)
+)
+
+// markdownLine styles one line of a plan for the plan overlay (v): headings
+// are bold, list bullets become •, code and quotes are dimmed, **bold** and
+// `code` spans are styled inline. inCode says whether the line is inside a
+// fenced code block; the state after the line is returned with it. This is
+// only meant to make plans easy to skim, not to render Markdown fully.
+func markdownLine(line string, inCode bool) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+		return dimStyle.Render(line), !inCode
+	}
+	if inCode {
+		return dimStyle.Render(line), true
+	}
+
+	switch {
+	case strings.HasPrefix(trimmed, "#"):
+		text := strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		style := lipgloss.NewStyle().Bold(true)
+		if !strings.HasPrefix(trimmed, "##") {
+			style = style.Underline(true)
+		}
+		return style.Render(text), false
+	case strings.HasPrefix(trimmed, ">"):
+		return dimStyle.Italic(true).Render(line), false
+	case trimmed == "---" || trimmed == "***" || trimmed == "___":
+		return dimStyle.Render(strings.Repeat("─", runewidth.StringWidth(line))), false
+	}
+
+	line = mdListItem.ReplaceAllString(line, "$1• ")
+	line = mdBold.ReplaceAllStringFunc(line, func(span string) string {
+		return lipgloss.NewStyle().Bold(true).Render(span[2 : len(span)-2])
+	})
+	line = mdInlineCode.ReplaceAllStringFunc(line, func(span string) string {
+		return dimStyle.Render(span[1 : len(span)-1])
+	})
+	return line, false
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The text prompt is a single input line shown in place of the help line.
+// promptAction decides what Enter does with the typed value.
+const (
+	promptNone = iota
+	promptExportMarkdown
+	promptExportJSONL
+	promptSaveSelection
+	promptLoadSelection
+	promptNote
+	promptChatLabel
+	promptMessageFilter
+)
+
+// openPrompt starts text entry for action on the chat at chatIdx, prefilled
+// with value.
+func (m *model) openPrompt(action int, label, value string, chatIdx int) {
+	m.promptAction = action
+	m.promptLabel = label
+	m.promptValue = value
+	m.promptChat = chatIdx
+}
+
+func (m *model) closePrompt() {
+	m.promptAction = promptNone
+	m.promptLabel = ""
+	m.promptValue = ""
+}
+
+// updatePrompt edits the prompt value; Enter submits, Esc cancels.
+func (m model) updatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.closePrompt()
+	case tea.KeyEnter:
+		action, value, idx := m.promptAction, m.promptValue, m.promptChat
+		m.closePrompt()
+		return m.submitPrompt(action, value, idx)
+	case tea.KeyBackspace:
+		if r := []rune(m.promptValue); len(r) > 0 {
+			m.promptValue = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.promptValue += string(msg.Runes)
+	}
+	return m, nil
+}
+
+// submitPrompt runs the action the prompt was opened for.
+func (m model) submitPrompt(action int, value string, chatIdx int) (tea.Model, tea.Cmd) {
+	value = strings.TrimSpace(value)
+	if action == promptNote {
+		return m.submitNote(value, chatIdx)
+	}
+	if action == promptChatLabel {
+		return m.submitLabel(value, chatIdx)
+	}
+	if action == promptMessageFilter {
+		return m.submitMessageFilter(value)
+	}
+	if value == "" {
+		return m, nil
+	}
+	switch action {
+	case promptExportMarkdown:
+		if chatIdx < 0 || chatIdx >= len(m.chats) {
+			return m, nil
+		}
+		if err := exportMarkdown(m.chats[chatIdx], value); err != nil {
+			m.error = fmt.Sprintf("Export failed: %v", err)
+			return m, nil
+		}
+		return m.flashStatus(fmt.Sprintf("Exported to %s", value))
+
+	case promptExportJSONL:
+		if chatIdx < 0 || chatIdx >= len(m.chats) {
+			return m, nil
+		}
+		if err := exportJSONL(m.chats[chatIdx], value); err != nil {
+			m.error = fmt.Sprintf("Export failed: %v", err)
+			return m, nil
+		}
+		return m.flashStatus(fmt.Sprintf("Copied raw JSONL to %s", value))
+
+	case promptSaveSelection:
+		uuids := m.selectedUUIDs()
+		if err := saveSelectionSet(value, uuids); err != nil {
+			m.error = fmt.Sprintf("Failed to save selection: %v", err)
+			return m, nil
+		}
+		return m.flashStatus(fmt.Sprintf("Saved %d chat(s) as selection %q", len(uuids), value))
+
+	case promptLoadSelection:
+		sets, err := loadSelectionSets()
+		if err != nil {
+			m.error = fmt.Sprintf("Failed to load selections: %v", err)
+			return m, nil
+		}
+		uuids, ok := sets[value]
+		if !ok {
+			m.error = fmt.Sprintf("No saved selection %q", value)
+			return m, nil
+		}
+		found := m.selectUUIDs(uuids)
+		return m.flashStatus(fmt.Sprintf("Loaded selection %q: %d of %d chat(s) found", value, found, len(uuids)))
+	}
+	return m, nil
+}
+
+// submitNote stores the note for the chat at chatIdx; an empty value removes
+// it. The list is reloaded since the note can change the chat's protection.
+func (m model) submitNote(value string, chatIdx int) (tea.Model, tea.Cmd) {
+	if chatIdx < 0 || chatIdx >= len(m.chats) {
+		return m, nil
+	}
+	if err := setNote(m.chats[chatIdx].UUID, value); err != nil {
+		m.error = fmt.Sprintf("Failed to save note: %v", err)
+		return m, nil
+	}
+	uuids := m.selectedUUIDs()
+	m.loadChats()
+	m.selectUUIDs(uuids)
+	if m.grouped {
+		m.rebuildGroupRows()
+	}
+	if value == "" {
+		return m.flashStatus("Note removed")
+	}
+	return m.flashStatus("Note saved")
+}
+
+// submitLabel stores the label for the chat at chatIdx; an empty value
+// restores the derived title. Labels are applied by the scan, so the list is
+// reloaded.
+func (m model) submitLabel(value string, chatIdx int) (tea.Model, tea.Cmd) {
+	if chatIdx < 0 || chatIdx >= len(m.chats) {
+		return m, nil
+	}
+	if err := setLabel(m.chats[chatIdx].UUID, value); err != nil {
+		m.error = fmt.Sprintf("Failed to save label: %v", err)
+		return m, nil
+	}
+	uuids := m.selectedUUIDs()
+	m.loadChats()
+	m.selectUUIDs(uuids)
+	if m.grouped {
+		m.rebuildGroupRows()
+	}
+	if value == "" {
+		return m.flashStatus("Label removed")
+	}
+	return m.flashStatus("Label saved")
+}
+
+// submitMessageFilter shows only the chats whose message count matches
+// value (see messageFilter); empty shows every chat again. The selection is
+// kept, as with Esc.
+func (m model) submitMessageFilter(value string) (tea.Model, tea.Cmd) {
+	if _, err := messageFilter(value); err != nil {
+		m.error = "Invalid message filter " + err.Error()
+		return m, nil
+	}
+	uuids := m.selectedUUIDs()
+	m.msgFilter = strings.ReplaceAll(value, " ", "")
+	m.loadChats()
+	m.selectUUIDs(uuids)
+	m.cursor = 0
+	m.scrollOffset = 0
+	if m.grouped {
+		m.rebuildGroupRows()
+	}
+	return m, nil
+}
+
+func (m model) renderPrompt() string {
+	return errorStyle.Render(m.promptLabel+": ") + m.promptValue + "█  " +
+		helpStyle.Render("[ENTER=OK] [ESC=Cancel]") + "\n"
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// The related-file index caches findRelatedFiles per chat next to the config,
+// so scripted cleanups don't repeat the discovery work (reading every chat to
+// check plan slugs) on each run. It is only used with cache_related_files and
+// only by the non-interactive delete modes. Each Claude directory's entries
+// carry a stamp of the directories and chat files they were resolved from;
+// any change there discards them.
+
+// relatedIndexPath returns the file holding the related-file index.
+func relatedIndexPath() string {
+	return filepath.Join(filepath.Dir(configPath), "related-files.json")
+}
+
+type relatedIndex struct {
+	Roots map[string]*rootRelated `json:"roots"`
+}
+
+// rootRelated holds the entries of one Claude directory, keyed by
+// relatedKey.
+type rootRelated struct {
+	Stamp string                  `json:"stamp"`
+	Chats map[string]relatedEntry `json:"chats"`
+}
+
+type relatedEntry struct {
+	Slug  string   `json:"slug,omitempty"` // plan slug; chats sharing it share the plan file
+	Files []string `json:"files"`
+}
+
+func relatedKey(project, uuid string) string {
+	return project + "/" + uuid
+}
+
+// loadRelatedIndex reads the index; a missing or unreadable file is an empty
+// index, since it can always be rebuilt.
+func loadRelatedIndex() *relatedIndex {
+	idx := &relatedIndex{}
+	if data, err := os.ReadFile(relatedIndexPath()); err == nil {
+		json.Unmarshal(data, idx)
+	}
+	if idx.Roots == nil {
+		idx.Roots = make(map[string]*rootRelated)
+	}
+	return idx
+}
+
+func (idx *relatedIndex) save() error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(relatedIndexPath()), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(relatedIndexPath(), data, 0644)
+}
+
+// resolveRelatedFiles fills Files of every chat from the index where it is
+// still current and with findRelatedFiles otherwise, then saves the index.
+// With refresh set the stored entries are ignored and rebuilt.
+func resolveRelatedFiles(chats []Chat, refresh bool) error {
+	idx := loadRelatedIndex()
+	for _, root := range scanRoots() {
+		stamp := relatedStamp(root)
+		entries := idx.Roots[root.dir]
+		if refresh || entries == nil || entries.Stamp != stamp {
+			entries = &rootRelated{Stamp: stamp, Chats: make(map[string]relatedEntry)}
+			idx.Roots[root.dir] = entries
+		}
+		for i := range chats {
+			if !chatInRoot(chats[i], root) {
+				continue
+			}
+			key := relatedKey(chats[i].Project, chats[i].UUID)
+			entry, ok := entries.Chats[key]
+			if !ok {
+				entry = relatedEntry{Files: findRelatedFiles(root, chats[i].UUID, chats[i].Project)}
+				if chats[i].Path != "" {
+					entry.Slug = getSlugFromChat(chats[i].Path)
+				}
+				entries.Chats[key] = entry
+			}
+			chats[i].Files = entry.Files
+		}
+	}
+	return idx.save()
+}
+
+// forgetRelatedFiles drops the entries of deleted chats, and of chats whose
+// files depended on them (same UUID elsewhere, same plan slug), then restamps
+// the index so the deletions themselves don't invalidate the rest.
+func forgetRelatedFiles(deleted []Chat) error {
+	idx := loadRelatedIndex()
+	for _, root := range scanRoots() {
+		entries := idx.Roots[root.dir]
+		if entries == nil {
+			continue
+		}
+		uuids := make(map[string]bool)
+		slugs := make(map[string]bool)
+		for _, chat := range deleted {
+			if !chatInRoot(chat, root) {
+				continue
+			}
+			uuids[chat.UUID] = true
+			if entry, ok := entries.Chats[relatedKey(chat.Project, chat.UUID)]; ok && entry.Slug != "" {
+				slugs[entry.Slug] = true
+			}
+		}
+		for key, entry := range entries.Chats {
+			if uuids[filepath.Base(key)] || (entry.Slug != "" && slugs[entry.Slug]) {
+				delete(entries.Chats, key)
+			}
+		}
+		entries.Stamp = relatedStamp(root)
+	}
+	return idx.save()
+}
+
+// chatInRoot reports whether chat was found in root; chats built without a
+// Root belong to claude_dir.
+func chatInRoot(chat Chat, root claudeRoot) bool {
+	return chat.root().dir == root.dir
+}
+
+// relatedStamp fingerprints what findRelatedFiles depends on in the Claude
+// directory root: the artifact directories, every project and agent
+// directory and every chat file.
+func relatedStamp(root claudeRoot) string {
+	h := sha256.New()
+	stat := func(path string) {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(h, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	for _, dir := range []string{root.dir, root.projects, root.debug, root.todos, root.sessionEnv, root.tasks, root.fileHistory, root.plans, root.agents} {
+		stat(dir)
+	}
+	for _, pattern := range []string{"*", filepath.Join("*", "*.jsonl")} {
+		matches, _ := filepath.Glob(filepath.Join(root.projects, pattern))
+		for _, m := range matches {
+			stat(m)
+		}
+	}
+	agents, _ := filepath.Glob(filepath.Join(root.agents, "*"))
+	for _, a := range agents {
+		stat(a)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Selection sets persist a selection across runs under a name. They store
+// UUIDs, not list indices, so they survive rescans and new chats.
+
+// selectionsPath returns the file holding the named selection sets.
+func selectionsPath() string {
+	return filepath.Join(filepath.Dir(configPath), "selections.json")
+}
+
+// loadSelectionSets reads every saved set. A missing file means no sets.
+func loadSelectionSets() (map[string][]string, error) {
+	sets := make(map[string][]string)
+	data, err := os.ReadFile(selectionsPath())
+	if os.IsNotExist(err) {
+		return sets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &sets); err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+// saveSelectionSet stores uuids under name, replacing a set of the same name.
+func saveSelectionSet(name string, uuids []string) error {
+	sets, err := loadSelectionSets()
+	if err != nil {
+		return err
+	}
+	sort.Strings(uuids)
+	sets[name] = uuids
+
+	data, err := json.MarshalIndent(sets, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(selectionsPath()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(selectionsPath(), data, 0644)
+}
+
+// selectionSetNames returns the saved set names, sorted.
+func selectionSetNames() ([]string, error) {
+	sets, err := loadSelectionSets()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(sets))
+	for name := range sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
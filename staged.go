@@ -0,0 +1,32 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// The staging list collects chats marked for deletion over time (x), apart
+// from the selection, which only lasts for one pass over the list. It is
+// stored by UUID next to the config, with the time each chat was staged, so
+// it survives runs; V selects the staged chats to review and delete them.
+
+// stagedPath returns the file holding the staging list.
+func stagedPath() string {
+	return filepath.Join(filepath.Dir(configPath), "staged.json")
+}
+
+// loadStaged reads the staging list: staging times keyed by UUID. A missing
+// file means nothing is staged.
+func loadStaged() (map[string]string, error) {
+	return readUUIDMap(stagedPath())
+}
+
+// setStaged adds uuid to the staging list, or removes it when staged is
+// false.
+func setStaged(uuid string, staged bool) error {
+	value := ""
+	if staged {
+		value = time.Now().Format(time.RFC3339)
+	}
+	return setUUIDValue(stagedPath(), uuid, value)
+}
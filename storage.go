@@ -3,110 +3,334 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode/utf8"
 )
 
-func findAllChats() []Chat {
-	var chats []Chat
+// findAllChats scans every project for chats. A missing projects directory
+// simply yields no chats; any other read failure (typically permissions) is
+// returned so it isn't mistaken for an empty history.
+func findAllChats() ([]Chat, error) {
+	files, scanErr := listChatFiles()
+	chats := make([]Chat, 0, len(files))
+	for _, f := range files {
+		chats = append(chats, scanChatFile(f))
+	}
+	labels, _ := loadLabels() // cosmetic: an unreadable file just shows the derived titles
+	applyLabels(chats, labels)
+	sortChats(chats)
+	return chats, scanErr
+}
+
+// streamChats scans like findAllChats but reports progress: after listing
+// every chat file it calls batch with the chats scanned since the previous
+// call, the number of files scanned so far and the total. Chats arrive
+// unsorted.
+func streamChats(batch func(chats []Chat, done, total int)) error {
+	files, scanErr := listChatFiles()
+	labels, _ := loadLabels()
+	const batchSize = 25
+	var pending []Chat
+	for i, f := range files {
+		pending = append(pending, scanChatFile(f))
+		if len(pending) == batchSize || i == len(files)-1 {
+			applyLabels(pending, labels)
+			batch(pending, i+1, len(files))
+			pending = nil
+		}
+	}
+	return scanErr
+}
+
+// sortChats orders chats by last activity (newest first); UUID breaks exact
+// ties so the order is stable across refreshes.
+func sortChats(chats []Chat) {
+	sort.Slice(chats, func(i, j int) bool {
+		if !chats[i].Time.Equal(chats[j].Time) {
+			return chats[i].Time.After(chats[j].Time)
+		}
+		return chats[i].UUID < chats[j].UUID
+	})
+}
+
+// artifactFilters are the related-file kinds the list can be narrowed to
+// (m in the TUI); entry 0 is no filter. The checks are single stats, much
+// cheaper than findRelatedFiles.
+var artifactFilters = []struct {
+	label string
+	has   func(chat Chat) bool
+}{
+	{"", nil},
+	{"plan", func(chat Chat) bool { return chat.PlanPath != "" }},
+	{"subagents", func(chat Chat) bool { return chatDirHas(chat, "subagents") }},
+	{"tool results", func(chat Chat) bool { return chatDirHas(chat, "tool-results") }},
+	{"file history", func(chat Chat) bool { return fileExists(filepath.Join(chat.root().fileHistory, chat.UUID)) }},
+	{"todos", func(chat Chat) bool {
+		matches, _ := filepath.Glob(filepath.Join(chat.root().todos, chat.UUID+"*.json"))
+		return len(matches) > 0
+	}},
+	{"tasks", func(chat Chat) bool { return fileExists(filepath.Join(chat.root().tasks, chat.UUID)) }},
+	{"summary", func(chat Chat) bool { return chat.Compacted }},
+}
+
+// chatDirHas reports whether the chat's own directory (next to its JSONL)
+// contains name.
+func chatDirHas(chat Chat, name string) bool {
+	if chat.Path == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(strings.TrimSuffix(chat.Path, ".jsonl"), name))
+	return err == nil
+}
+
+// chatFile is a chat transcript found by listChatFiles, not yet parsed.
+type chatFile struct {
+	root     claudeRoot
+	project  string
+	path     string
+	messages int // messageCount from sessions-index.json; -1 when it isn't listed
+}
+
+// listChatFiles lists the chat transcripts of every Claude directory. The
+// first read failure is returned alongside whatever could be listed.
+func listChatFiles() ([]chatFile, error) {
+	var files []chatFile
+	var scanErr error
+	for _, root := range scanRoots() {
+		found, err := listProjectFiles(root)
+		if err != nil && scanErr == nil {
+			scanErr = err
+		}
+		files = append(files, found...)
+	}
+	return files, scanErr
+}
 
-	entries, err := os.ReadDir(projectsDir)
+// listProjectFiles lists the chat transcripts under the projects directory
+// of root.
+func listProjectFiles(root claudeRoot) ([]chatFile, error) {
+	var files []chatFile
+
+	entries, err := os.ReadDir(root.projects)
 	if err != nil {
-		return chats
+		if os.IsNotExist(err) {
+			return files, nil
+		}
+		return files, describeScanError(root.projects, err)
 	}
 
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
 		}
+		if projectScope != "" && entry.Name() != projectScope {
+			continue
+		}
+		if isExcludedProject(entry.Name()) {
+			continue
+		}
 
-		projectPath := filepath.Join(projectsDir, entry.Name())
+		projectPath := filepath.Join(root.projects, entry.Name())
 
-		// TODO: Build a map of UUID -> messageCount from sessions-index.json if it exists
-		// messageCountMap := make(map[string]int)
-		// indexPath := filepath.Join(projectPath, "sessions-index.json")
-		// if data, err := os.ReadFile(indexPath); err == nil {
-		// 	var index SessionsIndex
-		// 	if err := json.Unmarshal(data, &index); err == nil {
-		// 		for _, sessionEntry := range index.Entries {
-		// 			messageCountMap[sessionEntry.SessionID] = sessionEntry.MessageCount
-		// 		}
-		// 	}
-		// }
+		// UUID -> messageCount from sessions-index.json, where there is one
+		messageCounts := make(map[string]int)
+		if data, err := os.ReadFile(filepath.Join(projectPath, "sessions-index.json")); err == nil {
+			var index SessionsIndex
+			if err := json.Unmarshal(data, &index); err == nil {
+				for _, sessionEntry := range index.Entries {
+					messageCounts[sessionEntry.SessionID] = sessionEntry.MessageCount
+				}
+			}
+		}
 
 		// Scan all JSONL files (original behavior)
-		files, err := filepath.Glob(filepath.Join(projectPath, "*.jsonl"))
+		matches, err := filepath.Glob(filepath.Join(projectPath, "*.jsonl"))
 		if err != nil {
 			continue
 		}
 
-		for _, file := range files {
-			basename := filepath.Base(file)
-			uuid := strings.TrimSuffix(basename, ".jsonl")
-
+		for _, file := range matches {
 			// Skip agent files
-			if strings.HasPrefix(uuid, "agent-") {
+			if strings.HasPrefix(filepath.Base(file), "agent-") {
 				continue
 			}
+			messages, ok := messageCounts[strings.TrimSuffix(filepath.Base(file), ".jsonl")]
+			if !ok {
+				messages = -1
+			}
+			files = append(files, chatFile{root: root, project: entry.Name(), path: file, messages: messages})
+		}
+	}
+	return files, nil
+}
 
-			title, version, forkParentID, lineCount := scanChatMetadata(file)
-			timestamp := getChatTimestamp(file)
+// scanChatFile reads a listed transcript into a Chat.
+func scanChatFile(f chatFile) Chat {
+	uuid := strings.TrimSuffix(filepath.Base(f.path), ".jsonl")
+	meta := scanChatMetadata(f.path)
+	lastActivity := meta.LastActivity
+	if lastActivity.IsZero() {
+		lastActivity = getChatModTime(f.path)
+	}
 
-			// TODO: Get messageCount from index if available
-			// msgCount := messageCountMap[uuid]
+	// The index's count where it lists the chat, else our own
+	msgCount := f.messages
+	if msgCount < 0 {
+		msgCount = meta.Messages
+	}
 
-			chats = append(chats, Chat{
-				UUID:      uuid,
-				Title:     title,
-				Timestamp: timestamp,
-				Project:   entry.Name(),
-				Version:   version,
-				// MessageCount: msgCount,
-				LineCount:    lineCount,
-				Path:         file,
-				ForkParentID: forkParentID,
-			})
-		}
+	return Chat{
+		UUID:         uuid,
+		Title:        meta.Title,
+		Timestamp:    formatTimestamp(lastActivity),
+		Time:         lastActivity,
+		Project:      f.project,
+		ProjectPath:  resolveProjectPath(f.root, f.project),
+		Version:      meta.Version,
+		MessageCount: msgCount,
+		LineCount:    meta.LineCount,
+		Path:         f.path,
+		PlanPath:     planPath(f.root, meta.Slug),
+		ForkParentID: meta.ForkParentID,
+		Root:         f.root,
+		Problem:      meta.Problem,
+		Empty:        meta.Empty,
+		GitBranch:    meta.GitBranch,
+		Compacted:    meta.Compacted,
 	}
+}
 
-	// Sort by timestamp (newest first)
-	sort.Slice(chats, func(i, j int) bool {
-		return chats[i].Timestamp > chats[j].Timestamp
-	})
+// projectPathCache maps project directories to their decoded real paths. The
+// mapping never changes for a given directory, so it survives refreshes.
+var (
+	projectPathCache   = make(map[string]string)
+	projectPathCacheMu sync.Mutex
+)
 
-	return chats
+// resolveProjectPath returns the real filesystem path the project directory
+// name of root stands for. sessions-index.json records it exactly when
+// present; otherwise the encoded name is decoded against the filesystem (see
+// decodeProjectName).
+func resolveProjectPath(root claudeRoot, name string) string {
+	dir := filepath.Join(root.projects, name)
+	projectPathCacheMu.Lock()
+	defer projectPathCacheMu.Unlock()
+	if path, ok := projectPathCache[dir]; ok {
+		return path
+	}
+
+	path := ""
+	indexPath := filepath.Join(dir, "sessions-index.json")
+	if data, err := os.ReadFile(indexPath); err == nil {
+		var index SessionsIndex
+		if json.Unmarshal(data, &index) == nil {
+			path = index.OriginalPath
+			for _, e := range index.Entries {
+				if path != "" {
+					break
+				}
+				path = e.ProjectPath
+			}
+		}
+	}
+	if path == "" {
+		path = decodeProjectName(name)
+	}
+	projectPathCache[dir] = path
+	return path
 }
 
-func cleanSystemTags(content string) string {
-	// Remove content within system tags (including the tags themselves)
-	systemTagPairs := [][2]string{
-		{"<local-command-caveat>", "</local-command-caveat>"},
-		{"<command-name>", "</command-name>"},
-		{"<command-message>", "</command-message>"},
-		{"<command-args>", "</command-args>"},
-		{"<local-command-stdout>", "</local-command-stdout>"},
-		{"<system-reminder>", "</system-reminder>"},
+// decodeProjectName reverses Claude's directory encoding, which replaces "/"
+// and "." with "-" ("/home/me/my-app/.x" -> "-home-me-my-app--x"). The
+// encoding is lossy, so existing directories decide where the separators
+// were; if nothing matches, every dash is taken as a separator.
+func decodeProjectName(name string) string {
+	parts := strings.Split(strings.TrimPrefix(name, "-"), "-")
+	if path := probeProjectPath(string(filepath.Separator), parts); path != "" {
+		return path
 	}
+	return string(filepath.Separator) + strings.Join(parts, string(filepath.Separator))
+}
 
-	cleaned := content
-	for _, pair := range systemTagPairs {
-		start := strings.Index(cleaned, pair[0])
-		for start >= 0 {
-			end := strings.Index(cleaned[start:], pair[1])
-			if end >= 0 {
-				end += start + len(pair[1])
-				cleaned = cleaned[:start] + cleaned[end:]
-			} else {
-				// No closing tag, remove from start tag onwards
-				cleaned = cleaned[:start]
-				break
+// probeProjectPath finds an existing directory under dir whose components,
+// joined by dashes, spell parts. Longer components are tried first so
+// "my-app" wins over "my/app" when both exist. An empty part comes from a
+// dot ("--x" is "/.x"). Returns "" when no such directory exists.
+func probeProjectPath(dir string, parts []string) string {
+	if len(parts) == 0 {
+		return dir
+	}
+	for n := len(parts); n >= 1; n-- {
+		component := strings.Join(parts[:n], "-")
+		if parts[0] == "" {
+			if n == 1 {
+				continue
 			}
-			start = strings.Index(cleaned, pair[0])
+			component = "." + strings.Join(parts[1:n], "-")
+		}
+		candidate := filepath.Join(dir, component)
+		if info, err := os.Stat(candidate); err != nil || !info.IsDir() {
+			continue
+		}
+		if path := probeProjectPath(candidate, parts[n:]); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// describeScanError turns an error reading the projects directory dir into
+// an actionable message, with a fix hint for the common permission case.
+func describeScanError(dir string, err error) error {
+	if os.IsPermission(err) {
+		return fmt.Errorf("permission denied reading %s; fix with: chmod u+rx %q (or check the directory owner)", dir, dir)
+	}
+	return fmt.Errorf("cannot read %s: %w", dir, err)
+}
+
+// markProtected flags chats whose UUID matches any of the exclude patterns.
+func markProtected(chats []Chat, patterns []string) {
+	for i := range chats {
+		chats[i].Protected = isProtectedUUID(chats[i].UUID, patterns)
+	}
+}
+
+// isProtectedUUID reports whether uuid matches one of the patterns, either
+// exactly or as a glob (e.g. "abc*").
+func isProtectedUUID(uuid string, patterns []string) bool {
+	for _, p := range patterns {
+		if p == uuid {
+			return true
+		}
+		if ok, _ := filepath.Match(p, uuid); ok {
+			return true
 		}
 	}
+	return false
+}
+
+// maxTitleBytes caps the text a title is derived from. A first message with
+// a pasted log or file can run to megabytes; no list shows more than a line
+// of it.
+const maxTitleBytes = 2048
+
+// cleanSystemTags strips system tags and flattens content to a single line
+// suitable for a title, from at most maxTitleBytes past its leading tags
+// (see titleText). Returns "" when nothing meaningful is left.
+func cleanSystemTags(content string) string {
+	cleaned := stripSystemTags(titleText(content))
 
 	// Trim whitespace and newlines
 	cleaned = strings.TrimSpace(cleaned)
@@ -130,8 +354,107 @@ func cleanSystemTags(content string) string {
 	return cleaned
 }
 
+// titleText cuts content down to what a title can come from before any
+// stripping, so a pasted blob is never scanned whole: the system tags it
+// opens with, which stripSystemTags removes entirely, and at most
+// maxTitleBytes after them, cut on a rune boundary.
+func titleText(content string) string {
+	start := 0
+	for skipped := true; skipped; {
+		skipped = false
+		rest := strings.TrimLeft(content[start:], " \t\r\n")
+		for _, pair := range systemTagPairs {
+			if !strings.HasPrefix(rest, pair[0]) {
+				continue
+			}
+			end := strings.Index(rest, pair[1])
+			if end < 0 {
+				return content // unclosed: stripping removes the rest anyway
+			}
+			start = len(content) - len(rest) + end + len(pair[1])
+			skipped = true
+			break
+		}
+	}
+	if len(content)-start <= maxTitleBytes {
+		return content
+	}
+	cut := start + maxTitleBytes
+	for cut > start && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return content[:cut]
+}
+
+// systemTagPairs are the opening and closing tags stripSystemTags removes.
+var systemTagPairs = [][2]string{
+	{"<local-command-caveat>", "</local-command-caveat>"},
+	{"<command-name>", "</command-name>"},
+	{"<command-message>", "</command-message>"},
+	{"<command-args>", "</command-args>"},
+	{"<local-command-stdout>", "</local-command-stdout>"},
+	{"<system-reminder>", "</system-reminder>"},
+}
+
+// stripSystemTags removes system tags (and their content) but otherwise keeps
+// the text as-is, newlines included.
+func stripSystemTags(content string) string {
+	// Remove content within system tags (including the tags themselves)
+	cleaned := content
+	for _, pair := range systemTagPairs {
+		start := strings.Index(cleaned, pair[0])
+		for start >= 0 {
+			end := strings.Index(cleaned[start:], pair[1])
+			if end >= 0 {
+				end += start + len(pair[1])
+				cleaned = cleaned[:start] + cleaned[end:]
+			} else {
+				// No closing tag, remove from start tag onwards
+				cleaned = cleaned[:start]
+				break
+			}
+			start = strings.Index(cleaned, pair[0])
+		}
+	}
+	return cleaned
+}
+
+// chatMetadata is the display metadata scanChatMetadata extracts from a chat.
+type chatMetadata struct {
+	Title        string
+	Version      string
+	ForkParentID string
+	Slug         string // first slug seen; names the plan file
+	LineCount    int
+	LastActivity time.Time // latest record timestamp; zero if none parse
+	Problem      string    // why the file couldn't be read fully; empty if it could
+	Empty        bool      // readable, but without a single user or assistant message
+	GitBranch    string    // branch of the last record that names one
+	Messages     int       // user and assistant records, meta ones aside
+	Compacted    bool      // has a summary record, as /compact leaves
+}
+
+// isMessageRecord reports whether a line that doesn't decode into
+// JSONLMessage is a user or assistant record, reading just its type and
+// isMeta. A line whose type can't be read either counts as a message, so an
+// unfamiliar record never makes a chat look empty.
+func isMessageRecord(line []byte) bool {
+	var rec struct {
+		Type   any `json:"type"`
+		IsMeta any `json:"isMeta"`
+	}
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return true
+	}
+	typ, ok := rec.Type.(string)
+	if !ok {
+		return true
+	}
+	return (typ == "user" || typ == "assistant") && rec.IsMeta != true
+}
+
 // scanChatMetadata reads a chat JSONL file in a single pass and extracts
-// display metadata (title, version, line count). Title priority matches the
+// display metadata (title, version, line count, latest record timestamp). Title priority matches the
 // Claude Code --resume picker: customTitle (/rename) > aiTitle (auto-generated,
 // v2.1.x) > first user message > summary fallback. Replaces three separate file
 // scans.
@@ -139,10 +462,12 @@ func cleanSystemTags(content string) string {
 // Scans the full file without an early exit: late /rename and ai-title records
 // can appear at any line and lineCount needs the whole file, so any bail-out cap
 // would silently break title detection on long sessions.
-func scanChatMetadata(jsonlFile string) (title, version, forkParentID string, lineCount int) {
+func scanChatMetadata(jsonlFile string) (meta chatMetadata) {
 	file, err := os.Open(jsonlFile)
 	if err != nil {
-		return "[Error opening file]", "", "", 0
+		meta.Title = "[Error opening file]"
+		meta.Problem = readProblem(err)
+		return meta
 	}
 	defer file.Close()
 
@@ -150,25 +475,60 @@ func scanChatMetadata(jsonlFile string) (title, version, forkParentID string, li
 	buf := make([]byte, 1024*1024) // 1MB buffer for large JSONL lines
 	scanner.Buffer(buf, len(buf))
 
+	// Lines that aren't JSON at all; a bad last line means an interrupted write.
+	corrupt, lastCorrupt := 0, false
+
 	// Titles use last-wins (rewritten over the session, keep the latest);
 	// firstUserMsg/firstSummary use first-wins (guarded by == "", keep the
 	// earliest). Don't mix the two strategies up when editing the loop below.
 	var firstUserMsg, firstSummary, lastCustomTitle, lastAiTitle string
+	var firstAssistantMsg string // only collected with assistantTitleBelow set
+	messages := 0                // user and assistant records, meta ones aside
 
 	for scanner.Scan() {
-		lineCount++
+		meta.LineCount++
 
 		var msg JSONLMessage
 		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			lastCorrupt = errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF)
+			if lastCorrupt {
+				corrupt++
+			} else if isMessageRecord(scanner.Bytes()) {
+				// Valid JSON with a field of an unexpected type is still a
+				// message; skipping it would make a real chat look empty.
+				messages++
+			}
 			continue
 		}
+		lastCorrupt = false
+
+		if meta.Version == "" && msg.Version != "" {
+			meta.Version = msg.Version
+		}
+
+		if meta.Slug == "" && msg.Slug != "" {
+			meta.Slug = msg.Slug
+		}
+
+		if msg.GitBranch != "" {
+			meta.GitBranch = msg.GitBranch
+		}
 
-		if version == "" && msg.Version != "" {
-			version = msg.Version
+		if meta.ForkParentID == "" && msg.ForkedFrom.SessionID != "" {
+			meta.ForkParentID = msg.ForkedFrom.SessionID
 		}
 
-		if forkParentID == "" && msg.ForkedFrom.SessionID != "" {
-			forkParentID = msg.ForkedFrom.SessionID
+		// Records are appended over time, but take the max rather than the
+		// last one in case a writer ever emits them out of order.
+		if msg.Timestamp != "" {
+			if t, err := time.Parse(time.RFC3339Nano, msg.Timestamp); err == nil && t.After(meta.LastActivity) {
+				meta.LastActivity = t
+			}
+		}
+
+		if (msg.Type == "user" || msg.Type == "assistant") && !msg.IsMeta {
+			messages++
 		}
 
 		// /rename writes a dedicated record; last one wins.
@@ -183,51 +543,115 @@ func scanChatMetadata(jsonlFile string) (title, version, forkParentID string, li
 			continue
 		}
 
-		if firstSummary == "" && msg.Type == "summary" && msg.Summary != "" {
-			firstSummary = msg.Summary
+		if msg.Type == "summary" && msg.Summary != "" {
+			meta.Compacted = true
+			if firstSummary == "" {
+				firstSummary = msg.Summary
+			}
 			continue
 		}
 
 		if firstUserMsg == "" && msg.Type == "user" && !msg.IsMeta {
-			if c := cleanSystemTags(msg.Message.Content); c != "" {
+			if c := cleanSystemTags(string(msg.Message.Content)); c != "" {
 				firstUserMsg = c
 			}
 		}
+
+		if assistantTitleBelow > 0 && firstAssistantMsg == "" && msg.Type == "assistant" {
+			firstAssistantMsg = cleanSystemTags(string(msg.Message.Content))
+		}
+	}
+
+	switch {
+	case scanner.Err() != nil:
+		meta.Problem = fmt.Sprintf("read stopped at line %d: %s", meta.LineCount+1, readProblem(scanner.Err()))
+	case lastCorrupt && corrupt == 1:
+		meta.Problem = "truncated: last line is incomplete"
+	case lastCorrupt:
+		meta.Problem = fmt.Sprintf("truncated, %d corrupt lines", corrupt)
+	case corrupt > 0:
+		meta.Problem = fmt.Sprintf("%d corrupt line(s)", corrupt)
+	}
+	// Sessions that were opened and left (often just a file-history-snapshot)
+	// are told apart from chats whose title merely couldn't be found.
+	meta.Messages = messages
+	meta.Empty = messages == 0 && meta.Problem == ""
+
+	// A terse (or missing) first prompt gives way to the first reply.
+	if firstAssistantMsg != "" && len([]rune(firstUserMsg)) < assistantTitleBelow {
+		firstUserMsg = firstAssistantMsg
 	}
 
 	switch {
 	case lastCustomTitle != "":
-		title = lastCustomTitle
+		meta.Title = lastCustomTitle
 	case lastAiTitle != "":
-		title = lastAiTitle
+		meta.Title = lastAiTitle
 	case firstUserMsg != "":
-		title = firstUserMsg
+		meta.Title = firstUserMsg
 	case firstSummary != "":
-		title = firstSummary
+		meta.Title = firstSummary
+	case meta.Empty:
+		meta.Title = "[empty session]"
 	default:
-		title = "[No title]"
+		meta.Title = "[No title]"
 	}
-	return
+	return meta
+}
+
+// readProblem describes a read failure in a few words for the chat list.
+func readProblem(err error) string {
+	switch {
+	case errors.Is(err, os.ErrPermission):
+		return "permission denied"
+	case errors.Is(err, bufio.ErrTooLong):
+		return "line longer than 1MB"
+	}
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err.Error()
+	}
+	return err.Error()
 }
 
 // getChatTitle returns just the title. Retained for test compatibility.
 func getChatTitle(jsonlFile string) string {
-	title, _, _, _ := scanChatMetadata(jsonlFile)
-	return title
+	return scanChatMetadata(jsonlFile).Title
 }
 
 // getChatVersion returns just the version. Retained for test compatibility.
 func getChatVersion(jsonlFile string) string {
-	_, version, _, _ := scanChatMetadata(jsonlFile)
-	return version
+	return scanChatMetadata(jsonlFile).Version
 }
 
-func getChatTimestamp(jsonlFile string) string {
+// getChatModTime returns the file's modification time, the fallback activity
+// time for chats whose records carry no timestamps.
+func getChatModTime(jsonlFile string) time.Time {
 	info, err := os.Stat(jsonlFile)
 	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// formatTimestamp renders a chat time for display in local time.
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
 		return "Unknown"
 	}
-	return info.ModTime().Format("2006-01-02 15:04:05")
+	return t.Local().Format(dateFormat)
+}
+
+// planPath returns the plan file for slug in root, or "" when there is none.
+func planPath(root claudeRoot, slug string) string {
+	if slug == "" {
+		return ""
+	}
+	path := filepath.Join(root.plans, slug+".md")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
 }
 
 func getSlugFromChat(jsonlFile string) string {
@@ -255,21 +679,24 @@ func getSlugFromChat(jsonlFile string) string {
 	return ""
 }
 
-// isSlugUsedInOtherChats checks whether slug is still referenced by chats other
-// than the one currently being deleted.
-func isSlugUsedInOtherChats(slug string, excludeUUID string) bool {
+// isSlugUsedInOtherChats checks whether slug is still referenced by chats of
+// root other than the one currently being deleted. The excluded chat is
+// identified by UUID and project; an empty project excludes that UUID in
+// every project.
+func isSlugUsedInOtherChats(root claudeRoot, slug string, excludeUUID, excludeProject string) bool {
 	if slug == "" {
 		return false
 	}
 
-	matches, err := filepath.Glob(filepath.Join(projectsDir, "*", "*.jsonl"))
+	matches, err := filepath.Glob(filepath.Join(root.projects, "*", "*.jsonl"))
 	if err != nil {
 		return true // safe default: keep plan file if we cannot verify
 	}
 
 	for _, path := range matches {
 		uuid := strings.TrimSuffix(filepath.Base(path), ".jsonl")
-		if uuid == excludeUUID {
+		project := filepath.Base(filepath.Dir(path))
+		if uuid == excludeUUID && (excludeProject == "" || project == excludeProject) {
 			continue
 		}
 		if getSlugFromChat(path) == slug {
@@ -280,9 +707,11 @@ func isSlugUsedInOtherChats(slug string, excludeUUID string) bool {
 	return false
 }
 
-func updateSessionsIndex(uuid string) error {
+// updateSessionsIndex drops uuid from the sessions index of project in root,
+// or from every project's index when project is empty.
+func updateSessionsIndex(root claudeRoot, uuid, project string) error {
 	// Find all sessions-index.json files in project directories
-	entries, err := os.ReadDir(projectsDir)
+	entries, err := os.ReadDir(root.projects)
 	if err != nil {
 		return err
 	}
@@ -291,8 +720,11 @@ func updateSessionsIndex(uuid string) error {
 		if !entry.IsDir() {
 			continue
 		}
+		if project != "" && entry.Name() != project {
+			continue
+		}
 
-		indexPath := filepath.Join(projectsDir, entry.Name(), "sessions-index.json")
+		indexPath := filepath.Join(root.projects, entry.Name(), "sessions-index.json")
 		if _, err := os.Stat(indexPath); os.IsNotExist(err) {
 			continue
 		}
@@ -336,74 +768,178 @@ func updateSessionsIndex(uuid string) error {
 	return nil
 }
 
-func findRelatedFiles(uuid string) []string {
+// staleIndexEntry is a sessions-index.json entry whose chat file is gone.
+type staleIndexEntry struct {
+	Root      claudeRoot
+	Project   string
+	SessionID string
+}
+
+// findStaleIndexEntries lists the index entries in root that point at no
+// chat file: neither the entry's fullPath nor <project>/<id>.jsonl exists.
+// Unreadable indexes are skipped.
+func findStaleIndexEntries(root claudeRoot) []staleIndexEntry {
+	var stale []staleIndexEntry
+	entries, err := os.ReadDir(root.projects)
+	if err != nil {
+		return nil
+	}
+	for _, dir := range entries {
+		if !dir.IsDir() || (projectScope != "" && dir.Name() != projectScope) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(root.projects, dir.Name(), "sessions-index.json"))
+		if err != nil {
+			continue
+		}
+		var index SessionsIndex
+		if err := json.Unmarshal(data, &index); err != nil {
+			continue
+		}
+		for _, entry := range index.Entries {
+			local := filepath.Join(root.projects, dir.Name(), entry.SessionID+".jsonl")
+			if fileExists(local) || (entry.FullPath != "" && fileExists(entry.FullPath)) {
+				continue
+			}
+			stale = append(stale, staleIndexEntry{Root: root, Project: dir.Name(), SessionID: entry.SessionID})
+		}
+	}
+	return stale
+}
+
+// removeIndexEntries drops the given session IDs from the sessions-index.json
+// of project in root, replacing the file atomically so Claude never reads a
+// half-written index.
+func removeIndexEntries(root claudeRoot, project string, ids map[string]bool) (removed int, err error) {
+	indexPath := filepath.Join(root.projects, project, "sessions-index.json")
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return 0, err
+	}
+	var index SessionsIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return 0, err
+	}
+	kept := index.Entries[:0]
+	for _, entry := range index.Entries {
+		if ids[entry.SessionID] {
+			removed++
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	index.Entries = kept
+	data, err = json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	return removed, writeFileAtomic(indexPath, data, 0644)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// uuidInOtherProject reports whether a chat with the same UUID exists in a
+// project of root other than the given one (e.g. a cloned session directory).
+func uuidInOtherProject(root claudeRoot, uuid, project string) bool {
+	matches, _ := filepath.Glob(filepath.Join(root.projects, "*", uuid+".jsonl"))
+	for _, m := range matches {
+		if filepath.Base(filepath.Dir(m)) != project {
+			return true
+		}
+	}
+	return false
+}
+
+// findRelatedFiles resolves every on-disk artifact of the chat uuid in
+// project of root. An empty project matches the UUID in all projects.
+func findRelatedFiles(root claudeRoot, uuid, project string) []string {
+	return findRelatedFilesWith(root, uuid, project, func(slug string) bool {
+		return isSlugUsedInOtherChats(root, slug, uuid, project)
+	})
+}
+
+// findRelatedFilesWith is findRelatedFiles with the check whether another
+// chat still uses the plan of slug supplied, so a caller that already knows
+// every chat's slug doesn't read all transcripts again for each chat.
+func findRelatedFilesWith(root claudeRoot, uuid, project string, slugInUse func(slug string) bool) []string {
 	var files []string
+	for _, path := range relatedPaths(root, uuid, project, slugInUse) {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// relatedPaths lists every path findRelatedFilesWith looks at for the chat,
+// whether it exists or not, so -files can show what is missing too. Todo
+// files are matched by pattern; without a match the pattern itself is listed.
+func relatedPaths(root claudeRoot, uuid, project string, slugInUse func(slug string) bool) []string {
+	var paths []string
 	var chatJSONLPath string
 
 	// Main JSONL file and subagents directory
-	matches, _ := filepath.Glob(filepath.Join(projectsDir, "*", uuid+".jsonl"))
+	matches := []string{filepath.Join(root.projects, project, uuid+".jsonl")}
+	if project == "" {
+		matches, _ = filepath.Glob(filepath.Join(root.projects, "*", uuid+".jsonl"))
+	}
 	for _, m := range matches {
-		files = append(files, m)
-		chatJSONLPath = m // Save for slug extraction
+		paths = append(paths, m)
+		if fileExists(m) {
+			chatJSONLPath = m // Save for slug extraction
+		}
 
 		// Subagents directory (same name as jsonl but without extension)
-		subagentsDir := strings.TrimSuffix(m, ".jsonl")
-		if _, err := os.Stat(subagentsDir); err == nil {
-			files = append(files, subagentsDir)
-		}
+		chatDir := strings.TrimSuffix(m, ".jsonl")
+		paths = append(paths, chatDir)
 
 		// Tool results directory (within chat directory)
-		chatDir := strings.TrimSuffix(m, ".jsonl")
-		toolResultsDir := filepath.Join(chatDir, "tool-results")
-		if _, err := os.Stat(toolResultsDir); err == nil {
-			files = append(files, toolResultsDir)
-		}
+		paths = append(paths, filepath.Join(chatDir, "tool-results"))
 	}
 
 	// Plan file (via slug)
 	if chatJSONLPath != "" {
 		slug := getSlugFromChat(chatJSONLPath)
-		if slug != "" && !isSlugUsedInOtherChats(slug, uuid) {
-			planFile := filepath.Join(plansDir, slug+".md")
-			if _, err := os.Stat(planFile); err == nil {
-				files = append(files, planFile)
-			}
+		if slug != "" && !slugInUse(slug) {
+			paths = append(paths, filepath.Join(root.plans, slug+".md"))
 		}
 	}
 
-	// Debug file
-	debugFile := filepath.Join(debugDir, uuid+".txt")
-	if _, err := os.Stat(debugFile); err == nil {
-		files = append(files, debugFile)
+	// Everything below is keyed by UUID alone. When the same UUID lives on in
+	// another project, that copy still owns these artifacts, so keep them.
+	if project != "" && uuidInOtherProject(root, uuid, project) {
+		return paths
 	}
 
-	// Session-scoped security warning dedupe state (security-guidance hook)
-	securityWarningsStateFile := filepath.Join(claudeDir, "security_warnings_state_"+uuid+".json")
-	if _, err := os.Stat(securityWarningsStateFile); err == nil {
-		files = append(files, securityWarningsStateFile)
-	}
+	paths = append(paths,
+		// Debug file
+		filepath.Join(root.debug, uuid+".txt"),
+		// Session-scoped security warning dedupe state (security-guidance hook)
+		filepath.Join(root.dir, "security_warnings_state_"+uuid+".json"),
+	)
 
 	// Todo files
-	todoMatches, _ := filepath.Glob(filepath.Join(todosDir, uuid+"*.json"))
-	files = append(files, todoMatches...)
-
-	// Session directory
-	sessionPath := filepath.Join(sessionDir, uuid)
-	if _, err := os.Stat(sessionPath); err == nil {
-		files = append(files, sessionPath)
+	todoPattern := filepath.Join(root.todos, uuid+"*.json")
+	if todoMatches, _ := filepath.Glob(todoPattern); len(todoMatches) > 0 {
+		paths = append(paths, todoMatches...)
+	} else {
+		paths = append(paths, todoPattern)
 	}
 
-	// Task state directory
-	tasksPath := filepath.Join(tasksDir, uuid)
-	if _, err := os.Stat(tasksPath); err == nil {
-		files = append(files, tasksPath)
-	}
-
-	// File history directory
-	fileHistoryPath := filepath.Join(fileHistoryDir, uuid)
-	if _, err := os.Stat(fileHistoryPath); err == nil {
-		files = append(files, fileHistoryPath)
-	}
+	paths = append(paths,
+		// Session directory
+		filepath.Join(root.sessionEnv, uuid),
+		// Task state directory
+		filepath.Join(root.tasks, uuid),
+		// File history directory
+		filepath.Join(root.fileHistory, uuid),
+	)
 
 	// Agent memory files (v2.1.33+)
 	// Parse agent IDs from chat JSONL and delete local scope memory
@@ -418,10 +954,7 @@ func findRelatedFiles(uuid string) []string {
 		agentIDs := parseAgentIDs(chatJSONLPath)
 		for _, agentID := range agentIDs {
 			// Delete local scope memory (always tied to this chat session)
-			localMemory := filepath.Join(agentsDir, agentID, "memory-local.md")
-			if _, err := os.Stat(localMemory); err == nil {
-				files = append(files, localMemory)
-			}
+			paths = append(paths, filepath.Join(root.agents, agentID, "memory-local.md"))
 
 			// Note: We don't delete memory-project.md or memory-user.md as they may be
 			// shared across multiple chats. Consider implementing reference counting
@@ -429,7 +962,7 @@ func findRelatedFiles(uuid string) []string {
 		}
 	}
 
-	return files
+	return paths
 }
 
 // parseAgentIDs extracts agent IDs from chat JSONL file
@@ -461,21 +994,424 @@ func parseAgentIDs(chatFile string) []string {
 	return agentIDs
 }
 
-// deleteChats deletes all files related to the given chats and updates sessions index.
-// Returns count of deleted chats or an error.
-func deleteChats(chats []Chat) (int, error) {
-	count := 0
+// deleteResult tallies the outcome of a deletion batch.
+type deleteResult struct {
+	Deleted    int   `json:"deleted"`
+	Failed     int   `json:"failed"`
+	FreedBytes int64 `json:"freed_bytes"`
+
+	removed []string         // every path deleteChats removed, for verifyDeletion
+	shared  []string         // shared files left in place for other chats (see noteShared)
+	records []deletionRecord // one per chat done, for the deletion log
+}
+
+// record adds chat to res.records with the paths and bytes res gained since
+// removed and freed were taken, i.e. the chat's own.
+func (res *deleteResult) record(chat Chat, removed int, freed int64, batch string) {
+	res.records = append(res.records, deletionRecord{
+		Time:       time.Now(),
+		UUID:       chat.UUID,
+		Title:      chat.Title,
+		Project:    chat.Project,
+		Files:      slices.Clone(res.removed[removed:]),
+		FreedBytes: res.FreedBytes - freed,
+		TrashBatch: batch,
+	})
+}
+
+// noteShared records the shared files that deleting chat leaves in place:
+// its plan file when another chat still uses the slug (so files, resolved for
+// the deletion, lacks it) and the project and user scope memory of its
+// agents. It reads the chat, so call it before the chat's files are removed.
+func (res *deleteResult) noteShared(chat Chat, files []string) {
+	transcript := chat.Path
+	for _, file := range files {
+		if transcript == "" && filepath.Base(file) == chat.UUID+".jsonl" {
+			transcript = file
+		}
+	}
+	if transcript == "" {
+		return
+	}
+	root := chat.root()
+	if plan := planPath(root, getSlugFromChat(transcript)); plan != "" && !slices.Contains(files, plan) {
+		res.shared = append(res.shared, plan)
+	}
+	for _, agentID := range parseAgentIDs(transcript) {
+		for _, name := range []string{"memory-project.md", "memory-user.md"} {
+			memory := filepath.Join(root.agents, agentID, name)
+			if _, err := os.Stat(memory); err == nil {
+				res.shared = append(res.shared, memory)
+			}
+		}
+	}
+}
+
+// sharedNote says which shared files the batch kept because other chats still
+// use them, e.g. "kept 2 shared plan(s) still in use"; empty if none. A plan
+// removed after all with the last chat of the batch using it is not counted.
+func (res deleteResult) sharedNote() string {
+	plans, memories := 0, 0
+	seen := make(map[string]bool)
+	for _, file := range res.shared {
+		if seen[file] {
+			continue
+		}
+		seen[file] = true
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		if strings.HasPrefix(filepath.Base(file), "memory-") {
+			memories++
+		} else {
+			plans++
+		}
+	}
+	var kept []string
+	if plans > 0 {
+		kept = append(kept, fmt.Sprintf("%d shared plan(s)", plans))
+	}
+	if memories > 0 {
+		kept = append(kept, fmt.Sprintf("%d agent memory file(s)", memories))
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+	return "kept " + strings.Join(kept, " and ") + " still in use"
+}
+
+// preflightReport sums up what a deletion batch does to the files chats
+// share, for the confirmation of big batches (see preflightDeletion).
+type preflightReport struct {
+	Remove   int // files and directories the batch removes
+	Shared   int // shared plans and agent memory kept for chats outside the batch
+	Orphaned int // agent memory kept although no chat outside the batch uses it
+}
+
+// preflightDeletion works out, before deleting batch, which shared files it
+// takes along and which it leaves: a plan goes with the last chat using it,
+// while project and user scope agent memory is never deleted, so memory whose
+// agents only the batch refers to is left orphaned. Chats outside the batch
+// are every transcript on disk, hidden and protected ones included; each is
+// read once.
+func preflightDeletion(batch []Chat) preflightReport {
+	var rep preflightReport
+	for _, root := range scanRoots() {
+		var chats []Chat
+		for _, chat := range batch {
+			if chatInRoot(chat, root) {
+				chats = append(chats, chat)
+			}
+		}
+		if len(chats) > 0 {
+			rep.add(preflightRoot(root, chats))
+		}
+	}
+	return rep
+}
+
+func (rep *preflightReport) add(o preflightReport) {
+	rep.Remove += o.Remove
+	rep.Shared += o.Shared
+	rep.Orphaned += o.Orphaned
+}
+
+// preflightRoot is preflightDeletion for the chats of one Claude directory.
+func preflightRoot(root claudeRoot, chats []Chat) preflightReport {
+	inBatch := make(map[string]bool)
 	for _, chat := range chats {
-		files := findRelatedFiles(chat.UUID)
-		for _, file := range files {
-			if err := os.RemoveAll(file); err != nil {
-				return 0, fmt.Errorf("failed to delete %s: %w", file, err)
+		inBatch[chat.Project+"/"+chat.UUID] = true
+	}
+
+	// What the chats staying behind still use
+	slugsInUse := make(map[string]bool)
+	agentsInUse := make(map[string]bool)
+	matches, _ := filepath.Glob(filepath.Join(root.projects, "*", "*.jsonl"))
+	for _, path := range matches {
+		key := filepath.Base(filepath.Dir(path)) + "/" + strings.TrimSuffix(filepath.Base(path), ".jsonl")
+		if inBatch[key] {
+			continue
+		}
+		slug, agents := chatRefs(path)
+		slugsInUse[slug] = true
+		for _, agent := range agents {
+			agentsInUse[agent] = true
+		}
+	}
+
+	var rep preflightReport
+	seen := make(map[string]bool)
+	for _, chat := range chats {
+		keepPlan := func(string) bool { return true } // plans are counted below, once
+		rep.Remove += len(findRelatedFilesWith(root, chat.UUID, chat.Project, keepPlan))
+
+		transcript := chat.Path
+		if transcript == "" {
+			transcript = filepath.Join(root.projects, chat.Project, chat.UUID+".jsonl")
+		}
+		slug, agents := chatRefs(transcript)
+		if plan := planPath(root, slug); plan != "" && !seen[plan] {
+			seen[plan] = true
+			if slugsInUse[slug] {
+				rep.Shared++
+			} else {
+				rep.Remove++
+			}
+		}
+		for _, agent := range agents {
+			for _, name := range []string{"memory-project.md", "memory-user.md"} {
+				memory := filepath.Join(root.agents, agent, name)
+				if seen[memory] || !fileExists(memory) {
+					continue
+				}
+				seen[memory] = true
+				if agentsInUse[agent] {
+					rep.Shared++
+				} else {
+					rep.Orphaned++
+				}
+			}
+		}
+	}
+	return rep
+}
+
+// chatRefs reads the shared things a transcript refers to: the slug naming
+// its plan and the IDs of its agents, in one pass (see getSlugFromChat and
+// parseAgentIDs).
+func chatRefs(path string) (slug string, agents []string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", nil
+	}
+	defer file.Close()
+
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		var msg struct {
+			Slug    string `json:"slug"`
+			AgentID string `json:"agentId"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &msg) != nil {
+			continue
+		}
+		if slug == "" {
+			slug = msg.Slug
+		}
+		if msg.AgentID != "" && !seen[msg.AgentID] {
+			seen[msg.AgentID] = true
+			agents = append(agents, msg.AgentID)
+		}
+	}
+	return slug, agents
+}
+
+// summary is the report as one line for the confirmation, e.g. "40 file(s)
+// to remove, 2 shared kept for other chats, 1 orphaned agent memory file(s)".
+func (rep preflightReport) summary() string {
+	parts := []string{fmt.Sprintf("%d file(s) to remove", rep.Remove)}
+	if rep.Shared > 0 {
+		parts = append(parts, fmt.Sprintf("%d shared kept for other chats", rep.Shared))
+	}
+	if rep.Orphaned > 0 {
+		parts = append(parts, fmt.Sprintf("%d orphaned agent memory file(s) left behind", rep.Orphaned))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// removeProjectDirIfEmpty removes projects/<project> of root once its chats
+// are gone. The directory counts as empty when nothing but sessions-index.json
+// is left; anything else (project memory, chats hidden or protected from
+// deletion) keeps it in place and removed is false.
+func removeProjectDirIfEmpty(root claudeRoot, project string) (removed bool, err error) {
+	dir := filepath.Join(root.projects, project)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if entry.Name() != "sessions-index.json" {
+			return false, nil
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// verifyDeletion re-checks a finished batch: every removed path must be gone
+// and no sessions-index.json may still list a deleted chat. It returns a
+// description of each leftover. RemoveAll can succeed while Claude, running
+// concurrently, writes the file again; this catches that.
+func verifyDeletion(res deleteResult, chats []Chat) []string {
+	var leftovers []string
+	for _, path := range res.removed {
+		if _, err := os.Lstat(path); err == nil {
+			leftovers = append(leftovers, path)
+		}
+	}
+	for _, chat := range chats {
+		root := chat.root()
+		if findIndexEntry(root, chat.UUID, chat.Project) != nil {
+			indexPath := filepath.Join(root.projects, chat.Project, "sessions-index.json")
+			leftovers = append(leftovers, fmt.Sprintf("%s (entry %s)", indexPath, chat.UUID))
+		}
+	}
+	return leftovers
+}
+
+// findIndexEntry returns the sessions-index.json entry for uuid of project in
+// root, or nil when there is none.
+func findIndexEntry(root claudeRoot, uuid, project string) *SessionEntry {
+	data, err := os.ReadFile(filepath.Join(root.projects, project, "sessions-index.json"))
+	if err != nil {
+		return nil
+	}
+	var index SessionsIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil
+	}
+	for _, entry := range index.Entries {
+		if entry.SessionID == uuid {
+			return &entry
+		}
+	}
+	return nil
+}
+
+// pathSize returns the total size in bytes of a file or directory tree.
+// Unreadable entries are skipped rather than failing the whole walk.
+func pathSize(path string) int64 {
+	var total int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// chatSize is the on-disk footprint of a chat: every related file, walked
+// recursively. slugs counts the transcripts using each plan (see
+// slugCounts).
+func chatSize(chat Chat, slugs map[string]int) int64 {
+	files := findRelatedFilesWith(chat.root(), chat.UUID, chat.Project, func(slug string) bool {
+		return slugs[slug] > 1
+	})
+	var total int64
+	for _, file := range files {
+		total += pathSize(file)
+	}
+	return total
+}
+
+// slugCounts reads every transcript's slug once per Claude directory of
+// chats and counts the transcripts using each, keyed by the directory, so
+// sizing doesn't read all transcripts again for each chat to tell a shared
+// plan.
+func slugCounts(chats []Chat) map[string]map[string]int {
+	counts := make(map[string]map[string]int)
+	for _, chat := range chats {
+		root := chat.root()
+		if counts[root.dir] != nil {
+			continue
+		}
+		perSlug := make(map[string]int)
+		matches, _ := filepath.Glob(filepath.Join(root.projects, "*", "*.jsonl"))
+		for _, path := range matches {
+			if slug := getSlugFromChat(path); slug != "" {
+				perSlug[slug]++
 			}
 		}
-		if err := updateSessionsIndex(chat.UUID); err != nil {
-			return 0, fmt.Errorf("failed to update index: %w", err)
+		counts[root.dir] = perSlug
+	}
+	return counts
+}
+
+// deleteChats deletes all files related to the given chats and updates sessions index.
+// Stops at the first failure, or before the next chat once stop is set (nil
+// never stops); the result covers the chats processed so far.
+func deleteChats(chats []Chat, stop *atomic.Bool) (deleteResult, error) {
+	var res deleteResult
+	for _, chat := range chats {
+		if stop != nil && stop.Load() {
+			break
+		}
+		if err := deleteChat(chat, &res); err != nil {
+			res.Failed++
+			return res, err
+		}
+		res.Deleted++
+	}
+	return res, nil
+}
+
+// busyRetryDelays are the pauses between attempts at removing a file that
+// is busy, typically because Claude is still writing the session.
+var busyRetryDelays = []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}
+
+// errInUse marks a file that stayed busy through every retry.
+var errInUse = errors.New("the chat appears to be in use (is Claude still running it?)")
+
+// retryBusy runs op, removing or moving a file, again after each of
+// busyRetryDelays while it fails with a busy or locked error. Other errors
+// are returned at once; a file still busy at the end is reported as
+// errInUse.
+func retryBusy(op func() error) error {
+	err := op()
+	for _, delay := range busyRetryDelays {
+		if !isBusy(err) {
+			return err
+		}
+		time.Sleep(delay)
+		err = op()
+	}
+	if isBusy(err) {
+		return fmt.Errorf("%w: %v", errInUse, err)
+	}
+	return err
+}
+
+// isBusy reports whether err is a transient "file in use" failure: EBUSY or
+// ETXTBSY, and on Windows a sharing or lock violation.
+func isBusy(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// ERROR_SHARING_VIOLATION, ERROR_LOCK_VIOLATION
+		return errno == 32 || errno == 33
+	}
+	return errno == syscall.EBUSY || errno == syscall.ETXTBSY
+}
+
+// deleteChat removes one chat's files within its own Claude directory,
+// adding what it freed to res. Files already resolved into chat.Files (see
+// resolveRelatedFiles) are used as is.
+func deleteChat(chat Chat, res *deleteResult) error {
+	root := chat.root()
+	files := chat.Files
+	if files == nil {
+		files = findRelatedFiles(root, chat.UUID, chat.Project)
+	}
+	res.noteShared(chat, files)
+	removed, freed := len(res.removed), res.FreedBytes
+	for _, file := range files {
+		size := pathSize(file)
+		if err := retryBusy(func() error { return os.RemoveAll(file) }); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", file, err)
 		}
-		count++
+		res.FreedBytes += size
+		res.removed = append(res.removed, file)
 	}
-	return count, nil
+	if err := updateSessionsIndex(root, chat.UUID, chat.Project); err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+	res.record(chat, removed, freed, "")
+	return nil
 }
@@ -1,12 +1,38 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
+func TestCleanSystemTags_LongPaste(t *testing.T) {
+	// A pasted blob is cut before flattening, on a rune boundary
+	paste := "<system-reminder>" + strings.Repeat("r", 5000) + "</system-reminder>" +
+		"fix this:\n" + strings.Repeat("é ", 1<<20)
+	got := cleanSystemTags(paste)
+	if !strings.HasPrefix(got, "fix this: é é") || len(got) > maxTitleBytes || !utf8.ValidString(got) {
+		t.Errorf("cleanSystemTags(long paste) = %d bytes starting %.20q, want the prefix only", len(got), got)
+	}
+	// ... and before the tags are stripped, keeping the leading reminder whole
+	text := strings.Index(paste, "fix this")
+	if n := len(titleText(paste)); n > text+maxTitleBytes || n < text+maxTitleBytes-utf8.UTFMax {
+		t.Errorf("titleText(long paste) = %d bytes, want the reminder and %d bytes after it", n, maxTitleBytes)
+	}
+}
+
 func TestCleanSystemTags(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -97,7 +123,8 @@ func writeTempJSONL(t *testing.T, lines []string) string {
 }
 
 func TestGetChatTitle(t *testing.T) {
-	// line1 is always skipped (file-history-snapshot)
+	// Most sessions open with a snapshot record; it is skipped by its type,
+	// not its position, so sessions without one keep their first message.
 	const line1 = `{"type":"snapshot","content":"file-history"}`
 
 	tests := []struct {
@@ -113,6 +140,31 @@ func TestGetChatTitle(t *testing.T) {
 			},
 			want: "what is the meaning of life",
 		},
+		{
+			name: "first user message on line 1 without a snapshot",
+			lines: []string{
+				`{"type":"user","message":{"content":"no snapshot here"}}`,
+				`{"type":"user","message":{"content":"second message"}}`,
+			},
+			want: "no snapshot here",
+		},
+		{
+			name: "snapshot after the first message",
+			lines: []string{
+				`{"type":"user","message":{"content":"opening question"}}`,
+				`{"type":"file-history-snapshot","messageId":"m1"}`,
+			},
+			want: "opening question",
+		},
+		{
+			name: "joins text blocks of structured content",
+			lines: []string{
+				line1,
+				`{"type":"user","message":{"content":[{"type":"tool_result","content":"ok"}]}}`,
+				`{"type":"user","message":{"content":[{"type":"image"},{"type":"text","text":"why does"},{"type":"text","text":"this fail"}]}}`,
+			},
+			want: "why does this fail",
+		},
 		{
 			name: "skips meta messages",
 			lines: []string{
@@ -132,9 +184,17 @@ func TestGetChatTitle(t *testing.T) {
 			want: "this is the summary",
 		},
 		{
-			name: "returns [No title] when file has only line1",
+			name: "returns [empty session] when file has only line1",
+			lines: []string{
+				line1,
+			},
+			want: "[empty session]",
+		},
+		{
+			name: "message with a mistyped field is not an empty session",
 			lines: []string{
 				line1,
+				`{"type":"user","message":{"content":"hi"},"isMeta":"no"}`,
 			},
 			want: "[No title]",
 		},
@@ -273,6 +333,31 @@ func TestGetChatTitle(t *testing.T) {
 	}
 }
 
+func TestGetChatTitle_AssistantTitleBelow(t *testing.T) {
+	path := writeTempJSONL(t, []string{
+		`{"type":"user","message":{"content":"go"}}`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Read"},{"type":"text","text":"Refactoring the\nconfig loader"}]}}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Done."}]}}`,
+	})
+	if got := getChatTitle(path); got != "go" {
+		t.Errorf("default title = %q, want the user message", got)
+	}
+
+	assistantTitleBelow = 10
+	t.Cleanup(func() { assistantTitleBelow = 0 })
+	if got := getChatTitle(path); got != "Refactoring the config loader" {
+		t.Errorf("short prompt title = %q, want the first assistant reply", got)
+	}
+
+	long := writeTempJSONL(t, []string{
+		`{"type":"user","message":{"content":"explain the retry logic"}}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Sure"}]}}`,
+	})
+	if got := getChatTitle(long); got != "explain the retry logic" {
+		t.Errorf("long prompt title = %q, want the user message", got)
+	}
+}
+
 func TestScanChatMetadata(t *testing.T) {
 	// Locks in the 4-in-1 contract: title, version, forkParentID, lineCount all
 	// returned from a single call in one file pass.
@@ -282,19 +367,44 @@ func TestScanChatMetadata(t *testing.T) {
 		`{"type":"custom-title","customTitle":"renamed","sessionId":"x"}`,
 	}
 	path := writeTempJSONL(t, lines)
-	title, version, forkParentID, lineCount := scanChatMetadata(path)
+	meta := scanChatMetadata(path)
 
-	if title != "renamed" {
-		t.Errorf("title = %q, want %q", title, "renamed")
+	if meta.Title != "renamed" {
+		t.Errorf("title = %q, want %q", meta.Title, "renamed")
+	}
+	if meta.Version != "2.1.76" {
+		t.Errorf("version = %q, want %q", meta.Version, "2.1.76")
 	}
-	if version != "2.1.76" {
-		t.Errorf("version = %q, want %q", version, "2.1.76")
+	if meta.ForkParentID != "" {
+		t.Errorf("forkParentID = %q, want empty (no forkedFrom in input)", meta.ForkParentID)
 	}
-	if forkParentID != "" {
-		t.Errorf("forkParentID = %q, want empty (no forkedFrom in input)", forkParentID)
+	if meta.LineCount != 3 {
+		t.Errorf("lineCount = %d, want 3", meta.LineCount)
 	}
-	if lineCount != 3 {
-		t.Errorf("lineCount = %d, want 3", lineCount)
+	if meta.Empty {
+		t.Error("a chat with a user message is not empty")
+	}
+	if meta.Compacted {
+		t.Error("a chat without a summary record is not compacted")
+	}
+	compacted := scanChatMetadata(writeTempJSONL(t, []string{
+		`{"type":"user","message":{"content":"long task"}}`,
+		`{"type":"summary","summary":"what happened so far","leafUuid":"abc"}`,
+	}))
+	if !compacted.Compacted || compacted.Title != "long task" {
+		t.Errorf("summary record: compacted=%v title=%q, want compacted with the user's title", compacted.Compacted, compacted.Title)
+	}
+	branched := scanChatMetadata(writeTempJSONL(t, []string{
+		`{"type":"user","message":{"content":"hi"},"gitBranch":"main"}`,
+		`{"type":"assistant","message":{"content":"ok"},"gitBranch":"feature"}`,
+		`{"type":"custom-title","customTitle":"named","sessionId":"x"}`,
+	}))
+	if branched.GitBranch != "feature" {
+		t.Errorf("gitBranch = %q, want the last branch named, %q", branched.GitBranch, "feature")
+	}
+	empty := scanChatMetadata(writeTempJSONL(t, []string{`{"type":"file-history-snapshot"}`, `{"type":"custom-title","customTitle":"named","sessionId":"x"}`}))
+	if !empty.Empty || empty.Title != "named" {
+		t.Errorf("snapshot-only chat: empty=%v title=%q, want empty with its custom title", empty.Empty, empty.Title)
 	}
 }
 
@@ -309,10 +419,41 @@ func TestScanChatMetadata_ForkParentID(t *testing.T) {
 		`{"type":"user","message":{"content":"new message in fork"}}`,
 	}
 	path := writeTempJSONL(t, lines)
-	_, _, forkParentID, _ := scanChatMetadata(path)
+	if got := scanChatMetadata(path).ForkParentID; got != parent {
+		t.Errorf("forkParentID = %q, want %q", got, parent)
+	}
+}
+
+func TestScanChatMetadata_Problem(t *testing.T) {
+	const ok = `{"type":"user","message":{"content":"hi"}}`
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"readable", []string{ok, `{"type":"user","message":{"content":1}}`}, ""},
+		{"truncated last line", []string{ok, `{"type":"assistant","mess`}, "truncated: last line is incomplete"},
+		{"corrupt middle line", []string{ok, "not json", ok}, "1 corrupt line(s)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := scanChatMetadata(writeTempJSONL(t, tt.lines))
+			if meta.Problem != tt.want {
+				t.Errorf("problem = %q, want %q", meta.Problem, tt.want)
+			}
+			if meta.Title != "hi" {
+				t.Errorf("readable lines should still give the title, got %q", meta.Title)
+			}
+		})
+	}
 
-	if forkParentID != parent {
-		t.Errorf("forkParentID = %q, want %q", forkParentID, parent)
+	missing := filepath.Join(t.TempDir(), "gone.jsonl")
+	if meta := scanChatMetadata(missing); meta.Problem == "" || meta.Title != "[Error opening file]" {
+		t.Errorf("missing file: %+v", meta)
+	}
+	denied := &os.PathError{Op: "open", Path: missing, Err: os.ErrPermission}
+	if got := readProblem(denied); got != "permission denied" {
+		t.Errorf("readProblem(permission) = %q", got)
 	}
 }
 
@@ -385,6 +526,7 @@ func setupStorageDirs(t *testing.T) string {
 	origFileHistory := fileHistoryDir
 	origPlans := plansDir
 	origAgents := agentsDir
+	origConfig := configPath
 
 	claudeDir = tmp
 	projectsDir = filepath.Join(tmp, "projects")
@@ -395,6 +537,8 @@ func setupStorageDirs(t *testing.T) string {
 	fileHistoryDir = filepath.Join(tmp, "file-history")
 	plansDir = filepath.Join(tmp, "plans")
 	agentsDir = filepath.Join(tmp, "agents")
+	// Trash batches and the deletion log live next to the config
+	configPath = filepath.Join(tmp, "config", "config.json")
 
 	for _, d := range []string{projectsDir, debugDir, todosDir, sessionDir, tasksDir, fileHistoryDir, plansDir, agentsDir} {
 		if err := os.MkdirAll(d, 0755); err != nil {
@@ -412,6 +556,7 @@ func setupStorageDirs(t *testing.T) string {
 		fileHistoryDir = origFileHistory
 		plansDir = origPlans
 		agentsDir = origAgents
+		configPath = origConfig
 	})
 
 	return tmp
@@ -464,7 +609,7 @@ func TestFindRelatedFiles(t *testing.T) {
 		}
 	}
 
-	got := findRelatedFiles(uuid)
+	got := findRelatedFiles(primaryRoot(), uuid, "")
 	found := make(map[string]bool)
 	for _, f := range got {
 		found[f] = true
@@ -503,7 +648,7 @@ func TestFindRelatedFiles_PlanSharedSlugNotDeleted(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got := findRelatedFiles(uuid1)
+	got := findRelatedFiles(primaryRoot(), uuid1, "")
 	for _, f := range got {
 		if f == planFile {
 			t.Fatalf("shared plan file must not be deleted while another chat uses same slug: %s", planFile)
@@ -548,7 +693,7 @@ func TestFindRelatedFiles_AgentMemory(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got := findRelatedFiles(uuid)
+	got := findRelatedFiles(primaryRoot(), uuid, "")
 	found := make(map[string]bool)
 	for _, f := range got {
 		found[f] = true
@@ -586,7 +731,7 @@ func TestUpdateSessionsIndex(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := updateSessionsIndex(targetUUID); err != nil {
+	if err := updateSessionsIndex(primaryRoot(), targetUUID, ""); err != nil {
 		t.Fatalf("updateSessionsIndex error: %v", err)
 	}
 
@@ -603,3 +748,1400 @@ func TestUpdateSessionsIndex(t *testing.T) {
 		t.Errorf("sessions-index lost the kept UUID %q", keepUUID)
 	}
 }
+
+// Deleting every chat that shares a slug in one batch must still remove the
+// plan: the last chat processed sees no surviving reference to the slug.
+func TestDeleteChats_SharedPlanRemovedWithLastReference(t *testing.T) {
+	setupStorageDirs(t)
+
+	const slug = "batch-plan-slug"
+	uuid1 := "deadbeef-0000-0000-0000-000000000201"
+	uuid2 := "deadbeef-0000-0000-0000-000000000202"
+	projDir := filepath.Join(projectsDir, "batch-plan-project")
+	if err := os.MkdirAll(projDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	content := "{\"type\":\"snapshot\"}\n" +
+		"{\"type\":\"user\",\"message\":{\"content\":\"hi\"},\"slug\":\"" + slug + "\",\"isMeta\":false}\n"
+	for _, u := range []string{uuid1, uuid2} {
+		if err := os.WriteFile(filepath.Join(projDir, u+".jsonl"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	planFile := filepath.Join(plansDir, slug+".md")
+	if err := os.WriteFile(planFile, []byte("# plan"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := deleteChats([]Chat{{UUID: uuid1}, {UUID: uuid2}}, nil)
+	if err != nil {
+		t.Fatalf("deleteChats: %v", err)
+	}
+	if _, err := os.Stat(planFile); !os.IsNotExist(err) {
+		t.Errorf("plan should be removed once no chat references it, stat err = %v", err)
+	}
+	if note := res.sharedNote(); note != "" {
+		t.Errorf("a plan removed with the last chat is not kept, got %q", note)
+	}
+}
+
+// The post-delete message names shared files that stay for other chats.
+func TestDeleteChats_ReportsKeptSharedFiles(t *testing.T) {
+	setupStorageDirs(t)
+	projDir := filepath.Join(projectsDir, "shared-project")
+	if err := os.MkdirAll(projDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `{"type":"user","message":{"content":"hi"},"slug":"kept-slug","agentId":"agent-1"}` + "\n"
+	for _, u := range []string{"chat-a", "chat-b"} {
+		if err := os.WriteFile(filepath.Join(projDir, u+".jsonl"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(plansDir, "kept-slug.md"), []byte("# plan"), 0644)
+	os.MkdirAll(filepath.Join(agentsDir, "agent-1"), 0755)
+	os.WriteFile(filepath.Join(agentsDir, "agent-1", "memory-project.md"), []byte("memory"), 0644)
+
+	res, err := deleteChats([]Chat{{UUID: "chat-a", Project: "shared-project"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "kept 1 shared plan(s) and 1 agent memory file(s) still in use"; res.sharedNote() != want {
+		t.Errorf("sharedNote() = %q, want %q", res.sharedNote(), want)
+	}
+}
+
+func TestRetryBusy(t *testing.T) {
+	saved := busyRetryDelays
+	busyRetryDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	defer func() { busyRetryDelays = saved }()
+	busy := &os.PathError{Op: "unlinkat", Path: "chat.jsonl", Err: syscall.EBUSY}
+	if runtime.GOOS == "windows" {
+		busy.Err = syscall.Errno(32)
+	}
+
+	calls := 0
+	err := retryBusy(func() error {
+		if calls++; calls < 3 {
+			return busy
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("busy twice: err = %v after %d calls, want success on the third", err, calls)
+	}
+
+	calls = 0
+	err = retryBusy(func() error { calls++; return busy })
+	if !errors.Is(err, errInUse) || calls != 3 {
+		t.Errorf("always busy: err = %v after %d calls, want errInUse after every retry", err, calls)
+	}
+
+	calls = 0
+	err = retryBusy(func() error { calls++; return os.ErrPermission })
+	if !errors.Is(err, os.ErrPermission) || calls != 1 {
+		t.Errorf("other error: err = %v after %d calls, want it returned at once", err, calls)
+	}
+}
+
+func TestPreflightDeletion(t *testing.T) {
+	setupStorageDirs(t)
+	projDir := filepath.Join(projectsDir, "shared-project")
+	os.MkdirAll(projDir, 0755)
+	refs := map[string]string{
+		"chat-a": `{"slug":"both","agentId":"agent-gone"}`,
+		"chat-b": `{"slug":"both"}`,
+		"chat-c": `{"slug":"mine","agentId":"agent-kept"}`,
+		"chat-d": `{"agentId":"agent-kept"}`,
+	}
+	for u, line := range refs {
+		os.WriteFile(filepath.Join(projDir, u+".jsonl"), []byte(line+"\n"), 0644)
+	}
+	for _, slug := range []string{"both", "mine"} {
+		os.WriteFile(filepath.Join(plansDir, slug+".md"), []byte("# plan"), 0644)
+	}
+	for _, agent := range []string{"agent-gone", "agent-kept"} {
+		os.MkdirAll(filepath.Join(agentsDir, agent), 0755)
+		os.WriteFile(filepath.Join(agentsDir, agent, "memory-project.md"), []byte("memory"), 0644)
+	}
+
+	// b and d stay: "both" and agent-kept are still used, agent-gone isn't
+	rep := preflightDeletion([]Chat{{UUID: "chat-a", Project: "shared-project"}, {UUID: "chat-c", Project: "shared-project"}})
+	if want := (preflightReport{Remove: 3, Shared: 2, Orphaned: 1}); rep != want {
+		t.Errorf("preflightDeletion = %+v, want %+v (two transcripts and the plan only c uses)", rep, want)
+	}
+	if want := "3 file(s) to remove, 2 shared kept for other chats, 1 orphaned agent memory file(s) left behind"; rep.summary() != want {
+		t.Errorf("summary() = %q, want %q", rep.summary(), want)
+	}
+}
+
+func TestLoadExcludeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exclude.txt")
+	content := "# keep these\n" +
+		"deadbeef-0000-0000-0000-000000000001\n" +
+		"\n" +
+		"  cafe*  \n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	patterns, err := loadExcludeFile(path)
+	if err != nil {
+		t.Fatalf("loadExcludeFile: %v", err)
+	}
+	want := []string{"deadbeef-0000-0000-0000-000000000001", "cafe*"}
+	if strings.Join(patterns, ",") != strings.Join(want, ",") {
+		t.Fatalf("patterns = %v, want %v", patterns, want)
+	}
+
+	tests := []struct {
+		uuid string
+		want bool
+	}{
+		{"deadbeef-0000-0000-0000-000000000001", true},
+		{"deadbeef-0000-0000-0000-000000000002", false},
+		{"cafebabe-1111-2222-3333-444444444444", true},
+		{"00cafe00-1111-2222-3333-444444444444", false},
+	}
+	for _, tt := range tests {
+		if got := isProtectedUUID(tt.uuid, patterns); got != tt.want {
+			t.Errorf("isProtectedUUID(%q) = %v, want %v", tt.uuid, got, tt.want)
+		}
+	}
+}
+
+func TestLoadExcludeFile_InvalidPattern(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exclude.txt")
+	if err := os.WriteFile(path, []byte("abc[\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadExcludeFile(path); err == nil {
+		t.Fatal("expected error for malformed glob pattern")
+	}
+}
+
+func TestDeleteChats_TalliesResult(t *testing.T) {
+	setupStorageDirs(t)
+
+	uuid := "deadbeef-0000-0000-0000-000000000301"
+	projDir := filepath.Join(projectsDir, "tally-project")
+	if err := os.MkdirAll(projDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	jsonl := "{\"type\":\"user\",\"message\":{\"content\":\"hi\"}}\n"
+	if err := os.WriteFile(filepath.Join(projDir, uuid+".jsonl"), []byte(jsonl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	debug := strings.Repeat("x", 100)
+	if err := os.WriteFile(filepath.Join(debugDir, uuid+".txt"), []byte(debug), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := deleteChats([]Chat{{UUID: uuid}}, nil)
+	if err != nil {
+		t.Fatalf("deleteChats: %v", err)
+	}
+	if res.Deleted != 1 || res.Failed != 0 {
+		t.Errorf("result = %+v, want 1 deleted, 0 failed", res)
+	}
+	if want := int64(len(jsonl) + len(debug)); res.FreedBytes != want {
+		t.Errorf("FreedBytes = %d, want %d", res.FreedBytes, want)
+	}
+}
+
+// Same UUID in two projects (cloned session): deleting one copy must leave the
+// other copy and the UUID-keyed global artifacts it still owns untouched.
+func TestDeleteChats_SameUUIDInTwoProjects(t *testing.T) {
+	setupStorageDirs(t)
+
+	uuid := "deadbeef-0000-0000-0000-000000000401"
+	content := "{\"type\":\"user\",\"message\":{\"content\":\"hi\"}}\n"
+	var paths []string
+	for _, project := range []string{"project-a", "project-b"} {
+		dir := filepath.Join(projectsDir, project)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, uuid+".jsonl")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	debugFile := filepath.Join(debugDir, uuid+".txt")
+	if err := os.WriteFile(debugFile, []byte("log"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := deleteChats([]Chat{{UUID: uuid, Project: "project-a"}}, nil); err != nil {
+		t.Fatalf("deleteChats: %v", err)
+	}
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Errorf("selected copy not deleted: %s", paths[0])
+	}
+	if _, err := os.Stat(paths[1]); err != nil {
+		t.Errorf("copy in other project was deleted: %v", err)
+	}
+	if _, err := os.Stat(debugFile); err != nil {
+		t.Errorf("debug log still owned by the surviving copy was deleted: %v", err)
+	}
+
+	if _, err := deleteChats([]Chat{{UUID: uuid, Project: "project-b"}}, nil); err != nil {
+		t.Fatalf("deleteChats: %v", err)
+	}
+	if _, err := os.Stat(debugFile); !os.IsNotExist(err) {
+		t.Errorf("debug log should go with the last copy, stat err = %v", err)
+	}
+}
+
+// Chats active within the same second must still sort by their sub-second
+// JSONL timestamps, and chats without timestamps fall back to file mtime.
+func TestFindAllChats_SortsByParsedTimestamp(t *testing.T) {
+	setupStorageDirs(t)
+
+	projDir := filepath.Join(projectsDir, "sort-project")
+	if err := os.MkdirAll(projDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(uuid, ts string) string {
+		line := `{"type":"user","message":{"content":"hi"}`
+		if ts != "" {
+			line += `,"timestamp":"` + ts + `"`
+		}
+		path := filepath.Join(projDir, uuid+".jsonl")
+		if err := os.WriteFile(path, []byte(line+"}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("earlier", "2026-01-01T10:00:00.100Z")
+	write("later", "2026-01-01T10:00:00.900Z")
+	noTS := write("no-timestamp", "")
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(noTS, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	chats, err := findAllChats()
+	if err != nil {
+		t.Fatalf("findAllChats: %v", err)
+	}
+	var order []string
+	for _, c := range chats {
+		order = append(order, c.UUID)
+	}
+	want := []string{"later", "earlier", "no-timestamp"}
+	if strings.Join(order, ",") != strings.Join(want, ",") {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+	if !chats[2].Time.Equal(old) {
+		t.Errorf("fallback time = %v, want file mtime %v", chats[2].Time, old)
+	}
+}
+
+func TestFindAllChats_ProjectScope(t *testing.T) {
+	setupStorageDirs(t)
+	t.Cleanup(func() { projectScope = "" })
+
+	for _, project := range []string{"wanted", "other"} {
+		dir := filepath.Join(projectsDir, project)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		line := `{"type":"user","message":{"content":"hi"}}` + "\n"
+		if err := os.WriteFile(filepath.Join(dir, project+"-chat.jsonl"), []byte(line), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := validateProjectScope("missing"); err == nil || !strings.Contains(err.Error(), "wanted") {
+		t.Errorf("validateProjectScope(missing) = %v, want error listing available projects", err)
+	}
+	if err := validateProjectScope("wanted"); err != nil {
+		t.Fatalf("validateProjectScope(wanted): %v", err)
+	}
+	for _, path := range []string{".", "..", "wanted/../other", "../projects"} {
+		if err := validateProjectScope(path); err == nil || !strings.Contains(err.Error(), "not a path") {
+			t.Errorf("validateProjectScope(%q) = %v, want it refused as a path", path, err)
+		}
+	}
+
+	projectScope = "wanted"
+	chats, _ := findAllChats()
+	if len(chats) != 1 || chats[0].Project != "wanted" {
+		t.Errorf("scoped scan returned %+v, want only the chat in \"wanted\"", chats)
+	}
+}
+
+func TestFindAllChats_ScanErrors(t *testing.T) {
+	setupStorageDirs(t)
+
+	// A missing projects dir is just "no chats", not an error.
+	projectsDir = filepath.Join(t.TempDir(), "does-not-exist")
+	if chats, err := findAllChats(); err != nil || len(chats) != 0 {
+		t.Errorf("missing dir: got %d chats, err %v; want none, nil", len(chats), err)
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("root bypasses directory permissions")
+	}
+	projectsDir = filepath.Join(t.TempDir(), "locked")
+	if err := os.Mkdir(projectsDir, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(projectsDir, 0755) })
+	_, err := findAllChats()
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("unreadable dir: err = %v, want permission denied", err)
+	}
+}
+
+func TestExportMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "chat.jsonl")
+	lines := []string{
+		`{"type":"user","message":{"role":"user","content":"<system-reminder>hidden</system-reminder>How do I\nfix this?"}}`,
+		`{"type":"user","isMeta":true,"message":{"role":"user","content":"meta noise"}}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Let me look."},{"type":"tool_use","name":"Read","input":{}}]}}`,
+		`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","content":"file body"}]}}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Fixed."}]}}`,
+		// Longer than any scanner buffer, and without a final newline
+		`{"type":"user","message":{"role":"user","content":"paste ` + strings.Repeat("x", 2<<20) + ` end"}}`,
+	}
+	if err := os.WriteFile(src, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chat := Chat{UUID: "abc", Title: "Fix bug", Project: "proj", Path: src}
+
+	out := filepath.Join(dir, "out.md")
+	if err := exportMarkdown(chat, out); err != nil {
+		t.Fatalf("exportMarkdown: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		"# Fix bug\n",
+		"## User\n\nHow do I\nfix this?\n",
+		"## Assistant\n\nLet me look.\n\n_[tool call: Read]_\n",
+		"## Assistant\n\nFixed.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("export missing %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"hidden", "meta noise", "file body"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("export should not contain %q:\n%s", unwanted, got)
+		}
+	}
+	if n := strings.Count(got, "## User"); n != 2 || !strings.Contains(got, "x end\n") {
+		t.Errorf("expected 2 user turns (tool results skipped), the long one whole, got %d", n)
+	}
+
+	if err := exportMarkdown(chat, out); err == nil {
+		t.Error("exporting over an existing file should fail")
+	}
+}
+
+func TestProbeProjectPath(t *testing.T) {
+	root := t.TempDir()
+	for _, d := range []string{"src/my-app/.cfg", "src/my"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		encoded string
+		want    string
+	}{
+		{"src-my-app", filepath.Join(root, "src", "my-app")},
+		{"src-my-app--cfg", filepath.Join(root, "src", "my-app", ".cfg")},
+		{"src-my", filepath.Join(root, "src", "my")},
+		{"src-missing", ""},
+	}
+	for _, tt := range tests {
+		if got := probeProjectPath(root, strings.Split(tt.encoded, "-")); got != tt.want {
+			t.Errorf("probeProjectPath(%q) = %q, want %q", tt.encoded, got, tt.want)
+		}
+	}
+}
+
+func TestFindAllChats_ProjectPathFromIndex(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "-work-my-app")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	line := `{"type":"user","message":{"content":"hi"}}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "chat.jsonl"), []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+	index := `{"version":1,"entries":[],"originalPath":"/work/my-app"}`
+	if err := os.WriteFile(filepath.Join(dir, "sessions-index.json"), []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	chats, _ := findAllChats()
+	if len(chats) != 1 || chats[0].ProjectPath != "/work/my-app" {
+		t.Errorf("ProjectPath not taken from sessions-index: %+v", chats)
+	}
+}
+
+func TestVerifyDeletion_ReportsReappearedFile(t *testing.T) {
+	setupStorageDirs(t)
+
+	uuid := "deadbeef-0000-0000-0000-000000000861"
+	projDir := filepath.Join(projectsDir, "verify-project")
+	if err := os.MkdirAll(projDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	chatFile := filepath.Join(projDir, uuid+".jsonl")
+	jsonl := "{\"type\":\"user\",\"message\":{\"content\":\"hi\"}}\n"
+	if err := os.WriteFile(chatFile, []byte(jsonl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chat := Chat{UUID: uuid, Project: "verify-project"}
+
+	res, err := deleteChats([]Chat{chat}, nil)
+	if err != nil {
+		t.Fatalf("deleteChats: %v", err)
+	}
+	if leftovers := verifyDeletion(res, []Chat{chat}); len(leftovers) != 0 {
+		t.Errorf("clean delete reported leftovers: %v", leftovers)
+	}
+
+	// Simulate Claude writing the session again right after deletion
+	if err := os.WriteFile(chatFile, []byte(jsonl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	index := `{"version":1,"entries":[{"sessionId":"` + uuid + `"}]}`
+	if err := os.WriteFile(filepath.Join(projDir, "sessions-index.json"), []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+	leftovers := verifyDeletion(res, []Chat{chat})
+	if len(leftovers) != 2 || leftovers[0] != chatFile || !strings.Contains(leftovers[1], "sessions-index.json") {
+		t.Errorf("leftovers = %v, want the chat file and the index entry", leftovers)
+	}
+}
+
+func TestDeleteTargets_VerifyFailureCountsAsFailed(t *testing.T) {
+	tmp := setupStorageDirs(t)
+
+	// updateSessionsIndex only walks real directories, so the entry in a
+	// project reached through a symlink survives the delete: a leftover
+	// -verify has to catch.
+	real := filepath.Join(tmp, "elsewhere", "linked")
+	if err := os.MkdirAll(real, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(real, filepath.Join(projectsDir, "linked")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	uuid := "deadbeef-0000-0000-0000-000000000862"
+	if err := os.WriteFile(filepath.Join(real, uuid+".jsonl"), []byte("{\"type\":\"user\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	index := `{"version":1,"entries":[{"sessionId":"` + uuid + `"}]}`
+	if err := os.WriteFile(filepath.Join(real, "sessions-index.json"), []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var res deleteResult
+	deleteTargets([]Chat{{UUID: uuid, Project: "linked"}}, &res, true)
+	if res.Deleted != 0 || res.Failed != 1 || res.FreedBytes != 0 {
+		t.Errorf("result = %+v, want the chat failed with nothing freed", res)
+	}
+	if logged, err := readDeletionLog(); err != nil || len(logged) != 0 {
+		t.Errorf("deletion log = %+v, %v; want no record for a chat that failed verification", logged, err)
+	}
+}
+
+func TestRemoveProjectDirIfEmpty(t *testing.T) {
+	setupStorageDirs(t)
+
+	retired := filepath.Join(projectsDir, "retired")
+	kept := filepath.Join(projectsDir, "kept")
+	for _, d := range []string{retired, filepath.Join(kept, "memory")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(retired, "sessions-index.json"), []byte(`{"entries":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if removed, err := removeProjectDirIfEmpty(primaryRoot(), "retired"); err != nil || !removed {
+		t.Errorf("retired: removed=%v err=%v, want removed", removed, err)
+	}
+	if _, err := os.Stat(retired); !os.IsNotExist(err) {
+		t.Error("retired project directory still exists")
+	}
+
+	if removed, err := removeProjectDirIfEmpty(primaryRoot(), "kept"); err != nil || removed {
+		t.Errorf("kept: removed=%v err=%v, want kept because of memory/", removed, err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("project with memory/ was removed: %v", err)
+	}
+}
+
+func TestDeletionLog(t *testing.T) {
+	setupStorageDirs(t)
+	projDir := filepath.Join(projectsDir, "log-project")
+	os.MkdirAll(projDir, 0755)
+	var chats []Chat
+	for i, content := range []string{"short\n", "a longer transcript\n"} {
+		uuid := fmt.Sprintf("deadbeef-0000-0000-0000-00000000092%d", i)
+		os.WriteFile(filepath.Join(projDir, uuid+".jsonl"), []byte(content), 0644)
+		chats = append(chats, Chat{UUID: uuid, Title: "chat " + uuid[len(uuid)-1:], Project: "log-project"})
+	}
+	os.WriteFile(filepath.Join(debugDir, chats[1].UUID+".txt"), []byte("log"), 0644)
+
+	res, err := deleteChats(chats, nil)
+	if err != nil || len(res.records) != 2 {
+		t.Fatalf("deleteChats: records=%+v err=%v", res.records, err)
+	}
+	if r := res.records[1]; r.UUID != chats[1].UUID || len(r.Files) != 2 || r.FreedBytes != 23 || r.TrashBatch != "" {
+		t.Errorf("record = %+v, want the second chat's 2 files and 23 bytes", r)
+	}
+	if res.records[0].FreedBytes+res.records[1].FreedBytes != res.FreedBytes {
+		t.Error("records should split the freed bytes between the chats")
+	}
+
+	if err := appendDeletionLog(res.records[:1]); err != nil {
+		t.Fatal(err)
+	}
+	f, _ := os.OpenFile(deletionLogPath(), os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"time":"2026-` + "\n") // a write cut short
+	f.Close()
+	if err := appendDeletionLog(res.records[1:]); err != nil {
+		t.Fatal(err)
+	}
+	logged, err := readDeletionLog()
+	if err != nil || len(logged) != 2 || logged[0].UUID != chats[0].UUID || logged[1].Title != "chat 1" {
+		t.Errorf("readDeletionLog = %+v, %v; want both records in order", logged, err)
+	}
+}
+
+func TestMoveChatsToTrash_RestoreRoundTrip(t *testing.T) {
+	tmp := setupStorageDirs(t)
+	origConfig := configPath
+	configPath = filepath.Join(tmp, "config", "config.json")
+	t.Cleanup(func() { configPath = origConfig })
+
+	uuid := "deadbeef-0000-0000-0000-000000000863"
+	projDir := filepath.Join(projectsDir, "trash-project")
+	if err := os.MkdirAll(filepath.Join(projDir, uuid, "subagents"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	chatFile := filepath.Join(projDir, uuid+".jsonl")
+	if err := os.WriteFile(chatFile, []byte("{\"type\":\"user\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	debugFile := filepath.Join(debugDir, uuid+".txt")
+	if err := os.WriteFile(debugFile, []byte("log"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	os.Chtimes(chatFile, old, old)
+	indexPath := filepath.Join(projDir, "sessions-index.json")
+	index := `{"version":1,"entries":[{"sessionId":"` + uuid + `","firstPrompt":"hello"}]}`
+	if err := os.WriteFile(indexPath, []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	chat := Chat{UUID: uuid, Project: "trash-project"}
+	batch, res, err := moveChatsToTrash([]Chat{chat}, "test", nil)
+	if err != nil || res.Deleted != 1 {
+		t.Fatalf("moveChatsToTrash: res=%+v err=%v", res, err)
+	}
+	for _, p := range []string{chatFile, debugFile, filepath.Join(projDir, uuid)} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s still present after trashing", p)
+		}
+	}
+	if findIndexEntry(primaryRoot(), uuid, "trash-project") != nil {
+		t.Error("index entry not removed when trashing")
+	}
+	if len(res.records) != 1 || res.records[0].TrashBatch != batch || len(res.records[0].Files) != 3 {
+		t.Errorf("trash record = %+v, want the chat's 3 files in batch %s", res.records, batch)
+	}
+
+	// A restore interrupted after its first file can be run again
+	manifest, err := readTrashManifest(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := manifest.Chats[0].Files[0]
+	if err := os.Rename(filepath.Join(trashDir(), batch, first.Stored), first.Original); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := restoreTrashBatch(batch)
+	if err != nil || restored != 1 {
+		t.Fatalf("restoreTrashBatch: restored=%d err=%v", restored, err)
+	}
+	for _, p := range []string{chatFile, debugFile, filepath.Join(projDir, uuid, "subagents")} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s not restored: %v", p, err)
+		}
+	}
+	if e := findIndexEntry(primaryRoot(), uuid, "trash-project"); e == nil || e.FirstPrompt != "hello" {
+		t.Errorf("index entry not restored: %+v", e)
+	}
+	if info, err := os.Stat(chatFile); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("restored chat should keep its modification time %v", old)
+	}
+	if _, err := os.Stat(filepath.Join(trashDir(), batch)); !os.IsNotExist(err) {
+		t.Error("restored batch should be removed from the trash")
+	}
+}
+
+func TestMoveChatsToTrash_SamePathInTwoRoots(t *testing.T) {
+	primary := setupStorageDirs(t)
+	second := t.TempDir()
+	claudeRoots = []string{primary, second}
+	t.Cleanup(func() { claudeRoots = nil })
+
+	var chats []Chat
+	for _, dir := range []string{primary, second} {
+		root := newClaudeRoot(dir)
+		path := filepath.Join(root.projects, "proj", "same.jsonl")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(dir+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		chats = append(chats, Chat{UUID: "same", Project: "proj", Root: root, Path: path})
+	}
+
+	batch, res, err := moveChatsToTrash(chats, "test", nil)
+	if err != nil || res.Deleted != 2 {
+		t.Fatalf("moveChatsToTrash: res=%+v err=%v", res, err)
+	}
+	if restored, err := restoreTrashBatch(batch); err != nil || restored != 2 {
+		t.Fatalf("restoreTrashBatch: restored=%d err=%v", restored, err)
+	}
+	for _, chat := range chats {
+		if data, err := os.ReadFile(chat.Path); err != nil || string(data) != chat.Root.dir+"\n" {
+			t.Errorf("%s restored as %q, %v; want its own content back", chat.Path, data, err)
+		}
+	}
+
+	src := filepath.Join(t.TempDir(), "src")
+	dst := filepath.Join(t.TempDir(), "dst")
+	os.WriteFile(src, []byte("new"), 0644)
+	os.WriteFile(dst, []byte("old"), 0644)
+	if err := moveFile(src, dst); err == nil {
+		t.Error("moveFile should refuse an existing destination")
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "old" {
+		t.Errorf("destination overwritten with %q", data)
+	}
+}
+
+func TestCopyTree_KeepsModesAndTimes(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "chat")
+	os.MkdirAll(filepath.Join(src, "subagents"), 0755)
+	os.WriteFile(filepath.Join(src, "subagents", "agent.jsonl"), []byte("{}\n"), 0600)
+	old := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, p := range []string{filepath.Join(src, "subagents", "agent.jsonl"), filepath.Join(src, "subagents"), src} {
+		os.Chtimes(p, old, old)
+	}
+
+	dst := filepath.Join(t.TempDir(), "copy")
+	if err := copyTree(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range []string{"", "subagents", filepath.Join("subagents", "agent.jsonl")} {
+		info, err := os.Stat(filepath.Join(dst, rel))
+		if err != nil || !info.ModTime().Equal(old) {
+			t.Errorf("%q: modification time not kept (%v)", rel, err)
+		}
+	}
+	if info, _ := os.Stat(filepath.Join(dst, "subagents", "agent.jsonl")); runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("file mode = %v, want 0600", info.Mode().Perm())
+	}
+	// A link is copied as a link, leaving the time of what it points at alone
+	if runtime.GOOS != "windows" {
+		target := filepath.Join(t.TempDir(), "target.md")
+		os.WriteFile(target, []byte("plan"), 0644)
+		os.Chtimes(target, old, old)
+		os.Symlink(target, filepath.Join(src, "link.md"))
+		os.Chtimes(src, old, old)
+		dst := filepath.Join(t.TempDir(), "copy")
+		if err := copyTree(src, dst); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := os.Readlink(filepath.Join(dst, "link.md")); err != nil || got != target {
+			t.Errorf("link copied as %q, %v; want a link to %s", got, err, target)
+		}
+		if info, _ := os.Stat(target); !info.ModTime().Equal(old) {
+			t.Errorf("copying a link changed its target's time to %v", info.ModTime())
+		}
+	}
+	if isCrossDevice(&os.LinkError{Op: "rename", Err: os.ErrPermission}) {
+		t.Error("only cross-device renames fall back to copying")
+	}
+}
+
+func TestTrashBatches_RetentionAndEmpty(t *testing.T) {
+	tmp := t.TempDir()
+	origConfig := configPath
+	configPath = filepath.Join(tmp, "config.json")
+	t.Cleanup(func() { configPath = origConfig })
+
+	now := time.Now()
+	for name, age := range map[string]int{"old": 40, "new": 2} {
+		dir := filepath.Join(trashDir(), name)
+		if err := os.MkdirAll(filepath.Join(dir, "files"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(dir, "files", "chat.jsonl"), []byte(strings.Repeat("x", 100)), 0644)
+		manifest := trashManifest{Created: now.AddDate(0, 0, -age), Chats: []trashedChat{{UUID: name}}}
+		if err := writeTrashManifest(dir, manifest); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	batches, err := listTrashBatches()
+	if err != nil || len(batches) != 2 || batches[0].Name != "old" {
+		t.Fatalf("listTrashBatches = %+v, %v; want old then new", batches, err)
+	}
+	if chats, size := trashTotal(batches); chats != 2 || size < 200 {
+		t.Errorf("trashTotal = %d chats, %d bytes", chats, size)
+	}
+
+	expired := expiredTrashBatches(batches, 30, now)
+	if len(expired) != 1 || expired[0].Name != "old" {
+		t.Fatalf("expiredTrashBatches(30) = %+v, want only old", expired)
+	}
+	if removed, freed, err := removeTrashBatches(expired); removed != 1 || freed != expired[0].Size || err != nil {
+		t.Errorf("removeTrashBatches = %d, %d, %v", removed, freed, err)
+	}
+	if batches, _ := listTrashBatches(); len(batches) != 1 || batches[0].Name != "new" {
+		t.Errorf("only the new batch should remain, got %+v", batches)
+	}
+}
+
+func TestRunPostDeleteHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook runs through sh")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	deleted := []Chat{{UUID: "uuid-1"}, {UUID: "uuid-2"}}
+	hook := `echo "$CLAUDE_CHATS_DELETED $CLAUDE_CHATS_TRASHED $*" > "` + out + `"; cat >> "` + out + `"`
+	if err := runPostDeleteHook(hook, deleted, true); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(out)
+	if want := "2 1 uuid-1 uuid-2\nuuid-1\nuuid-2\n"; string(got) != want {
+		t.Errorf("hook saw %q, want %q", got, want)
+	}
+
+	err := runPostDeleteHook("echo boom >&2; exit 3", deleted, false)
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("a failing hook should report its output, got %v", err)
+	}
+	if err := runPostDeleteHook("exit 1", nil, false); err != nil {
+		t.Errorf("the hook should not run without deleted chats, got %v", err)
+	}
+}
+
+func TestCollectStats(t *testing.T) {
+	jan := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+	mar := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	chats := []Chat{
+		{UUID: "a", Project: "small", Time: mar},
+		{UUID: "b", Project: "big", Time: jan, Empty: true},
+		{UUID: "c", Project: "big"},
+	}
+	st := collectStats(chats, []int64{100, 2000, 3000})
+	if st.Chats != 3 || st.Bytes != 5100 || st.Empty != 1 {
+		t.Errorf("totals = %d chats, %d bytes, %d empty; want 3, 5100, 1", st.Chats, st.Bytes, st.Empty)
+	}
+	if st.Oldest != jan.Format(time.RFC3339) || st.Newest != mar.Format(time.RFC3339) {
+		t.Errorf("range = %s .. %s, want undated chats ignored", st.Oldest, st.Newest)
+	}
+	if len(st.Projects) != 2 || st.Projects[0].Project != "big" || st.Projects[0].Chats != 2 || st.Projects[0].Bytes != 5000 {
+		t.Errorf("projects = %+v, want big (2 chats, 5000 bytes) first", st.Projects)
+	}
+}
+
+func TestPurgeCandidates(t *testing.T) {
+	dir := t.TempDir()
+	transcript := func(name string, size int) string {
+		path := filepath.Join(dir, name+".jsonl")
+		if err := os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	now := time.Now()
+	old := now.Add(-2 * time.Hour)
+	chats := []Chat{
+		{UUID: "empty", LineCount: 1, Time: old},
+		{UUID: "real", LineCount: 40, Time: old},
+		{UUID: "fresh", LineCount: 1, Time: now},
+		{UUID: "kept", LineCount: 1, Time: old, Protected: true},
+		{UUID: "snapshot", LineCount: 1, Time: old, Empty: true},
+		{UUID: "snapshots", LineCount: 12, Time: old, Empty: true, Path: transcript("snapshots", 4096)},
+		{UUID: "huge", LineCount: 900, Time: old, Empty: true, Path: transcript("huge", purgeEmptyMaxBytes+1)},
+		{UUID: "fresh-snapshots", LineCount: 12, Time: now, Empty: true, Path: transcript("fresh-snapshots", 4096)},
+	}
+	var got []string
+	for _, chat := range purgeCandidates(chats, 3, now) {
+		got = append(got, chat.UUID)
+	}
+	if want := []string{"empty", "snapshot", "snapshots"}; !slices.Equal(got, want) {
+		t.Errorf("purgeCandidates = %v, want %v: idle short chats and small empty sessions", got, want)
+	}
+}
+
+func TestValidateDateFormat(t *testing.T) {
+	for _, layout := range []string{"02/01/2006 15:04", "2006-01-02 15:04 MST", defaultDateFormat} {
+		if err := validateDateFormat(layout); err != nil {
+			t.Errorf("validateDateFormat(%q) = %v, want nil", layout, err)
+		}
+	}
+	if err := validateDateFormat("DD/MM/YYYY"); err == nil {
+		t.Error("layout without Go reference elements should be rejected")
+	}
+
+	orig := dateFormat
+	t.Cleanup(func() { dateFormat = orig })
+	dateFormat = "02/01/2006 15:04"
+	ts := time.Date(2026, 3, 9, 14, 5, 0, 0, time.Local)
+	if got := formatTimestamp(ts); got != "09/03/2026 14:05" {
+		t.Errorf("formatTimestamp with custom layout = %q", got)
+	}
+}
+
+func TestFindAllChats_PlanPath(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	with := `{"type":"user","slug":"brave-fox","message":{"content":"hi"}}` + "\n"
+	without := `{"type":"user","slug":"lazy-dog","message":{"content":"hi"}}` + "\n"
+	for name, data := range map[string]string{"with.jsonl": with, "without.jsonl": without} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan := filepath.Join(plansDir, "brave-fox.md")
+	if err := os.WriteFile(plan, []byte("# Plan\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	chats, _ := findAllChats()
+	got := map[string]string{}
+	for _, c := range chats {
+		got[c.UUID] = c.PlanPath
+	}
+	if got["with"] != plan || got["without"] != "" {
+		t.Errorf("PlanPath = %v, want with=%s and without empty", got, plan)
+	}
+}
+
+func TestWriteRelatedFiles(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	jsonl := filepath.Join(dir, "abc.jsonl")
+	debug := filepath.Join(debugDir, "abc.txt")
+	for _, f := range []string{jsonl, debug} {
+		if err := os.WriteFile(f, []byte(`{"type":"user"}`+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf strings.Builder
+	n, err := writeRelatedFiles(&buf, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if n != 2 || !strings.Contains(out, "abc (proj)") ||
+		!strings.Contains(out, "✓ "+jsonl) || !strings.Contains(out, "✓ "+debug) {
+		t.Errorf("n = %d, output:\n%s", n, out)
+	}
+	// Paths that would be removed if present are listed as missing
+	for _, missing := range []string{filepath.Join(sessionDir, "abc"), filepath.Join(fileHistoryDir, "abc")} {
+		if !strings.Contains(out, "✗ "+missing) {
+			t.Errorf("missing %s not marked:\n%s", missing, out)
+		}
+	}
+
+	// Orphaned artifacts are still listed when the chat file is gone
+	os.Remove(jsonl)
+	buf.Reset()
+	n, _ = writeRelatedFiles(&buf, "abc")
+	if n != 1 || !strings.Contains(buf.String(), "(no chat file)") {
+		t.Errorf("orphan: n = %d, output:\n%s", n, buf.String())
+	}
+}
+
+func TestFindAllChats_MultipleRoots(t *testing.T) {
+	primary := setupStorageDirs(t)
+	second := t.TempDir()
+	claudeRoots = []string{primary, second}
+	t.Cleanup(func() { claudeRoots = nil })
+
+	write := func(path string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(`{"type":"user","message":{"content":"hi"}}`+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(primary, "projects", "proj", "one.jsonl"))
+	write(filepath.Join(second, "projects", "proj", "two.jsonl"))
+	write(filepath.Join(second, "debug", "two.txt"))
+
+	chats, err := findAllChats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := map[string]string{}
+	for _, c := range chats {
+		roots[c.UUID] = c.Root.dir
+	}
+	if len(chats) != 2 || roots["one"] != primary || roots["two"] != second {
+		t.Fatalf("roots = %v, want one in %s and two in %s", roots, primary, second)
+	}
+
+	// Deleting the second root's chat resolves its files in that root
+	for _, c := range chats {
+		if c.UUID == "two" {
+			if _, err := deleteChats([]Chat{c}, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for _, gone := range []string{"projects/proj/two.jsonl", "debug/two.txt"} {
+		if _, err := os.Stat(filepath.Join(second, gone)); !os.IsNotExist(err) {
+			t.Errorf("%s still present in second root", gone)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(primary, "projects", "proj", "one.jsonl")); err != nil {
+		t.Errorf("primary root chat touched: %v", err)
+	}
+}
+
+func TestDeleteChats_StopsBeforeNextChat(t *testing.T) {
+	setupStorageDirs(t)
+	projDir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(projDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, uuid := range []string{"first", "second"} {
+		if err := os.WriteFile(filepath.Join(projDir, uuid+".jsonl"), []byte("{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stop := new(atomic.Bool)
+	stop.Store(true)
+	res, err := deleteChats([]Chat{{UUID: "first", Project: "proj"}, {UUID: "second", Project: "proj"}}, stop)
+	if err != nil || res.Deleted != 0 {
+		t.Fatalf("stopped batch: res = %+v, err = %v, want nothing deleted", res, err)
+	}
+	if _, err := os.Stat(filepath.Join(projDir, "first.jsonl")); err != nil {
+		t.Errorf("chat deleted despite stop: %v", err)
+	}
+}
+
+func TestMatchUUIDs(t *testing.T) {
+	known := []string{"abc-2", "abc-1", "abd-1", "xyz"}
+	tests := []struct {
+		arg  string
+		want []string
+	}{
+		{"xyz", []string{"xyz"}},
+		{"abc", []string{"abc-1", "abc-2"}},
+		{"ab?-1", []string{"abc-1", "abd-1"}},
+		{"*-2", []string{"abc-2"}},
+		{"nope", nil},
+	}
+	for _, tt := range tests {
+		got, err := matchUUIDs(tt.arg, known)
+		if err != nil || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("matchUUIDs(%q) = %v, %v; want %v", tt.arg, got, err, tt.want)
+		}
+	}
+	if _, err := matchUUIDs("[", known); err == nil {
+		t.Error("malformed glob should be an error")
+	}
+}
+
+func TestDeadBranchChats(t *testing.T) {
+	repo := t.TempDir()
+	plain := t.TempDir()
+	gone := filepath.Join(repo, "gone")
+	list := func(dir string) (map[string]bool, error) {
+		if dir != repo {
+			return nil, fmt.Errorf("not a git repository")
+		}
+		return map[string]bool{"main": true, "feature": true}, nil
+	}
+	chats := []Chat{
+		{UUID: "alive", ProjectPath: repo, GitBranch: "feature"},
+		{UUID: "dead", ProjectPath: repo, GitBranch: "merged-fix"},
+		{UUID: "no-branch", ProjectPath: repo},
+		{UUID: "detached", ProjectPath: repo, GitBranch: "HEAD"},
+		{UUID: "not-a-repo", ProjectPath: plain, GitBranch: "old"},
+		{UUID: "dir-gone", ProjectPath: gone, GitBranch: "old"},
+	}
+	dead := deadBranchChats(chats, list)
+	if len(dead) != 1 || dead[0].UUID != "dead" {
+		t.Errorf("deadBranchChats = %v, want only the chat on merged-fix", dead)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	git("commit", "-q", "--allow-empty", "-m", "init")
+	git("branch", "feature")
+	git("update-ref", "refs/remotes/origin/pushed", "HEAD")
+	branches, err := gitBranches(repo)
+	if err != nil || !branches["main"] || !branches["feature"] || !branches["pushed"] || branches["merged-fix"] {
+		t.Errorf("gitBranches = %v, %v; want main, feature and the remote pushed", branches, err)
+	}
+	if _, err := gitBranches(plain); err == nil {
+		t.Error("gitBranches accepted a directory that is not a repository")
+	}
+}
+
+func TestKeepRecentTargets(t *testing.T) {
+	now := time.Now()
+	chat := func(uuid, project string, age int) Chat {
+		return Chat{UUID: uuid, Project: project, Time: now.Add(-time.Duration(age) * time.Hour)}
+	}
+	chats := []Chat{
+		chat("a3", "a", 3), chat("b1", "b", 1), chat("a1", "a", 1),
+		chat("a2", "a", 2), chat("b2", "b", 2), chat("c1", "c", 1),
+	}
+	var got []string
+	for _, c := range keepRecentTargets(chats, 1) {
+		got = append(got, c.UUID)
+	}
+	if want := "a2,a3,b2"; strings.Join(got, ",") != want {
+		t.Errorf("keepRecentTargets(1) = %v, want %s", got, want)
+	}
+	if targets := keepRecentTargets(chats, 3); len(targets) != 0 {
+		t.Errorf("no project has more than 3 chats, got %d targets", len(targets))
+	}
+}
+
+func TestPickClaudeDir(t *testing.T) {
+	tmp := t.TempDir()
+	good := filepath.Join(tmp, "claude")
+	bare := filepath.Join(tmp, "bare")
+	os.MkdirAll(filepath.Join(good, "projects"), 0755)
+	os.MkdirAll(bare, 0755)
+	t.Setenv("HOME", tmp)
+	t.Setenv("CLAUDE_CONFIG_DIR", good)
+	t.Setenv("CLAUDE_HOME", bare)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+
+	candidates := claudeDirCandidates()
+	if len(candidates) != 2 || candidates[0] != good || candidates[1] != bare {
+		t.Fatalf("claudeDirCandidates() = %v, want the existing directories only", candidates)
+	}
+
+	var out bytes.Buffer
+	dir, err := pickClaudeDir(strings.NewReader("2\n1\n"), &out, candidates)
+	if err != nil || dir != good {
+		t.Errorf("pickClaudeDir = %q, %v; want %q after the bare choice is refused", dir, err, good)
+	}
+	if !strings.Contains(out.String(), "2) "+bare+"  (no projects directory)") || !strings.Contains(out.String(), "has no projects directory") {
+		t.Errorf("the list should flag and refuse directories without projects:\n%s", out.String())
+	}
+
+	if dir, err := pickClaudeDir(strings.NewReader("\n"), io.Discard, candidates); err != nil || dir != good {
+		t.Errorf("Enter should take the first candidate, got %q, %v", dir, err)
+	}
+	if dir, err := pickClaudeDir(strings.NewReader(good+"\n"), io.Discard, nil); err != nil || dir != good {
+		t.Errorf("a typed path should be used, got %q, %v", dir, err)
+	}
+	if _, err := pickClaudeDir(strings.NewReader(bare), io.Discard, nil); err == nil {
+		t.Error("input ending without a valid directory should fail")
+	}
+}
+
+func TestHomeDir_Unset(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skip("the home directory doesn't come from HOME here")
+	}
+	claude := filepath.Join(t.TempDir(), "claude")
+	os.MkdirAll(claude, 0755)
+	t.Setenv("HOME", "")
+	t.Setenv("CLAUDE_CONFIG_DIR", claude)
+	t.Setenv("CLAUDE_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+
+	if path := defaultConfigPath(); path != "" {
+		t.Errorf("defaultConfigPath() = %q, want none without a home directory", path)
+	}
+	if got := expandHome("~/chats"); got != "~/chats" {
+		t.Errorf("expandHome = %q, want the path unchanged", got)
+	}
+	if got := claudeDirCandidates(); len(got) != 1 || got[0] != claude {
+		t.Errorf("claudeDirCandidates() = %v, want only %s", got, claude)
+	}
+}
+
+func TestLoadConfig_YAMLAndTOML(t *testing.T) {
+	origConfig := configPath
+	t.Cleanup(func() { configPath = origConfig })
+
+	files := map[string]string{
+		"config.yaml": "claude_dir: /data/.claude\nlast_update_check: 1729000000\nclaude_dirs:\n  - /work/.claude\ntheme:\n  preset: light\n",
+		"config.toml": "claude_dir = \"/data/.claude\"\nlast_update_check = 1729000000\nclaude_dirs = [\"/work/.claude\"]\n\n[theme]\npreset = \"light\"\n",
+	}
+	for name, content := range files {
+		dir := t.TempDir()
+		configPath = filepath.Join(dir, "config.json")
+		// A stale config.json must not shadow the hand-written file.
+		os.WriteFile(configPath, []byte(`{"claude_dir":"/old"}`), 0644)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, path, err := loadConfig()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if filepath.Base(path) != name || filepath.Base(configPath) != "config.json" {
+			t.Errorf("loaded %s (configPath %s), want %s without touching configPath", path, configPath, name)
+		}
+		if cfg.ClaudeDir != "/data/.claude" || cfg.LastUpdateCheck != 1729000000 ||
+			len(cfg.ClaudeDirs) != 1 || cfg.Theme == nil || cfg.Theme.Preset != "light" {
+			t.Errorf("%s decoded to %+v", name, cfg)
+		}
+
+		// What the app changes goes to the state file; the config stays as written.
+		if err := saveState("last_update_check", int64(1729000001)); err != nil {
+			t.Fatalf("%s: save: %v", name, err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != content {
+			t.Errorf("%s was rewritten:\n%s", name, data)
+		}
+		if again, _, err := loadConfig(); err != nil || again.LastUpdateCheck != 1729000001 || again.Theme.Preset != "light" {
+			t.Errorf("%s reload = %+v, %v", name, again, err)
+		}
+	}
+}
+
+func TestMeasureChats_InOrder(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	os.MkdirAll(dir, 0755)
+	var chats []Chat
+	for i, size := range []int{300, 10, 2000, 50} {
+		uuid := fmt.Sprintf("chat-%d", i)
+		if err := os.WriteFile(filepath.Join(dir, uuid+".jsonl"), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		chats = append(chats, Chat{UUID: uuid, Project: "proj"})
+	}
+
+	var got []string
+	measureChats(chats, func(chat Chat, size int64) {
+		got = append(got, fmt.Sprintf("%s=%d", chat.UUID, size))
+	})
+	if want := "chat-0=300,chat-1=10,chat-2=2000,chat-3=50"; strings.Join(got, ",") != want {
+		t.Errorf("measureChats emitted %v, want %s", got, want)
+	}
+
+	// A plan counts toward its chat only while no other chat uses it
+	os.MkdirAll(plansDir, 0755)
+	for _, slug := range []string{"own", "shared"} {
+		os.WriteFile(filepath.Join(plansDir, slug+".md"), make([]byte, 1000), 0644)
+	}
+	transcripts := map[string]string{"a": "own", "b": "shared", "c": "shared"}
+	for uuid, slug := range transcripts {
+		os.WriteFile(filepath.Join(dir, uuid+".jsonl"), []byte(`{"slug":"`+slug+`"}`), 0644)
+	}
+	got = nil
+	measureChats([]Chat{{UUID: "a", Project: "proj"}, {UUID: "b", Project: "proj"}}, func(chat Chat, size int64) {
+		got = append(got, fmt.Sprintf("%s=%d", chat.UUID, size))
+	})
+	if want := "a=1014,b=17"; strings.Join(got, ",") != want {
+		t.Errorf("measureChats with plans emitted %v, want %s", got, want)
+	}
+}
+
+func TestLargestFirst(t *testing.T) {
+	chats := []Chat{{UUID: "small"}, {UUID: "big"}, {UUID: "tie-1"}, {UUID: "tie-2"}}
+	sorted, sizes := largestFirst(chats, []int64{10, 300, 50, 50})
+	var got []string
+	for _, c := range sorted {
+		got = append(got, c.UUID)
+	}
+	if want := []string{"big", "tie-1", "tie-2", "small"}; !slices.Equal(got, want) || !slices.Equal(sizes, []int64{300, 50, 50, 10}) {
+		t.Errorf("largestFirst = %v %v, want %v with the sizes alongside, ties in list order", got, sizes, want)
+	}
+}
+
+func TestRelatedIndex_ReuseAndInvalidate(t *testing.T) {
+	setupStorageDirs(t)
+	origConfig := configPath
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	t.Cleanup(func() { configPath = origConfig })
+
+	dir := filepath.Join(projectsDir, "proj")
+	os.MkdirAll(dir, 0755)
+	var chats []Chat
+	for _, uuid := range []string{"a", "b"} {
+		path := filepath.Join(dir, uuid+".jsonl")
+		os.WriteFile(path, []byte(`{"type":"user"}`+"\n"), 0644)
+		os.WriteFile(filepath.Join(debugDir, uuid+".txt"), []byte("log"), 0644)
+		chats = append(chats, Chat{UUID: uuid, Project: "proj", Path: path})
+	}
+	if err := resolveRelatedFiles(chats, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(chats[0].Files) != 2 {
+		t.Fatalf("expected chat file and debug file, got %v", chats[0].Files)
+	}
+
+	// Plant a marker in the stored entries to tell reuse from recomputation.
+	mark := func() {
+		idx := loadRelatedIndex()
+		for _, root := range idx.Roots {
+			for key, e := range root.Chats {
+				e.Files = []string{"cached:" + key}
+				root.Chats[key] = e
+			}
+		}
+		idx.save()
+	}
+	mark()
+	resolveRelatedFiles(chats, false)
+	if chats[1].Files[0] != "cached:proj/b" {
+		t.Errorf("unchanged directories should reuse the index, got %v", chats[1].Files)
+	}
+	resolveRelatedFiles(chats, true)
+	if len(chats[1].Files) != 2 {
+		t.Errorf("refresh should recompute, got %v", chats[1].Files)
+	}
+
+	// Deleting a through the index keeps b's entry valid.
+	mark()
+	resolveRelatedFiles(chats[:1], false)
+	os.Remove(filepath.Join(dir, "a.jsonl"))
+	os.Remove(filepath.Join(debugDir, "a.txt"))
+	forgetRelatedFiles(chats[:1])
+	resolveRelatedFiles(chats[1:], false)
+	if chats[1].Files[0] != "cached:proj/b" {
+		t.Errorf("own deletions should not invalidate other entries, got %v", chats[1].Files)
+	}
+
+	// Anything else touching the directories does.
+	os.WriteFile(filepath.Join(debugDir, "other.txt"), []byte("x"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(debugDir, later, later)
+	resolveRelatedFiles(chats[1:], false)
+	if len(chats[1].Files) != 2 {
+		t.Errorf("a changed directory should invalidate the index, got %v", chats[1].Files)
+	}
+}
+
+func TestAssumeYes(t *testing.T) {
+	t.Setenv(assumeYesEnv, "")
+	if assumeYes(false) || !assumeYes(true) {
+		t.Error("without the env var only -yes should skip the confirmation")
+	}
+	t.Setenv(assumeYesEnv, "1")
+	if !assumeYes(false) {
+		t.Errorf("%s=1 should skip the confirmation", assumeYesEnv)
+	}
+	t.Setenv(assumeYesEnv, "true")
+	if assumeYes(false) {
+		t.Errorf("only %s=1 should count", assumeYesEnv)
+	}
+}
+
+func TestTitleMatcher(t *testing.T) {
+	tests := []struct {
+		pattern, title string
+		want           bool
+	}{
+		{"scratch", "Scratch: try the parser", true},
+		{"scratch", "Fix login bug", false},
+		{"/^tmp-[0-9]+/", "tmp-42 quick test", true},
+		{"/^tmp-[0-9]+/", "old tmp-42", false},
+		{"/", "a/b", true},
+	}
+	for _, tt := range tests {
+		match, err := titleMatcher(tt.pattern)
+		if err != nil {
+			t.Fatalf("titleMatcher(%q): %v", tt.pattern, err)
+		}
+		if got := match(tt.title); got != tt.want {
+			t.Errorf("titleMatcher(%q)(%q) = %v, want %v", tt.pattern, tt.title, got, tt.want)
+		}
+	}
+	if _, err := titleMatcher("/(/"); err == nil {
+		t.Error("malformed regex should be an error")
+	}
+}
+
+func TestFindAndRemoveStaleIndexEntries(t *testing.T) {
+	setupStorageDirs(t)
+	dir := filepath.Join(projectsDir, "proj")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "live.jsonl"), []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	index := `{"version":1,"entries":[{"sessionId":"live"},{"sessionId":"gone","fullPath":"/nonexistent/gone.jsonl"}],"originalPath":"/work/proj"}`
+	indexPath := filepath.Join(dir, "sessions-index.json")
+	if err := os.WriteFile(indexPath, []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := findStaleIndexEntries(primaryRoot())
+	if len(stale) != 1 || stale[0].SessionID != "gone" || stale[0].Project != "proj" {
+		t.Fatalf("stale = %+v, want only gone in proj", stale)
+	}
+
+	n, err := removeIndexEntries(primaryRoot(), "proj", map[string]bool{"gone": true})
+	if err != nil || n != 1 {
+		t.Fatalf("removeIndexEntries = %d, %v", n, err)
+	}
+	if findIndexEntry(primaryRoot(), "gone", "proj") != nil || findIndexEntry(primaryRoot(), "live", "proj") == nil {
+		t.Error("wrong entries left in the index")
+	}
+	data, _ := os.ReadFile(indexPath)
+	if !strings.Contains(string(data), `"originalPath": "/work/proj"`) {
+		t.Errorf("index header lost:\n%s", data)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, ".sessions-index.json.tmp-*")); len(leftovers) > 0 {
+		t.Errorf("temp files left behind: %v", leftovers)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ThemeConfig selects a color preset and optionally overrides single roles.
+// Colors are lipgloss color strings: ANSI codes ("6", "226") or hex ("#ff8800").
+type ThemeConfig struct {
+	Preset   string `json:"preset,omitempty"` // dark (default), light, mono
+	Title    string `json:"title,omitempty"`  // active tab background
+	Selected string `json:"selected,omitempty"`
+	Cursor   string `json:"cursor,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Success  string `json:"success,omitempty"`
+	Warning  string `json:"warning,omitempty"` // chats with unreadable files
+	Help     string `json:"help,omitempty"`    // help line, dimmed text, inactive tabs
+}
+
+// themeColor is a rich 256-color code with a basic 16-color fallback (see
+// adaptiveColor). An empty rich value means "no color": the role falls back
+// to reverse/bold so it stays visible on any terminal.
+type themeColor struct {
+	rich, fallback string
+}
+
+type themePalette struct {
+	title, selected, cursor, err, success, warning, help, dim themeColor
+}
+
+// themePresets; "dark" reproduces the built-in styles in model.go.
+var themePresets = map[string]themePalette{
+	"dark": {
+		title:    themeColor{"6", "6"},
+		selected: themeColor{"226", "11"},
+		err:      themeColor{"196", "9"},
+		success:  themeColor{"46", "10"},
+		warning:  themeColor{"214", "3"},
+		help:     themeColor{"241", "8"},
+		dim:      themeColor{"240", "8"},
+	},
+	"light": {
+		title:    themeColor{"25", "4"},
+		selected: themeColor{"222", "3"},
+		err:      themeColor{"160", "1"},
+		success:  themeColor{"28", "2"},
+		warning:  themeColor{"130", "3"},
+		help:     themeColor{"244", "8"},
+		dim:      themeColor{"245", "8"},
+	},
+	"mono": {},
+}
+
+// applyTheme rebuilds the global styles from cfg. A nil cfg restores the
+// default dark theme, so it is safe to call again after a config reload.
+func applyTheme(cfg *ThemeConfig) error {
+	name := "dark"
+	if cfg != nil && cfg.Preset != "" {
+		name = strings.ToLower(cfg.Preset)
+	}
+	p, ok := themePresets[name]
+	if !ok {
+		var names []string
+		for n := range themePresets {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown theme preset %q (available: %s)", cfg.Preset, strings.Join(names, ", "))
+	}
+
+	if cfg != nil {
+		override := func(c *themeColor, value string) {
+			if value != "" {
+				*c = themeColor{value, value}
+			}
+		}
+		override(&p.title, cfg.Title)
+		override(&p.selected, cfg.Selected)
+		override(&p.cursor, cfg.Cursor)
+		override(&p.err, cfg.Error)
+		override(&p.success, cfg.Success)
+		override(&p.warning, cfg.Warning)
+		override(&p.help, cfg.Help)
+		if cfg.Help != "" {
+			p.dim = p.help
+		}
+	}
+
+	activeTabStyle = filled(lipgloss.NewStyle().Bold(true).Padding(0, 1), p.title)
+	inactiveTabStyle = foreground(lipgloss.NewStyle().Padding(0, 1), p.help)
+	selectedStyle = filled(lipgloss.NewStyle().Bold(true), p.selected)
+	if p.selected.rich == "" {
+		// The cursor already uses reverse video; underline keeps them apart.
+		selectedStyle = lipgloss.NewStyle().Bold(true).Underline(true)
+	}
+	cursorStyle = filled(lipgloss.NewStyle(), p.cursor)
+	dimStyle = foreground(lipgloss.NewStyle(), p.dim)
+	errorStyle = foreground(lipgloss.NewStyle().Bold(true), p.err)
+	successStyle = foreground(lipgloss.NewStyle().Bold(true), p.success)
+	warningStyle = foreground(lipgloss.NewStyle(), p.warning)
+	helpStyle = foreground(lipgloss.NewStyle(), p.help)
+	return nil
+}
+
+// filled paints c as a bar with black text, or reverses video when c is unset.
+func filled(s lipgloss.Style, c themeColor) lipgloss.Style {
+	if c.rich == "" {
+		return s.Reverse(true)
+	}
+	return s.Background(adaptiveColor(c.rich, c.fallback)).
+		Foreground(adaptiveColor("0", "0"))
+}
+
+// foreground colors the text with c; unset leaves the terminal default.
+func foreground(s lipgloss.Style, c themeColor) lipgloss.Style {
+	if c.rich == "" {
+		return s
+	}
+	return s.Foreground(adaptiveColor(c.rich, c.fallback))
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// The trash keeps chats recoverable: instead of being removed, a chat's files
+// are moved into a batch directory under ~/.config/claude-chats/trash/ together
+// with a manifest recording where each file came from and the chat's
+// sessions-index.json entry, so restoreTrashBatch can put everything back.
+
+const trashManifestName = "manifest.json"
+
+// trashDir returns the directory holding trash batches.
+func trashDir() string {
+	return filepath.Join(filepath.Dir(configPath), "trash")
+}
+
+// trashManifest describes one trash batch.
+type trashManifest struct {
+	Created time.Time     `json:"created"`
+	Reason  string        `json:"reason"` // e.g. "auto-purge"
+	Chats   []trashedChat `json:"chats"`
+}
+
+type trashedChat struct {
+	UUID       string        `json:"uuid"`
+	Title      string        `json:"title"`
+	Project    string        `json:"project"`
+	Root       string        `json:"root,omitempty"` // Claude directory; empty means claude_dir
+	Files      []trashedFile `json:"files"`
+	IndexEntry *SessionEntry `json:"index_entry,omitempty"`
+}
+
+// trashedFile maps an original path to its location inside the batch
+// directory (relative to it). Each chat's files are kept under a directory
+// of their own, files/<n>/ for the batch's nth chat, since chats of two
+// Claude directories can have files at the same relative path.
+type trashedFile struct {
+	Original string `json:"original"`
+	Stored   string `json:"stored"`
+}
+
+// moveChatsToTrash moves every related file of chats into a new trash batch
+// and drops their sessions-index.json entries. The manifest is written even
+// when a move fails part-way, so whatever was moved stays restorable. Like
+// deleteChats it stops before the next chat once stop is set.
+func moveChatsToTrash(chats []Chat, reason string, stop *atomic.Bool) (batch string, res deleteResult, err error) {
+	batch = time.Now().Format("20060102-150405")
+	batchDir := filepath.Join(trashDir(), batch)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(batchDir); os.IsNotExist(err) {
+			break
+		}
+		batch = fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405"), n)
+		batchDir = filepath.Join(trashDir(), batch)
+	}
+	if err := os.MkdirAll(batchDir, 0755); err != nil {
+		return "", res, err
+	}
+
+	manifest := trashManifest{Created: time.Now(), Reason: reason}
+	defer func() {
+		if werr := writeTrashManifest(batchDir, manifest); werr != nil && err == nil {
+			err = werr
+		}
+	}()
+
+	for i, chat := range chats {
+		if stop != nil && stop.Load() {
+			break
+		}
+		tc, err := trashChat(chat, batchDir, filepath.Join("files", strconv.Itoa(i)), &res)
+		manifest.Chats = append(manifest.Chats, tc)
+		if err != nil {
+			res.Failed++
+			return batch, res, err
+		}
+		res.Deleted++
+	}
+	return batch, res, nil
+}
+
+// trashChat moves one chat's files into dir inside batchDir, working within
+// the chat's own Claude directory. The returned record lists whatever was
+// moved, also on error.
+func trashChat(chat Chat, batchDir, dir string, res *deleteResult) (trashedChat, error) {
+	root := chat.root()
+	tc := trashedChat{UUID: chat.UUID, Title: chat.Title, Project: chat.Project, Root: chat.Root.dir,
+		IndexEntry: findIndexEntry(root, chat.UUID, chat.Project)}
+
+	files := findRelatedFiles(root, chat.UUID, chat.Project)
+	res.noteShared(chat, files)
+	removed, freed := len(res.removed), res.FreedBytes
+	for _, file := range files {
+		// Nested entries (tool-results inside the chat dir) moved with their parent
+		if _, err := os.Lstat(file); os.IsNotExist(err) {
+			continue
+		}
+		stored := filepath.Join(dir, trashRelPath(root, file))
+		size := pathSize(file)
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(batchDir, stored)), 0755); err != nil {
+			return tc, err
+		}
+		if err := retryBusy(func() error { return moveFile(file, filepath.Join(batchDir, stored)) }); err != nil {
+			return tc, fmt.Errorf("failed to move %s to trash: %w", file, err)
+		}
+		tc.Files = append(tc.Files, trashedFile{Original: file, Stored: stored})
+		res.FreedBytes += size
+		res.removed = append(res.removed, file)
+	}
+
+	if err := updateSessionsIndex(root, chat.UUID, chat.Project); err != nil {
+		return tc, fmt.Errorf("failed to update index: %w", err)
+	}
+	res.record(chat, removed, freed, filepath.Base(batchDir))
+	return tc, nil
+}
+
+// moveFile moves the file or directory tree src to dst, which must not
+// exist: a rename would silently replace a file there. A rename keeps the
+// modification times, which the list shows and sorts by; when src and dst
+// are on different filesystems (a separate /home or ~/.config mount) the
+// tree is copied with its modes and times instead and src removed. Access
+// times are set to the modification times.
+func moveFile(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s already exists", dst)
+	}
+	err := os.Rename(src, dst)
+	if !isCrossDevice(err) {
+		return err
+	}
+	if err := copyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// isCrossDevice reports whether a rename failed because the paths are on
+// different filesystems.
+func isCrossDevice(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return errno == 17 // ERROR_NOT_SAME_DEVICE
+	}
+	return errno == syscall.EXDEV
+}
+
+// copyTree copies src to dst, recursing into directories, and gives every
+// copy the mode and modification time of its original. Directory times are
+// set last, since filling a directory changes its time.
+func copyTree(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		// Chtimes below would follow the link and stamp its target, so a
+		// link keeps the time of its creation.
+		return os.Symlink(target, dst)
+	case info.IsDir():
+		if err := os.Mkdir(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyTree(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := copyFile(src, dst); err != nil {
+			return err
+		}
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// trashRelPath is where file is kept inside a batch: its path relative to the
+// Claude directory root, or just its name when it lives elsewhere.
+func trashRelPath(root claudeRoot, file string) string {
+	if rel, err := filepath.Rel(root.dir, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return filepath.Base(file)
+}
+
+func writeTrashManifest(batchDir string, manifest trashManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(batchDir, trashManifestName), data, 0644)
+}
+
+func readTrashManifest(batch string) (trashManifest, error) {
+	var manifest trashManifest
+	data, err := os.ReadFile(filepath.Join(trashDir(), batch, trashManifestName))
+	if err != nil {
+		return manifest, err
+	}
+	err = json.Unmarshal(data, &manifest)
+	return manifest, err
+}
+
+// restoreTrashBatch moves a batch's files back to their original locations,
+// re-inserts the saved index entries and removes the batch. It stops at the
+// first file whose original path is occupied again, leaving the rest in the
+// trash; files an earlier, interrupted restore already moved back are
+// skipped, so it can simply be run again.
+func restoreTrashBatch(batch string) (restored int, err error) {
+	manifest, err := readTrashManifest(batch)
+	if err != nil {
+		return 0, fmt.Errorf("cannot read trash batch %s: %w", batch, err)
+	}
+	batchDir := filepath.Join(trashDir(), batch)
+
+	for _, chat := range manifest.Chats {
+		for _, f := range chat.Files {
+			stored := filepath.Join(batchDir, f.Stored)
+			if _, err := os.Lstat(f.Original); err == nil {
+				if _, err := os.Lstat(stored); os.IsNotExist(err) {
+					continue // restored by an earlier run
+				}
+				return restored, fmt.Errorf("cannot restore %s: path already exists", f.Original)
+			}
+			if err := os.MkdirAll(filepath.Dir(f.Original), 0755); err != nil {
+				return restored, err
+			}
+			if err := moveFile(stored, f.Original); err != nil {
+				return restored, fmt.Errorf("cannot restore %s: %w", f.Original, err)
+			}
+		}
+		if chat.IndexEntry != nil {
+			if err := addIndexEntry(rootOf(chat.Root), chat.Project, *chat.IndexEntry); err != nil {
+				return restored, fmt.Errorf("failed to update index: %w", err)
+			}
+		}
+		restored++
+	}
+	return restored, os.RemoveAll(batchDir)
+}
+
+// addIndexEntry appends entry to the sessions-index.json of project in root
+// unless it is already listed. A project without an index is left without
+// one.
+func addIndexEntry(root claudeRoot, project string, entry SessionEntry) error {
+	indexPath := filepath.Join(root.projects, project, "sessions-index.json")
+	data, err := os.ReadFile(indexPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var index SessionsIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return err
+	}
+	for _, e := range index.Entries {
+		if e.SessionID == entry.SessionID {
+			return nil
+		}
+	}
+	index.Entries = append(index.Entries, entry)
+	data, err = json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(indexPath, data, 0644)
+}
+
+// defaultPurgeBelowLines is the auto_purge_empty threshold when
+// purge_below_lines is not set: chats with fewer lines hold no conversation.
+const defaultPurgeBelowLines = 3
+
+// purgeMinAge keeps auto-purge away from sessions that may still be open.
+const purgeMinAge = time.Hour
+
+// purgeEmptyMaxBytes caps the transcript of an empty session auto-purge
+// trashes whatever its line count: file-history snapshots stay small, so a
+// bigger "empty" chat is more likely one whose messages the scan misread.
+const purgeEmptyMaxBytes = 256 << 10
+
+// purgeCandidates returns the chats auto_purge_empty would trash: fewer than
+// below lines, or empty sessions (see Chat.Empty) of at most
+// purgeEmptyMaxBytes, that are not protected and idle for at least
+// purgeMinAge.
+func purgeCandidates(chats []Chat, below int, now time.Time) []Chat {
+	var out []Chat
+	for _, chat := range chats {
+		if chat.Protected || now.Sub(chat.Time) < purgeMinAge {
+			continue
+		}
+		if chat.LineCount >= below && !(chat.Empty && smallTranscript(chat)) {
+			continue
+		}
+		out = append(out, chat)
+	}
+	return out
+}
+
+// smallTranscript reports whether the chat's JSONL is at most
+// purgeEmptyMaxBytes; a transcript that can't be measured is not.
+func smallTranscript(chat Chat) bool {
+	info, err := os.Stat(chat.Path)
+	return err == nil && info.Size() <= purgeEmptyMaxBytes
+}
+
+// autoPurgeEmpty moves every purge candidate to a new trash batch. It returns
+// the number of chats moved and the batch name (empty when nothing matched).
+func autoPurgeEmpty(below int, protect []string) (count int, batch string, err error) {
+	chats, err := findAllChats()
+	if err != nil {
+		return 0, "", err
+	}
+	markProtected(chats, protect)
+	candidates := purgeCandidates(chats, below, time.Now())
+	if len(candidates) == 0 {
+		return 0, "", nil
+	}
+	batch, res, err := moveChatsToTrash(candidates, "auto-purge", nil)
+	if lerr := appendDeletionLog(res.records); lerr != nil && err == nil {
+		err = fmt.Errorf("could not write the deletion log: %w", lerr)
+	}
+	return res.Deleted, batch, err
+}
+
+// trashBatch summarizes one batch directory for emptying the trash.
+type trashBatch struct {
+	Name    string
+	Created time.Time
+	Reason  string
+	Chats   int
+	Size    int64
+	Titles  []string // "title (project)" of every chat, for the Trash tab
+}
+
+// listTrashBatches returns every batch in the trash, oldest first. A batch
+// whose manifest is unreadable is still listed, dated by its directory.
+func listTrashBatches() ([]trashBatch, error) {
+	entries, err := os.ReadDir(trashDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var batches []trashBatch
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		b := trashBatch{Name: entry.Name(), Size: pathSize(filepath.Join(trashDir(), entry.Name()))}
+		if manifest, err := readTrashManifest(entry.Name()); err == nil {
+			b.Created, b.Reason, b.Chats = manifest.Created, manifest.Reason, len(manifest.Chats)
+			for _, chat := range manifest.Chats {
+				b.Titles = append(b.Titles, fmt.Sprintf("%s (%s)", chat.Title, chat.Project))
+			}
+		} else if info, err := entry.Info(); err == nil {
+			b.Created = info.ModTime()
+		}
+		batches = append(batches, b)
+	}
+	sort.Slice(batches, func(i, j int) bool { return batches[i].Created.Before(batches[j].Created) })
+	return batches, nil
+}
+
+// expiredTrashBatches returns the batches older than days (trash_retention_days).
+func expiredTrashBatches(batches []trashBatch, days int, now time.Time) []trashBatch {
+	cutoff := now.AddDate(0, 0, -days)
+	var out []trashBatch
+	for _, b := range batches {
+		if b.Created.Before(cutoff) {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// removeTrashBatches permanently deletes batches, continuing past failures.
+// It returns how many were removed, the bytes freed and the first error.
+func removeTrashBatches(batches []trashBatch) (removed int, freed int64, err error) {
+	for _, b := range batches {
+		if rerr := os.RemoveAll(filepath.Join(trashDir(), b.Name)); rerr != nil {
+			if err == nil {
+				err = rerr
+			}
+			continue
+		}
+		removed++
+		freed += b.Size
+	}
+	return removed, freed, err
+}
+
+// trashTotal sums the chats and bytes of batches.
+func trashTotal(batches []trashBatch) (chats int, size int64) {
+	for _, b := range batches {
+		chats += b.Chats
+		size += b.Size
+	}
+	return chats, size
+}
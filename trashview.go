@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The Trash tab lists the trash batches, newest first, with the chats each
+// one holds, so trashed chats can be restored or deleted for good without
+// -restore-trash and -empty-trash. A batch is what one deletion moved to the
+// trash and is restored as a whole, so batches are what the tab selects.
+
+// trashTitlesMax is how many chat titles the Trash tab lists under a batch
+// before collapsing the rest into "... and N more".
+const trashTitlesMax = 3
+
+// trashLoadedMsg carries the trash batches read for the tab (see loadTrash).
+type trashLoadedMsg struct {
+	batches []trashBatch
+	err     error
+}
+
+// trashRestoredMsg reports a restore started on the tab (see restoreTrash).
+type trashRestoredMsg struct {
+	restored, batches int
+	err               error
+}
+
+// loadTrash reads the trash batches in the background; sizing them walks
+// every file in the trash.
+func (m *model) loadTrash() tea.Cmd {
+	m.trashLoading = true
+	return func() tea.Msg {
+		batches, err := listTrashBatches()
+		return trashLoadedMsg{batches: batches, err: err}
+	}
+}
+
+// setTrash shows the batches loadTrash read, keeping the cursor in range and
+// the marks of batches still there.
+func (m *model) setTrash(msg trashLoadedMsg) {
+	m.trashLoading = false
+	if msg.err != nil {
+		m.trashStatus = "Error: " + msg.err.Error()
+	}
+	batches := msg.batches
+	slices.Reverse(batches)
+	m.trashBatches = batches
+	marked := make(map[string]bool)
+	for _, b := range batches {
+		if m.trashSelected[b.Name] {
+			marked[b.Name] = true
+		}
+	}
+	m.trashSelected = marked
+	m.trashCursor = max(min(m.trashCursor, len(batches)-1), 0)
+}
+
+// trashTargets returns the marked batches, or the one under the cursor when
+// none is marked.
+func (m model) trashTargets() []trashBatch {
+	var targets []trashBatch
+	for _, b := range m.trashBatches {
+		if m.trashSelected[b.Name] {
+			targets = append(targets, b)
+		}
+	}
+	if len(targets) == 0 && m.trashCursor < len(m.trashBatches) {
+		targets = append(targets, m.trashBatches[m.trashCursor])
+	}
+	return targets
+}
+
+// updateTrash handles keys on the Trash tab. Deleting for good goes through
+// the "Empty trash" confirmation (trashPending) with just the chosen batches.
+func (m model) updateTrash(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.trashCursor > 0 {
+			m.trashCursor--
+		}
+	case "down", "j":
+		if m.trashCursor < len(m.trashBatches)-1 {
+			m.trashCursor++
+		}
+	case "g", "home":
+		m.trashCursor = 0
+	case "G", "end":
+		m.trashCursor = max(len(m.trashBatches)-1, 0)
+	case " ":
+		if m.trashCursor < len(m.trashBatches) {
+			name := m.trashBatches[m.trashCursor].Name
+			if m.trashSelected[name] {
+				delete(m.trashSelected, name)
+			} else {
+				m.trashSelected[name] = true
+			}
+		}
+	case "r", "enter":
+		return m.restoreTrash()
+	case "d", "P":
+		switch {
+		case m.readOnly:
+			m.trashStatus = "read-only mode: deleting from the trash is disabled"
+		case m.restoring:
+			m.trashStatus = "restore in progress: deleting from the trash is disabled until it finishes"
+		case m.emptying:
+			m.trashStatus = "deleting from the trash is in progress"
+		case m.updating:
+			m.trashStatus = "update in progress: deleting from the trash is disabled until it finishes"
+		default:
+			m.trashPending = m.trashTargets()
+			m.trashStatus = ""
+		}
+	}
+	return m, nil
+}
+
+// restoreTrash puts the chosen batches back in the background (see
+// restoreTrashBatch); finishRestore takes it from there.
+func (m model) restoreTrash() (tea.Model, tea.Cmd) {
+	targets := m.trashTargets()
+	if len(targets) == 0 || m.restoring || m.emptying {
+		return m, nil
+	}
+	m.restoring = true
+	m.trashStatus = fmt.Sprintf("Restoring %d batch(es)...", len(targets))
+	return m, func() tea.Msg {
+		var msg trashRestoredMsg
+		for _, b := range targets {
+			n, err := restoreTrashBatch(b.Name)
+			msg.restored += n
+			if err != nil {
+				msg.err = err
+				break
+			}
+			msg.batches++
+		}
+		return msg
+	}
+}
+
+// finishRestore reports a restore and rescans the chats so the restored
+// ones show up in the list again.
+func (m model) finishRestore(msg trashRestoredMsg) (tea.Model, tea.Cmd) {
+	m.restoring = false
+	m.trashStatus = fmt.Sprintf("✓ Restored %d chat(s) from %d batch(es)", msg.restored, msg.batches)
+	if msg.err != nil {
+		m.trashStatus = fmt.Sprintf("Restored %d chat(s), then: %v", msg.restored, msg.err)
+	}
+	reload := m.loadTrash()
+	if msg.restored == 0 {
+		return m, reload
+	}
+	m.loadChats()
+	m.selected = make(map[int]bool)
+	m.autoSelected = false
+	m.cursor = 0
+	m.scrollOffset = 0
+	if m.chatSizes != nil {
+		return m, tea.Batch(reload, m.startSizes(true))
+	}
+	return m, reload
+}
+
+// trashPendingQuestion asks to confirm deleting trashPending for good.
+func (m model) trashPendingQuestion() string {
+	chats, size := trashTotal(m.trashPending)
+	return errorStyle.Render(fmt.Sprintf("Delete %d chat(s) in %d batch(es) for good, freeing %s?", chats, len(m.trashPending), formatBytes(size))) +
+		" " + helpStyle.Render("[ENTER=Yes] [ESC/n/q=No]")
+}
+
+// trashRows renders the batches, each followed by the titles of its chats,
+// and returns the row of every batch line.
+func (m model) trashRows(width int) (rows []string, batchRow []int) {
+	for i, b := range m.trashBatches {
+		indicator := "[ ]"
+		if m.trashSelected[b.Name] {
+			indicator = "[✓]"
+		}
+		reason := b.Reason
+		if reason == "" {
+			reason = b.Name
+		}
+		line := fmt.Sprintf("%s %s  %3d chat(s)  %9s  %s", indicator, formatTimestamp(b.Created), b.Chats, formatBytes(b.Size), reason)
+		line = displayTitle(line, width)
+		switch {
+		case i == m.trashCursor:
+			line = cursorStyle.Render(line)
+		case m.trashSelected[b.Name]:
+			line = selectedStyle.Render(line)
+		}
+		batchRow = append(batchRow, len(rows))
+		rows = append(rows, line)
+
+		for n, title := range b.Titles {
+			if n == trashTitlesMax {
+				rows = append(rows, dimStyle.Render(fmt.Sprintf("      ... and %d more", len(b.Titles)-trashTitlesMax)))
+				break
+			}
+			rows = append(rows, "      "+displayTitle("• "+title, width-6))
+		}
+	}
+	return rows, batchRow
+}
+
+func (m model) viewTrash() string {
+	width := m.width
+	if width < 75 {
+		width = 75
+	}
+
+	var s strings.Builder
+	s.WriteString(m.renderTabBar())
+	s.WriteString("\n")
+	s.WriteString(dimStyle.Render(strings.Repeat("─", width)))
+	s.WriteString("\n")
+	chats, size := trashTotal(m.trashBatches)
+	s.WriteString(dimStyle.Render(fmt.Sprintf("  %d batch(es), %d chat(s), %s in %s", len(m.trashBatches), chats, formatBytes(size), trashDir())))
+	s.WriteString("\n")
+
+	// title(1) + separator(1) + summary(1) + separator(1) + help(1)
+	height := max(m.height-5, 1)
+	rows, batchRow := m.trashRows(width)
+	switch {
+	case len(rows) == 0 && m.trashLoading:
+		rows = []string{"", "  Reading the trash..."}
+	case len(rows) == 0:
+		rows = []string{"", "  The trash is empty. Chats deleted with \"Move to trash\" on show up here."}
+	}
+	// Scroll just far enough to show the cursor's batch with its titles
+	start := 0
+	if m.trashCursor < len(batchRow) {
+		end := len(rows)
+		if m.trashCursor+1 < len(batchRow) {
+			end = batchRow[m.trashCursor+1]
+		}
+		start = min(max(end-height, 0), batchRow[m.trashCursor])
+	}
+	end := min(start+height, len(rows))
+	for _, row := range rows[start:end] {
+		s.WriteString(row)
+		s.WriteString("\n")
+	}
+
+	s.WriteString(dimStyle.Render(strings.Repeat("─", width)))
+	s.WriteString("\n")
+	switch {
+	case m.trashPending != nil:
+		s.WriteString(m.trashPendingQuestion())
+	case m.trashStatus != "":
+		s.WriteString(dimStyle.Render(m.trashStatus))
+	default:
+		s.WriteString(helpStyle.Render("↑/↓:Navigate | Space:Mark | r/Enter:Restore | d:Delete for good | u:Chats | ←/→:Switch tabs | q:Quit"))
+	}
+	s.WriteString("\n")
+	return s.String()
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -17,10 +21,36 @@ const (
 	GitHubAPIURL   = "https://api.github.com/repos/ataleckij/claude-chats-delete/releases/latest"
 )
 
+// updateURLEnv overrides GitHubAPIURL, e.g. to test against a mock server or
+// to point at an internal mirror of the releases API.
+const updateURLEnv = "CLAUDE_CHATS_UPDATE_URL"
+
+// Update channels (the update_channel config). Stable only sees full
+// releases; beta also sees pre-releases.
+const (
+	channelStable = "stable"
+	channelBeta   = "beta"
+)
+
+// updateAPIURL returns the releases endpoint to query for updates. The beta
+// channel lists all releases: the same URL without its trailing /latest.
+func updateAPIURL(channel string) string {
+	url := GitHubAPIURL
+	if override := os.Getenv(updateURLEnv); override != "" {
+		url = override
+	}
+	if channel == channelBeta {
+		url = strings.TrimSuffix(url, "/latest")
+	}
+	return url
+}
+
 // GitHubRelease represents the GitHub API response for a release
 type GitHubRelease struct {
-	TagName string `json:"tag_name"` // e.g. "v0.1.6"
-	HTMLURL string `json:"html_url"`
+	TagName    string `json:"tag_name"` // e.g. "v0.1.6" or "v0.2.0-beta.1"
+	HTMLURL    string `json:"html_url"`
+	Prerelease bool   `json:"prerelease"`
+	Draft      bool   `json:"draft"`
 }
 
 // shouldCheckUpdate returns true if enough time has passed since last check
@@ -33,11 +63,11 @@ func shouldCheckUpdate(lastCheck int64, intervalHours int) bool {
 	return hoursSinceCheck >= float64(intervalHours)
 }
 
-// checkForUpdate queries GitHub API for the latest release
+// checkForUpdate queries GitHub API for the latest release on channel
 // Returns the new version string (without 'v' prefix) if update is available, empty string otherwise
-func checkForUpdate() string {
+func checkForUpdate(channel string) string {
 	client := &http.Client{Timeout: 3 * time.Second}
-	resp, err := client.Get(GitHubAPIURL)
+	resp, err := client.Get(updateAPIURL(channel))
 	if err != nil {
 		return "" // Silently fail on network errors
 	}
@@ -47,49 +77,125 @@ func checkForUpdate() string {
 		return ""
 	}
 
-	var release GitHubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return ""
+	var latestVersion string
+	if channel == channelBeta {
+		var releases []GitHubRelease
+		if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+			return ""
+		}
+		latestVersion = newestRelease(releases)
+	} else {
+		var release GitHubRelease
+		if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+			return ""
+		}
+		latestVersion = strings.TrimPrefix(release.TagName, "v")
 	}
 
-	latestVersion := strings.TrimPrefix(release.TagName, "v")
-	if isNewerVersion(latestVersion, CurrentVersion) {
+	if latestVersion != "" && isNewerVersion(latestVersion, CurrentVersion) {
 		return latestVersion
 	}
 
 	return ""
 }
 
+// newestRelease returns the highest version among releases, pre-releases
+// included and drafts skipped, without the 'v' prefix.
+func newestRelease(releases []GitHubRelease) string {
+	newest := ""
+	for _, r := range releases {
+		if r.Draft {
+			continue
+		}
+		v := strings.TrimPrefix(r.TagName, "v")
+		if newest == "" || isNewerVersion(v, newest) {
+			newest = v
+		}
+	}
+	return newest
+}
+
 // isNewerVersion compares two semantic version strings
 // Returns true if latest > current
 func isNewerVersion(latest, current string) bool {
-	latestParts := strings.Split(latest, ".")
-	currentParts := strings.Split(current, ".")
+	return compareVersions(latest, current) > 0
+}
 
-	// Compare each version component
+// compareVersions orders two versions by semver rules: the numeric
+// major.minor.patch first, then a pre-release ("1.2.0-beta.2") sorts before
+// the release itself, and pre-release identifiers compare field by field,
+// numerically where both are numbers.
+func compareVersions(a, b string) int {
+	aCore, aPre, _ := strings.Cut(a, "-")
+	bCore, bPre, _ := strings.Cut(b, "-")
+
+	aParts := strings.Split(aCore, ".")
+	bParts := strings.Split(bCore, ".")
 	for i := 0; i < 3; i++ {
-		var latestNum, currentNum int
-		if i < len(latestParts) {
-			fmt.Sscanf(latestParts[i], "%d", &latestNum)
+		var aNum, bNum int
+		if i < len(aParts) {
+			fmt.Sscanf(aParts[i], "%d", &aNum)
 		}
-		if i < len(currentParts) {
-			fmt.Sscanf(currentParts[i], "%d", &currentNum)
+		if i < len(bParts) {
+			fmt.Sscanf(bParts[i], "%d", &bNum)
 		}
-
-		if latestNum > currentNum {
-			return true
+		if aNum != bNum {
+			if aNum > bNum {
+				return 1
+			}
+			return -1
 		}
-		if latestNum < currentNum {
-			return false
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	aIDs := strings.Split(aPre, ".")
+	bIDs := strings.Split(bPre, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		if c := comparePrereleaseID(aIDs[i], bIDs[i]); c != 0 {
+			return c
 		}
 	}
+	switch {
+	case len(aIDs) > len(bIDs):
+		return 1
+	case len(aIDs) < len(bIDs):
+		return -1
+	}
+	return 0
+}
 
-	return false
+// comparePrereleaseID compares one dot-separated pre-release field: numbers
+// numerically and below any alphanumeric field, everything else as text.
+func comparePrereleaseID(a, b string) int {
+	aNum, aErr := strconv.Atoi(a)
+	bNum, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		if aNum != bNum {
+			if aNum > bNum {
+				return 1
+			}
+			return -1
+		}
+		return 0
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
 }
 
 // promptAndUpdate asks user if they want to update and performs the update if yes
 // Returns true if user declined the update (or update failed), false if update succeeded
-func promptAndUpdate(newVersion string) bool {
+func promptAndUpdate(newVersion string, autoRestart bool) bool {
 	fmt.Printf("\n")
 	fmt.Printf("Update available: v%s → v%s\n", CurrentVersion, newVersion)
 	fmt.Print("Download and install? [y/N]: ")
@@ -106,7 +212,12 @@ func promptAndUpdate(newVersion string) bool {
 			time.Sleep(2 * time.Second)
 			return true // Update failed, remember check time
 		} else {
-			fmt.Println("\n✓ Update successful! Restarting...")
+			fmt.Println("\n✓ Update successful!")
+			if !autoRestart && !confirmRestart() {
+				fmt.Printf("Relaunch claude-chats to use v%s.\n\n", newVersion)
+				return true
+			}
+			fmt.Println("Restarting...")
 
 			// Get current executable path
 			exePath, err := os.Executable()
@@ -129,6 +240,16 @@ func promptAndUpdate(newVersion string) bool {
 	return true // User declined
 }
 
+// confirmRestart asks whether to re-exec the freshly installed binary now.
+// Empty input means yes.
+func confirmRestart() bool {
+	fmt.Print("Restart now? [Y/n]: ")
+	var response string
+	fmt.Scanln(&response)
+	response = strings.ToLower(strings.TrimSpace(response))
+	return response == "" || response == "y"
+}
+
 // downloadAndInstall downloads the binary and replaces the current executable
 func downloadAndInstall(version string) error {
 	// Determine platform-specific binary name
@@ -165,6 +286,11 @@ func downloadAndInstall(version string) error {
 	}
 	tmpFile.Close()
 
+	// Never install an error page or another platform's build
+	if err := checkExecutable(tmpPath, goos, goarch); err != nil {
+		return fmt.Errorf("refusing to install the download: %w", err)
+	}
+
 	// Make executable
 	if err := os.Chmod(tmpPath, 0755); err != nil {
 		return fmt.Errorf("failed to chmod: %w", err)
@@ -204,6 +330,67 @@ func downloadAndInstall(version string) error {
 	return nil
 }
 
+// checkExecutable verifies that path holds an executable for goos/goarch by
+// its header (ELF, Mach-O or PE), so a misbehaving server can't replace the
+// binary with an HTML page or a build for another platform. Platforms without
+// a known format only get the text check.
+func checkExecutable(path, goos, goarch string) error {
+	head := make([]byte, 512)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	n, _ := io.ReadFull(f, head)
+	f.Close()
+	if kind := http.DetectContentType(head[:n]); strings.HasPrefix(kind, "text/") {
+		return fmt.Errorf("got %s instead of an executable", strings.Split(kind, ";")[0])
+	}
+
+	want := goos + "/" + goarch
+	switch goos {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		bin, err := elf.Open(path)
+		if err != nil {
+			return fmt.Errorf("not an ELF executable for %s", want)
+		}
+		defer bin.Close()
+		machines := map[string]elf.Machine{"amd64": elf.EM_X86_64, "arm64": elf.EM_AARCH64, "386": elf.EM_386, "arm": elf.EM_ARM}
+		if m, ok := machines[goarch]; ok && bin.Machine != m {
+			return fmt.Errorf("built for %s, not %s", bin.Machine, want)
+		}
+	case "darwin":
+		cpus := map[string]macho.Cpu{"amd64": macho.CpuAmd64, "arm64": macho.CpuArm64}
+		if fat, err := macho.OpenFat(path); err == nil {
+			defer fat.Close()
+			for _, arch := range fat.Arches {
+				if arch.Cpu == cpus[goarch] {
+					return nil
+				}
+			}
+			return fmt.Errorf("universal binary without %s", want)
+		}
+		bin, err := macho.Open(path)
+		if err != nil {
+			return fmt.Errorf("not a Mach-O executable for %s", want)
+		}
+		defer bin.Close()
+		if cpu, ok := cpus[goarch]; ok && bin.Cpu != cpu {
+			return fmt.Errorf("built for %s, not %s", bin.Cpu, want)
+		}
+	case "windows":
+		bin, err := pe.Open(path)
+		if err != nil {
+			return fmt.Errorf("not a PE executable for %s", want)
+		}
+		defer bin.Close()
+		machines := map[string]uint16{"amd64": pe.IMAGE_FILE_MACHINE_AMD64, "arm64": pe.IMAGE_FILE_MACHINE_ARM64, "386": pe.IMAGE_FILE_MACHINE_I386}
+		if m, ok := machines[goarch]; ok && bin.Machine != m {
+			return fmt.Errorf("built for machine %#x, not %s", bin.Machine, want)
+		}
+	}
+	return nil
+}
+
 // copyFile copies a file from src to dst, preserving permissions
 func copyFile(src, dst string) error {
 	// Read source file
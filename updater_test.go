@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCheckForUpdate_URLOverride(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name":"v999.0.0","html_url":"https://example.invalid"}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv(updateURLEnv, srv.URL)
+	if got := checkForUpdate(channelStable); got != "999.0.0" {
+		t.Errorf("checkForUpdate() = %q, want %q from the overridden URL", got, "999.0.0")
+	}
+
+	t.Setenv(updateURLEnv, "")
+	if got := updateAPIURL(channelStable); got != GitHubAPIURL {
+		t.Errorf("updateAPIURL() = %q, want default %q", got, GitHubAPIURL)
+	}
+}
+
+func TestCheckExecutable(t *testing.T) {
+	// The test binary is a real executable for this platform.
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkExecutable(self, runtime.GOOS, runtime.GOARCH); err != nil {
+		t.Errorf("own test binary rejected: %v", err)
+	}
+	other := "arm64"
+	if runtime.GOARCH == "arm64" {
+		other = "amd64"
+	}
+	if err := checkExecutable(self, runtime.GOOS, other); err == nil {
+		t.Errorf("binary accepted for the wrong architecture %s", other)
+	}
+
+	page := filepath.Join(t.TempDir(), "download")
+	os.WriteFile(page, []byte("<!DOCTYPE html><html><body>Not Found</body></html>"), 0644)
+	if err := checkExecutable(page, runtime.GOOS, runtime.GOARCH); err == nil {
+		t.Error("an HTML page should be rejected")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"0.3.8", "0.3.7", 1},
+		{"0.3.7", "0.3.7", 0},
+		{"0.4.0-beta.1", "0.3.7", 1},
+		{"0.4.0-beta.1", "0.4.0", -1},
+		{"0.4.0-beta.2", "0.4.0-beta.1", 1},
+		{"0.4.0-beta.10", "0.4.0-beta.9", 1},
+		{"0.4.0-rc.1", "0.4.0-beta.3", 1},
+		{"0.4.0-beta", "0.4.0-beta.1", -1},
+		{"0.4.0-1", "0.4.0-alpha", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckForUpdate_BetaChannel(t *testing.T) {
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`[
+			{"tag_name":"v999.0.0-beta.2","prerelease":true},
+			{"tag_name":"v999.0.0-beta.3","draft":true},
+			{"tag_name":"v998.0.0"}
+		]`))
+	}))
+	defer srv.Close()
+
+	t.Setenv(updateURLEnv, srv.URL+"/repos/x/releases/latest")
+	if got := checkForUpdate(channelBeta); got != "999.0.0-beta.2" {
+		t.Errorf("checkForUpdate(beta) = %q, want the newest non-draft pre-release", got)
+	}
+	if path != "/repos/x/releases" {
+		t.Errorf("beta queried %q, want the release list", path)
+	}
+}
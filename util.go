@@ -2,9 +2,12 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/mattn/go-runewidth"
 )
@@ -26,6 +29,87 @@ func truncateLeft(s string, maxWidth int) string {
 	return ".."
 }
 
+// padRight pads s with spaces to width visual columns. Use it instead of a
+// %-Ns verb for cells that may hold wide (CJK, emoji) characters: fmt pads by
+// rune count, which leaves those rows short and shifts every later column.
+func padRight(s string, width int) string {
+	return runewidth.FillRight(s, width)
+}
+
+// cutANSI returns the columns [from, from+width) of a styled line. Escape
+// sequences are all kept, so colors carry over into the cut; a wide rune
+// split by either edge becomes spaces.
+func cutANSI(s string, from, width int) string {
+	var b strings.Builder
+	col := 0
+	to := from + width
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			// CSI sequence: ESC [ parameters final byte (0x40-0x7e)
+			j := i + 1
+			if j < len(s) && s[j] == '[' {
+				j++
+				for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+					j++
+				}
+			}
+			j = min(j+1, len(s))
+			b.WriteString(s[i:j])
+			i = j
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w := runewidth.RuneWidth(r)
+		switch {
+		case col >= from && col+w <= to:
+			b.WriteRune(r)
+		case col < to && col+w > from:
+			// Straddles an edge: keep the visible part as blanks
+			b.WriteString(strings.Repeat(" ", min(col+w, to)-max(col, from)))
+		}
+		col += w
+		i += size
+	}
+	return b.String()
+}
+
+// wrapText breaks s into lines of at most width visual columns, at spaces
+// where possible. Existing line breaks are kept.
+func wrapText(s string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			for runewidth.StringWidth(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				head := runewidth.Truncate(word, width, "")
+				if head == "" {
+					head = string([]rune(word)[:1]) // a wide rune in a narrow column
+				}
+				lines = append(lines, head)
+				word = word[len(head):]
+			}
+			switch {
+			case line == "":
+				line = word
+			case runewidth.StringWidth(line)+1+runewidth.StringWidth(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
 // justifyItems distributes items evenly across totalWidth.
 // prefix is printed as-is before the items (e.g. "Actions:    ").
 // Items are separated by " | " stretched to fill the remaining width.
@@ -94,3 +178,47 @@ func copyToClipboard(text string) error {
 	cmd.Stdin = strings.NewReader(text)
 	return cmd.Run()
 }
+
+// formatBytes renders a byte count in human-readable binary units (e.g. "4.1 GB").
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// progressBar draws done out of total as a bar of width cells, e.g. [███░░░].
+func progressBar(done, total, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = done * width / total
+	}
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers see either the old or the new content.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}